@@ -0,0 +1,130 @@
+package gongoff
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is a money amount in euro cents.
+// Ex. Amount(750) -> 7,50€
+type Amount int64
+
+// ParseAmount parses an amount written with either ',' or '.' as decimal separator.
+// Ex. "7,50", "7.50", "€ 1.234,56", "1,234.56", "-3"
+// When both separators are present the last one is the decimal separator, the other one groups thousands.
+// At most two decimal digits are accepted.
+func ParseAmount(s string) (Amount, error) {
+	value := strings.TrimSpace(s)
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(value, "€"), "€"))
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	lastComma := strings.LastIndexByte(value, ',')
+	lastDot := strings.LastIndexByte(value, '.')
+	decimal := -1
+	grouping := byte(0)
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		decimal = lastComma
+		grouping = '.'
+		if lastDot > lastComma {
+			decimal = lastDot
+			grouping = ','
+		}
+	case lastComma >= 0:
+		if strings.Count(value, ",") == 1 {
+			decimal = lastComma
+		} else {
+			grouping = ','
+		}
+	case lastDot >= 0:
+		if strings.Count(value, ".") == 1 {
+			decimal = lastDot
+		} else {
+			grouping = '.'
+		}
+	}
+
+	integer, fraction := value, ""
+	if decimal >= 0 {
+		integer, fraction = value[:decimal], value[decimal+1:]
+	}
+	if grouping != 0 {
+		groups := strings.Split(integer, string(grouping))
+		for i, group := range groups {
+			if len(group) > 3 || group == "" || (i > 0 && len(group) != 3) {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+			}
+		}
+		integer = strings.Join(groups, "")
+	}
+	if integer == "" || len(fraction) > 2 || !allDigits(integer) || !allDigits(fraction) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	cents, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if negative {
+		cents = -cents
+	}
+	return Amount(cents), nil
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount in the italian locale.
+// Ex. Amount(123456) -> "1.234,56"
+func (a Amount) String() string {
+	cents := int64(a)
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	integer := strconv.FormatInt(cents/100, 10)
+	var grouped strings.Builder
+	for i := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteByte(integer[i])
+	}
+	return fmt.Sprintf("%s%s,%02d", sign, grouped.String(), cents%100)
+}
+
+// encode returns the amount as sent to the printer, in cents.
+func (a Amount) encode() string {
+	return strconv.FormatInt(int64(a), 10)
+}
+
+// Multiply returns the amount of quantity pieces, see MultiplyQuantity for fractional quantities.
+func (a Amount) Multiply(quantity int) Amount {
+	return a.MultiplyQuantity(NewQuantity(quantity))
+}
+
+// Percentage returns percentage% of the amount, rounded to the cent like the printer does (half away from zero).
+// Ex. Amount(999).Percentage(10) -> Amount(100)
+func (a Amount) Percentage(percentage float64) Amount {
+	basisPoints := int64(math.Round(percentage * 100))
+	return Amount(divideRound(int64(a)*basisPoints, 10000))
+}
+
+// divideRound divides n by d rounding half away from zero, d must be positive.
+func divideRound(n int64, d int64) int64 {
+	if n < 0 {
+		return -((-n*2 + d) / (d * 2))
+	}
+	return (n*2 + d) / (d * 2)
+}
@@ -0,0 +1,81 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+
+	valid := map[string]Amount{
+		"7,50":      750,
+		"7.50":      750,
+		"7,5":       750,
+		"7":         700,
+		"€ 7,50":    750,
+		"7,50€":     750,
+		"1.234,56":  123456,
+		"1,234.56":  123456,
+		"1.234.567": 123456700,
+		"-3,10":     -310,
+		"0,05":      5,
+	}
+	for s, expected := range valid {
+		amount, err := ParseAmount(s)
+		if err != nil {
+			t.Errorf("Expected error = nil parsing %s, got %s", s, err)
+		}
+		if amount != expected {
+			t.Errorf("Expected %d parsing %s, got %d", expected, s, amount)
+		}
+	}
+
+	for _, s := range []string{"", "abc", "7,505", "1,2,3", ",50", "7,5a"} {
+		_, err := ParseAmount(s)
+		if !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Expected ErrInvalidAmount parsing %q, got %v", s, err)
+		}
+	}
+
+	fmt.Println("Completed testParseAmount")
+}
+
+func TestAmountString(t *testing.T) {
+
+	formatted := map[Amount]string{
+		750:       "7,50",
+		5:         "0,05",
+		123456:    "1.234,56",
+		123456700: "1.234.567,00",
+		-310:      "-3,10",
+	}
+	for amount, expected := range formatted {
+		if amount.String() != expected {
+			t.Errorf("Expected %s, got %s", expected, amount.String())
+		}
+	}
+
+	fmt.Println("Completed testAmountString")
+}
+
+func TestAmountPercentage(t *testing.T) {
+
+	if got := Amount(999).Percentage(10); got != 100 {
+		t.Errorf("Expected 100, got %d", got)
+	}
+	if got := Amount(250).Percentage(50.12); got != 125 {
+		t.Errorf("Expected 125, got %d", got)
+	}
+	if got := Amount(5).Percentage(50); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+	if got := Amount(-5).Percentage(50); got != -3 {
+		t.Errorf("Expected -3, got %d", got)
+	}
+	if got := Amount(750).Multiply(3); got != 2250 {
+		t.Errorf("Expected 2250, got %d", got)
+	}
+
+	fmt.Println("Completed testAmountPercentage")
+}
@@ -0,0 +1,355 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ReceiptBuilder builds a DocumentCommercial with chained calls.
+// Commands are placed in the order required by the printer (products and their adjustments,
+// subtotal and its adjustments, customer identifier, trailers, payments) regardless of the order of the calls.
+// Errors are collected along the way and returned by Build.
+//
+// Ex.
+//
+//	doc, err := NewReceiptBuilder().
+//		AddItem("BREAD", 750, 1).
+//		AddItemQty("MILK", 120, 2, 2).DiscountPercent(10).
+//		PayRest(TerminatorTypePaymentCash).
+//		Build()
+type ReceiptBuilder struct {
+	items              []Command
+	subtotal           []Command
+	customerIdentifier *CommandCustomerIdentifier
+	trailers           []Command
+	payments           []Command
+	errs               []error
+}
+
+func NewReceiptBuilder() *ReceiptBuilder {
+	return &ReceiptBuilder{}
+}
+
+// AddItem adds a product sold once in the given department.
+func (b *ReceiptBuilder) AddItem(description string, unitPrice Amount, department int) *ReceiptBuilder {
+	if department <= 0 {
+		b.errorf("%q: %w", description, ErrInvalidDepartment)
+		return b
+	}
+	b.items = append(b.items, NewCommandProduct(unitPrice, &description, nil, &department))
+	return b
+}
+
+// AddItemQty adds a product sold quantity times in the given department.
+func (b *ReceiptBuilder) AddItemQty(description string, unitPrice Amount, quantity int, department int) *ReceiptBuilder {
+	if quantity <= 0 {
+		b.errorf("%w: quantity of %q must be greater than zero", ErrInvalidData, description)
+		return b
+	}
+	if department <= 0 {
+		b.errorf("%q: %w", description, ErrInvalidDepartment)
+		return b
+	}
+	b.items = append(b.items, NewCommandProduct(unitPrice, &description, &quantity, &department))
+	return b
+}
+
+// AddItemWeight adds a product sold by weight, or by any fractional quantity, at unitPrice per unit.
+// Ex. AddItemWeight("CHEESE", 1890, 750, 2) -> 0,750 kg of cheese at 18,90€/kg.
+func (b *ReceiptBuilder) AddItemWeight(description string, unitPrice Amount, weight Quantity, department int) *ReceiptBuilder {
+	if department <= 0 {
+		b.errorf("%q: %w", description, ErrInvalidDepartment)
+		return b
+	}
+	command, err := NewCommandProductQuantity(unitPrice, &description, weight, &department)
+	if err != nil {
+		b.errorf("%q: %w", description, err)
+		return b
+	}
+	b.items = append(b.items, command)
+	return b
+}
+
+// AddPLU adds a PLU programmed in the printer sold quantity times at its programmed price.
+func (b *ReceiptBuilder) AddPLU(plu int, quantity int) *ReceiptBuilder {
+	return b.addPLU(plu, quantity, nil)
+}
+
+// AddPLUPrice adds a PLU programmed in the printer sold quantity times at the given price.
+func (b *ReceiptBuilder) AddPLUPrice(plu int, quantity int, unitPrice Amount) *ReceiptBuilder {
+	return b.addPLU(plu, quantity, &unitPrice)
+}
+
+func (b *ReceiptBuilder) addPLU(plu int, quantity int, unitPrice *Amount) *ReceiptBuilder {
+	var pluQuantity *int
+	if quantity != 1 {
+		pluQuantity = &quantity
+	}
+	command, err := NewCommandProductPLU(plu, pluQuantity, unitPrice)
+	if err != nil {
+		b.errorf("PLU %d: %w", plu, err)
+		return b
+	}
+	b.items = append(b.items, command)
+	return b
+}
+
+// AddPLUWeight adds a PLU programmed in the printer sold by weight at its programmed price per unit.
+func (b *ReceiptBuilder) AddPLUWeight(plu int, weight Quantity) *ReceiptBuilder {
+	command, err := NewCommandProductPLUQuantity(plu, weight, nil)
+	if err != nil {
+		b.errorf("PLU %d: %w", plu, err)
+		return b
+	}
+	b.items = append(b.items, command)
+	return b
+}
+
+// DiscountDepartment subtracts a fixed value from the sales of a department, ex. returned bottle deposits.
+func (b *ReceiptBuilder) DiscountDepartment(description string, discount Amount, department int) *ReceiptBuilder {
+	command, err := NewCommandDiscountDepartment(discount, &description, department)
+	if err != nil {
+		b.errorf("department discount %q: %w", description, err)
+		return b
+	}
+	b.items = append(b.items, command)
+	return b
+}
+
+// VoidLastItem voids the last added item together with its discounts and increases.
+func (b *ReceiptBuilder) VoidLastItem() *ReceiptBuilder {
+	if len(b.items) == 0 {
+		b.errs = append(b.errs, ErrVoidWithoutItem)
+		return b
+	}
+	b.items = append(b.items, NewCommandVoidItem(nil))
+	return b
+}
+
+// VoidItem voids the item added by the index-th call to AddItem, AddItemQty or AddItemWeight, counting from 0.
+func (b *ReceiptBuilder) VoidItem(index int) *ReceiptBuilder {
+	product, ok := nthProduct(b.items, index)
+	if !ok {
+		b.errorf("%w: item %d", ErrVoidWithoutItem, index)
+		return b
+	}
+	b.items = append(b.items, NewCommandVoidItem(product))
+	return b
+}
+
+// DiscountPercent applies a percentage discount to the last added item.
+func (b *ReceiptBuilder) DiscountPercent(percentage float64) *ReceiptBuilder {
+	command, err := NewCommandDiscountPercentage(percentage)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.adjustLastItem(command)
+}
+
+// DiscountAmount applies a fixed value discount to the last added item.
+func (b *ReceiptBuilder) DiscountAmount(discount Amount) *ReceiptBuilder {
+	command, err := NewCommandDiscountAmount(discount)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.adjustLastItem(command)
+}
+
+// Surcharge applies a fixed value increase to the last added item.
+func (b *ReceiptBuilder) Surcharge(increase Amount) *ReceiptBuilder {
+	command, err := NewCommandIncreaseAmount(increase)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.adjustLastItem(command)
+}
+
+// SurchargePercent applies a percentage increase to the last added item.
+func (b *ReceiptBuilder) SurchargePercent(percentage float64) *ReceiptBuilder {
+	command, err := NewCommandIncreasePercentage(percentage)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.adjustLastItem(command)
+}
+
+// SubtotalDiscountPercent applies a percentage discount to the subtotal of all the items.
+func (b *ReceiptBuilder) SubtotalDiscountPercent(percentage float64) *ReceiptBuilder {
+	command, err := NewCommandDiscountPercentageSubtotal(percentage)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.subtotal = append(b.subtotal, command)
+	return b
+}
+
+// SubtotalDiscountAmount applies a fixed value discount to the subtotal of all the items.
+func (b *ReceiptBuilder) SubtotalDiscountAmount(discount Amount) *ReceiptBuilder {
+	command, err := NewCommandDiscountAmountSubtotal(discount)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.subtotal = append(b.subtotal, command)
+	return b
+}
+
+// SubtotalSurchargePercent applies a percentage increase to the subtotal of all the items.
+func (b *ReceiptBuilder) SubtotalSurchargePercent(percentage float64) *ReceiptBuilder {
+	command, err := NewCommandIncreasePercentageSubtotal(percentage)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.subtotal = append(b.subtotal, command)
+	return b
+}
+
+// SubtotalSurcharge applies a fixed value increase to the subtotal of all the items.
+func (b *ReceiptBuilder) SubtotalSurcharge(increase Amount) *ReceiptBuilder {
+	command, err := NewCommandIncreaseAmountSubtotal(increase)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.subtotal = append(b.subtotal, command)
+	return b
+}
+
+func (b *ReceiptBuilder) adjustLastItem(command Command) *ReceiptBuilder {
+	if len(b.items) == 0 {
+		b.errs = append(b.errs, ErrDiscountWithoutProduct)
+		return b
+	}
+	b.items = append(b.items, command)
+	return b
+}
+
+// CustomerID prints the customer identifier (codice fiscale or partita IVA) on the receipt.
+func (b *ReceiptBuilder) CustomerID(customerIdentifier string) *ReceiptBuilder {
+	command, err := NewCommandCustomerIdentifier(customerIdentifier)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.customerIdentifier = command
+	return b
+}
+
+// Trailer adds a line printed at the end of the receipt.
+func (b *ReceiptBuilder) Trailer(trailer string) *ReceiptBuilder {
+	b.trailers = append(b.trailers, NewCommandTrailer(trailer))
+	return b
+}
+
+// Pay adds a payment of the given amount.
+func (b *ReceiptBuilder) Pay(method TerminatorType, amount Amount) *ReceiptBuilder {
+	if err := checkAmount("payment", amount); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	return b.pay(method, &amount)
+}
+
+// PayRest pays the amount still due with the given method.
+func (b *ReceiptBuilder) PayRest(method TerminatorType) *ReceiptBuilder {
+	return b.pay(method, nil)
+}
+
+func (b *ReceiptBuilder) pay(method TerminatorType, amount *Amount) *ReceiptBuilder {
+	command, err := NewCommandPayment(method, amount, nil)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.payments = append(b.payments, command)
+	return b
+}
+
+// nthProduct returns the index-th CommandProduct of commands, counting from 0.
+func nthProduct(commands []Command, index int) (*CommandProduct, bool) {
+	products := 0
+	for _, command := range commands {
+		product, ok := command.(*CommandProduct)
+		if !ok {
+			continue
+		}
+		if products == index {
+			return product, true
+		}
+		products++
+	}
+	return nil, false
+}
+
+func (b *ReceiptBuilder) errorf(format string, a ...interface{}) {
+	b.errs = append(b.errs, fmt.Errorf(format, a...))
+}
+
+// Build returns the document, or a *BuildError with every error found while building and validating it.
+func (b *ReceiptBuilder) Build() (*DocumentCommercial, error) {
+	var commands []Command
+	commands = append(commands, b.items...)
+	if len(b.subtotal) > 0 {
+		commands = append(commands, NewCommandSubtotal())
+		commands = append(commands, b.subtotal...)
+	}
+	if b.customerIdentifier != nil {
+		commands = append(commands, b.customerIdentifier)
+	}
+	commands = append(commands, b.trailers...)
+	commands = append(commands, b.payments...)
+
+	doc := &DocumentCommercial{
+		DocumentGeneric: DocumentGeneric{
+			commands: commands,
+		},
+	}
+
+	errs := append([]error{}, b.errs...)
+	// Commands refused while building are missing from the document, validating it would report misleading errors.
+	if len(errs) == 0 {
+		err := doc.Validate()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &BuildError{Errors: errs}
+	}
+	return doc, nil
+}
+
+// BuildError collects the errors found by ReceiptBuilder.
+// errors.Is and errors.As match any of the collected errors.
+type BuildError struct {
+	Errors []error
+}
+
+func (e *BuildError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid receipt: " + strings.Join(messages, "; ")
+}
+
+func (e *BuildError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is makes errors.Is match any of the collected errors, also on go versions not unwrapping multiple errors.
+func (e *BuildError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
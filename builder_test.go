@@ -0,0 +1,202 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestReceiptBuilder(t *testing.T) {
+
+	doc, err := NewReceiptBuilder().
+		Pay(TerminatorTypePaymentCards, 500).
+		AddItem("BREAD", 750, 1).
+		AddItemQty("MILK", 120, 2, 2).DiscountPercent(10).
+		AddItem("BAG", 10, 3).Surcharge(5).
+		Trailer("THANK YOU").
+		CustomerID("RSSMRA80A01H501U").
+		PayRest(TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	expected := []string{
+		`"BREAD"750H1R`,
+		`"MILK"2*120H2R`,
+		`10.001M`,
+		`"BAG"10H3R`,
+		`5H7M`,
+		`"RSSMRA80A01H501U"@39F`,
+	}
+	commands := doc.Commands()
+	if len(commands) != 9 {
+		t.Fatalf("Expected 9 commands, got %d", len(commands))
+	}
+	for i, e := range expected {
+		encoded, _ := commands[i].Encode()
+		if string(encoded) != e {
+			t.Errorf("Expected command %d = %s, got %s", i, e, encoded)
+		}
+	}
+	if _, ok := commands[6].(*CommandTrailer); !ok {
+		t.Errorf("Expected trailer before payments, got %T", commands[6])
+	}
+	if _, ok := commands[7].(*CommandPayment); !ok {
+		t.Errorf("Expected payment, got %T", commands[7])
+	}
+
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 7,50 + 2,40 - 0,24 + 0,10 + 0,05 = 9,81
+	if totals.Total != 981 || totals.Paid != 981 || totals.Increase != 5 {
+		t.Errorf("Expected total and paid 9,81 with 0,05 increase, got %+v", *totals)
+	}
+
+	doc, err = NewReceiptBuilder().
+		SubtotalDiscountPercent(10).
+		AddItem("BREAD", 1000, 1).SurchargePercent(5).
+		AddItem("MILK", 500, 1).
+		SubtotalSurcharge(100).
+		PayRest(TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	expected = []string{`"BREAD"1000H1R`, `5.005M`, `"MILK"500H1R`, `=`, `10.002M`, `100H8M`, `1T`}
+	commands = doc.Commands()
+	if len(commands) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d", len(expected), len(commands))
+	}
+	for i, e := range expected {
+		encoded, _ := commands[i].Encode()
+		if string(encoded) != e {
+			t.Errorf("Expected command %d = %s, got %s", i, e, encoded)
+		}
+	}
+	totals, err = doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 10,00 + 0,50 + 5,00 = 15,50 - 1,55 + 1,00 = 14,95
+	if totals.Total != 1495 {
+		t.Errorf("Expected total 14,95, got %s", totals.Total)
+	}
+
+	doc, err = NewReceiptBuilder().
+		AddItem("BREAD", 750, 1).DiscountAmount(50).
+		AddItem("MILK", 120, 1).
+		AddItem("BAG", 10, 1).
+		VoidLastItem().
+		VoidItem(0).
+		PayRest(TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	totals, err = doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if totals.Total != 120 || totals.Discount != 0 {
+		t.Errorf("Expected total 1,20 without discounts, got %+v", *totals)
+	}
+
+	doc, err = NewReceiptBuilder().
+		AddPLU(12, 1).
+		AddPLUPrice(7, 3, 200).
+		DiscountDepartment("BOTTLE", 50, 1).
+		PayRest(TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	expected = []string{`12P`, `3*200H7P`, `"BOTTLE"50H1r`, `1T`}
+	commands = doc.Commands()
+	if len(commands) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d", len(expected), len(commands))
+	}
+	for i, e := range expected {
+		encoded, _ := commands[i].Encode()
+		if string(encoded) != e {
+			t.Errorf("Expected command %d = %s, got %s", i, e, encoded)
+		}
+	}
+
+	doc, err = NewReceiptBuilder().
+		AddItemWeight("CHEESE", 1890, 750, 2).
+		AddPLUWeight(12, 1500).
+		PayRest(TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	expected = []string{`"CHEESE"0.750*1890H2R`, `1.500*12P`, `1T`}
+	commands = doc.Commands()
+	if len(commands) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d", len(expected), len(commands))
+	}
+	for i, e := range expected {
+		encoded, _ := commands[i].Encode()
+		if string(encoded) != e {
+			t.Errorf("Expected command %d = %s, got %s", i, e, encoded)
+		}
+	}
+
+	fmt.Println("Completed testReceiptBuilder")
+}
+
+func TestReceiptBuilderErrors(t *testing.T) {
+
+	_, err := NewReceiptBuilder().
+		DiscountAmount(100).
+		AddItemQty("BREAD", 750, 0, 1).
+		AddItem("MILK", 120, 1).
+		DiscountPercent(120).
+		CustomerID("123").
+		Pay("1M", 100).
+		Build()
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("Expected *BuildError, got %v", err)
+	}
+	if len(buildErr.Errors) != 5 {
+		t.Errorf("Expected 5 errors, got %d: %s", len(buildErr.Errors), err)
+	}
+	for _, target := range []error{ErrDiscountWithoutProduct, ErrInvalidData, ErrInvalidCustomerIdentifier, ErrInvalidPaymentMethod} {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected errors.Is(err, %v)", target)
+		}
+	}
+
+	_, err = NewReceiptBuilder().VoidLastItem().AddItem("BREAD", 750, 1).VoidItem(3).Build()
+	if !errors.Is(err, ErrVoidWithoutItem) {
+		t.Errorf("Expected ErrVoidWithoutItem, got %v", err)
+	}
+
+	_, err = NewReceiptBuilder().AddItemWeight("CHEESE", 1890, 0, 2).AddPLUWeight(12, 0).Build()
+	if !errors.Is(err, ErrInvalidQuantity) {
+		t.Errorf("Expected ErrInvalidQuantity, got %v", err)
+	}
+
+	_, err = NewReceiptBuilder().AddItem("BREAD", 750, 0).AddItemQty("MILK", 120, 2, -1).AddItemWeight("CHEESE", 1890, 750, 0).Build()
+	buildErr = nil
+	if !errors.As(err, &buildErr) || len(buildErr.Errors) != 3 || !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Expected 3 ErrInvalidDepartment, got %v", err)
+	}
+
+	_, err = NewReceiptBuilder().AddPLU(0, 1).DiscountDepartment("BOTTLE", 100, 0).Build()
+	if !errors.Is(err, ErrInvalidPLU) || !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Expected ErrInvalidPLU and ErrInvalidDepartment, got %v", err)
+	}
+
+	_, err = NewReceiptBuilder().AddItem("BREAD", 750, 1).Pay(TerminatorTypePaymentCash, 500).Build()
+	if !errors.Is(err, ErrInsufficientPayment) {
+		t.Errorf("Expected ErrInsufficientPayment, got %v", err)
+	}
+
+	fmt.Println("Completed testReceiptBuilderErrors")
+}
@@ -0,0 +1,289 @@
+// Command gongoff sends documents and commands to a fiscal printer through serial port or network.
+//
+// Usage:
+//
+//	gongoff (--serial PORT [--baud RATE] | --net HOST[:PORT]) [--replies] [--timeout DURATION] COMMAND [ARGS]
+//
+// --replies waits for a reply after every command, for gateways and the gongofftest emulator answering
+// the commands, see gongoff.GenericPrinter.SetReplies.
+//
+// Commands:
+//
+//	print FILE     print a document described in JSON or YAML (.yaml, .yml), "-" reads JSON from stdin
+//	raw STRING...  send raw Xon-Xoff commands, ex. gongoff --net 192.168.1.100 raw '"BREAD"750H1R' 1T
+//	management     print the lines read from stdin as a management document
+//	report x|z     print the X financial report or the Z report with fiscal closure
+//	drawer         open the cash drawer
+//	clock [TIME]   set the printer clock to TIME ("2006-01-02 15:04"), the current time if omitted
+//	status         print the status of the printer: paper, cover, fiscal memory, open document and daily closure,
+//	               not an Epson command, it needs --replies and a device answering it
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/internal/printerflag"
+)
+
+const clockLayout = "2006-01-02 15:04"
+
+// errUsage is returned when the command line is not valid, the usage is printed and the exit code is 2.
+var errUsage = errors.New("invalid usage")
+
+// subcommand prepares the commands to send from the arguments, before the printer is opened.
+type subcommand func(args []string, stdin io.Reader) ([]gongoff.Command, error)
+
+var subcommands = map[string]subcommand{
+	"print":      printFile,
+	"raw":        raw,
+	"management": management,
+	"report":     report,
+	"drawer":     drawer,
+	"clock":      clock,
+	"status":     status,
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gongoff:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gongoff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gongoff (--serial PORT [--baud RATE] | --net HOST[:PORT]) [--replies] [--timeout DURATION] COMMAND [ARGS]")
+		fmt.Fprintln(stderr, "Commands: print FILE, raw STRING..., management, report x|z, drawer, clock [TIME], status")
+		flags.PrintDefaults()
+	}
+	serialPort := flags.String("serial", "", "serial port name or device path")
+	baudRate := flags.Int("baud", gongoff.DefaultSerialOptions.BaudRate, "serial baud rate")
+	address := flags.String("net", "", fmt.Sprintf("printer address, port %d if omitted", printerflag.DefaultNetworkPort))
+	replies := flags.Bool("replies", false, "wait for a reply after every command, the printer must answer them")
+	timeout := flags.Duration("timeout", time.Minute, "maximum duration of the whole operation")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+	if flags.NArg() == 0 || (*serialPort == "") == (*address == "") {
+		flags.Usage()
+		return errUsage
+	}
+	name := flags.Arg(0)
+	prepare, ok := subcommands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", name)
+		flags.Usage()
+		return errUsage
+	}
+
+	commands, err := prepare(flags.Args()[1:], stdin)
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	connection := printerflag.Connection{Serial: *serialPort, BaudRate: *baudRate, Address: *address, Replies: *replies}
+	printer, description, err := connection.Printer()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	err = gongoff.OpenContext(ctx, printer)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %s: %w", description, err)
+	}
+	defer printer.Close()
+
+	if name == "status" {
+		fmt.Fprintf(stdout, "connected to %s\n", description)
+		status, err := gongoff.StatusContext(ctx, printer)
+		var printerErr *gongoff.PrinterError
+		if errors.As(err, &printerErr) || errors.Is(err, gongoff.ErrStatusUnsupported) {
+			fmt.Fprintf(stdout, "the printer does not support status requests: %s\n", err)
+			return nil
+		}
+		if errors.Is(err, gongoff.ErrRepliesDisabled) {
+			fmt.Fprintln(stdout, "the status needs the printer replies, see --replies")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		printStatus(stdout, status)
+		return nil
+	}
+	err = gongoff.PrintCommandsContext(ctx, printer, commands)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "sent %d commands to %s\n", len(commands), description)
+	return nil
+}
+
+// printStatus writes one line per condition reported by the printer.
+func printStatus(w io.Writer, status *gongoff.PrinterStatus) {
+	paper := "ok"
+	switch {
+	case status.PaperOut:
+		paper = "out"
+	case status.PaperLow:
+		paper = "low"
+	}
+	cover := "closed"
+	if status.CoverOpen {
+		cover = "open"
+	}
+	fiscalMemory := "ok"
+	if status.FiscalMemoryNearlyFull {
+		fiscalMemory = "nearly full"
+	}
+	document := "none"
+	if status.DocumentOpen() {
+		document = status.Document
+	}
+	closure := "ok"
+	if status.ClosureOverdue {
+		closure = "overdue"
+	}
+	ready := "yes"
+	if err := status.ReadyForSale(); err != nil {
+		ready = "no, " + err.Error()
+	}
+	fmt.Fprintf(w, "paper: %s\ncover: %s\nfiscal memory: %s\ndocument: %s\ndaily closure: %s\nready for sale: %s\n",
+		paper, cover, fiscalMemory, document, closure, ready)
+}
+
+func printFile(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var document gongoff.Document
+	switch strings.ToLower(filepath.Ext(args[0])) {
+	case ".yaml", ".yml":
+		document, err = gongoff.UnmarshalDocumentYAML(data)
+	default:
+		document, err = gongoff.UnmarshalDocument(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read document %s: %w", args[0], err)
+	}
+
+	if commercial, ok := document.(*gongoff.DocumentCommercial); ok {
+		err = commercial.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return document.Commands(), nil
+}
+
+func raw(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	if len(args) == 0 {
+		return nil, errUsage
+	}
+	var commands []gongoff.Command
+	for _, arg := range args {
+		parsed, err := gongoff.ParseCommands(arg)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q: %w", arg, err)
+		}
+		commands = append(commands, parsed...)
+	}
+	return commands, nil
+}
+
+func management(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	if len(args) != 0 {
+		return nil, errUsage
+	}
+	var rows []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		rows = append(rows, scanner.Text())
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	return gongoff.NewDocumentManagement(rows).Commands(), nil
+}
+
+func report(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	switch strings.ToLower(args[0]) {
+	case "x":
+		return []gongoff.Command{gongoff.NewCommandFinancialReport(false)}, nil
+	case "z":
+		return []gongoff.Command{gongoff.NewCommandFinancialReport(true)}, nil
+	default:
+		return nil, errUsage
+	}
+}
+
+func drawer(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	if len(args) != 0 {
+		return nil, errUsage
+	}
+	return []gongoff.Command{gongoff.NewCommandOpenCashDrawer()}, nil
+}
+
+func clock(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	dateTime := time.Now()
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		dateTime, err = time.ParseInLocation(clockLayout, args[0], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, expected format %q", args[0], clockLayout)
+		}
+	default:
+		return nil, errUsage
+	}
+	return []gongoff.Command{gongoff.NewCommandSetDateTime(dateTime)}, nil
+}
+
+func status(args []string, stdin io.Reader) ([]gongoff.Command, error) {
+	if len(args) != 0 {
+		return nil, errUsage
+	}
+	return nil, nil
+}
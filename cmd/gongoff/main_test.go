@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paolo96/gongoff/gongofftest"
+)
+
+// runEmulator runs the command line against a new emulator and returns it with the output.
+func runEmulator(t *testing.T, stdin string, args ...string) (*gongofftest.Emulator, string, error) {
+	t.Helper()
+	emulator := gongofftest.NewEmulator()
+	t.Cleanup(func() { emulator.Close() })
+
+	address := net.JoinHostPort(emulator.Host(), strconv.Itoa(emulator.Port()))
+	var stdout, stderr bytes.Buffer
+	err := run(append([]string{"--net", address, "--replies"}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return emulator, stdout.String(), err
+}
+
+func TestPrint(t *testing.T) {
+
+	receipt := `{"version":1,"type":"commercial","commands":[
+		{"type":"product","description":"BREAD","quantity":2,"unitPrice":750},
+		{"type":"payment","method":"1T"}
+	]}`
+	path := filepath.Join(t.TempDir(), "receipt.json")
+	err := os.WriteFile(path, []byte(receipt), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emulator, output, err := runEmulator(t, "", "print", path)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.DailyTotal != 1500 || state.DocumentNumber != 1 {
+		t.Errorf("Expected one receipt of 15,00, got %s", state)
+	}
+	if !strings.HasPrefix(output, "sent 2 commands") {
+		t.Errorf("Expected sent 2 commands, got %q", output)
+	}
+
+	emulator, _, err = runEmulator(t, "version: 1\ntype: management\nrows: [hello]\n", "print", "-")
+	if err == nil {
+		t.Errorf("Expected error reading YAML from stdin as JSON, got nil")
+	}
+	if len(emulator.Received()) != 0 {
+		t.Errorf("Expected no commands sent, got %v", emulator.Received())
+	}
+
+	fmt.Println("Completed testPrint")
+}
+
+func TestRaw(t *testing.T) {
+
+	emulator, _, err := runEmulator(t, "", "raw", `"BREAD"750H1R`, "1T")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.DailyTotal != 750 {
+		t.Errorf("Expected daily total 7,50, got %s", state)
+	}
+
+	_, _, err = runEmulator(t, "", "raw", `"BREAD`)
+	if err == nil {
+		t.Errorf("Expected parse error, got nil")
+	}
+
+	fmt.Println("Completed testRaw")
+}
+
+func TestManagement(t *testing.T) {
+
+	emulator, _, err := runEmulator(t, "first\nsecond\n", "management")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	received := emulator.Received()
+	if len(received) != 4 || received[1] != `"first"@` || received[2] != `"second"@` {
+		t.Errorf("Expected management document with 2 rows, got %v", received)
+	}
+
+	fmt.Println("Completed testManagement")
+}
+
+func TestReportDrawerClock(t *testing.T) {
+
+	emulator, _, err := runEmulator(t, "", "report", "z")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.ClosureNumber != 1 {
+		t.Errorf("Expected fiscal closure, got %s", state)
+	}
+
+	emulator, _, err = runEmulator(t, "", "drawer")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.DrawerOpened != 1 {
+		t.Errorf("Expected drawer opened, got %s", state)
+	}
+
+	emulator, _, err = runEmulator(t, "", "clock", "2023-05-17 15:30")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	expected := time.Date(2023, 5, 17, 15, 30, 0, 0, time.Local)
+	if state := emulator.State(); !state.Clock.Equal(expected) {
+		t.Errorf("Expected clock %s, got %s", expected, state.Clock)
+	}
+
+	fmt.Println("Completed testReportDrawerClock")
+}
+
+func TestStatus(t *testing.T) {
+
+	_, output, err := runEmulator(t, "", "status")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if !strings.HasPrefix(output, "connected to") || !strings.Contains(output, "paper: ok\n") || !strings.Contains(output, "ready for sale: yes\n") {
+		t.Errorf("Expected connected and ready printer, got %q", output)
+	}
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	emulator.Update(func(state *gongofftest.State) {
+		state.PaperOut = true
+		state.ClosureOverdue = true
+	})
+	var stdout bytes.Buffer
+	address := net.JoinHostPort(emulator.Host(), strconv.Itoa(emulator.Port()))
+	err = run([]string{"--net", address, "--replies", "status"}, strings.NewReader(""), &stdout, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if !strings.Contains(stdout.String(), "paper: out\n") || !strings.Contains(stdout.String(), "daily closure: overdue\n") || !strings.Contains(stdout.String(), "ready for sale: no") {
+		t.Errorf("Expected paper out and closure overdue, got %q", stdout.String())
+	}
+
+	// Without replies the printer cannot answer the status request.
+	stdout.Reset()
+	err = run([]string{"--net", address, "status"}, strings.NewReader(""), &stdout, &bytes.Buffer{})
+	if err != nil || !strings.Contains(stdout.String(), "--replies") {
+		t.Errorf("Expected status not available without replies, got %q %v", stdout.String(), err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address = listener.Addr().String()
+	listener.Close()
+	err = run([]string{"--net", address, "--timeout", "1s", "status"}, strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "cannot connect") {
+		t.Errorf("Expected connection error, got %v", err)
+	}
+
+	fmt.Println("Completed testStatus")
+}
+
+func TestUsage(t *testing.T) {
+
+	for _, args := range [][]string{
+		{"status"},
+		{"--net", "127.0.0.1", "--serial", "COM1", "status"},
+		{"--net", "127.0.0.1"},
+		{"--net", "127.0.0.1", "print"},
+		{"--net", "127.0.0.1", "report", "y"},
+		{"--net", "127.0.0.1", "unknown"},
+	} {
+		err := run(args, strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+		if !errors.Is(err, errUsage) {
+			t.Errorf("Expected errUsage for %v, got %v", args, err)
+		}
+	}
+
+	fmt.Println("Completed testUsage")
+}
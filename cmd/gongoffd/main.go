@@ -0,0 +1,132 @@
+// Command gongoffd serves fiscal printers over HTTP, see package gongoffhttp for the endpoints.
+//
+// Usage:
+//
+//	gongoffd [--listen ADDRESS] [--replies] --printer NAME=net:HOST[:PORT] --printer NAME=serial:PORT ...
+//
+// --replies waits for a reply after every command, for gateways answering the commands,
+// see gongoff.GenericPrinter.SetReplies.
+//
+// Ex. gongoffd --listen :8080 --printer till=net:192.168.1.100 --printer bar=serial:/dev/ttyUSB0
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/gongoffhttp"
+	"github.com/paolo96/gongoff/internal/printerflag"
+)
+
+// shutdownTimeout is how long the requests in progress are waited for after a signal.
+const shutdownTimeout = 30 * time.Second
+
+// printerFlags collects the repeated --printer flags.
+type printerFlags map[string]printerflag.Connection
+
+func (f printerFlags) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (f printerFlags) Set(value string) error {
+	name, connection, err := parsePrinter(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := f[name]; ok {
+		return fmt.Errorf("printer %q given twice", name)
+	}
+	f[name] = connection
+	return nil
+}
+
+// parsePrinter parses NAME=net:HOST[:PORT] or NAME=serial:PORT.
+func parsePrinter(value string) (string, printerflag.Connection, error) {
+	name, connection, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return "", printerflag.Connection{}, fmt.Errorf("invalid printer %q, expected NAME=net:HOST[:PORT] or NAME=serial:PORT", value)
+	}
+	parsed, err := printerflag.Parse(connection)
+	if err != nil {
+		return "", printerflag.Connection{}, fmt.Errorf("printer %q: %w", name, err)
+	}
+	return name, parsed, nil
+}
+
+func main() {
+	printers := printerFlags{}
+	listen := flag.String("listen", ":8080", "HTTP listen address")
+	replies := flag.Bool("replies", false, "wait for a reply after every command, the printers must answer them")
+	flag.Var(printers, "printer", "printer to serve as NAME=net:HOST[:PORT] or NAME=serial:PORT, can be repeated")
+	flag.Parse()
+	if len(printers) == 0 || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	servedPrinters := make(map[string]gongoff.Printer, len(printers))
+	for name, connection := range printers {
+		connection.Replies = *replies
+		printer, _, err := connection.Printer()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gongoffd: printer %q: %s\n", name, err)
+			os.Exit(2)
+		}
+		servedPrinters[name] = printer
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gongoffd:", err)
+		os.Exit(1)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	fmt.Fprintf(os.Stderr, "gongoffd: serving %s on %s\n", printers, *listen)
+	err = serve(listener, gongoffhttp.NewServer(servedPrinters), stop)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gongoffd:", err)
+		os.Exit(1)
+	}
+}
+
+// serve serves the printers until a signal arrives on stop, then waits for the requests in progress
+// and prints the queued jobs before returning.
+func serve(listener net.Listener, server *gongoffhttp.Server, stop <-chan os.Signal) error {
+	httpServer := &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(ctx)
+		close(shutdown)
+	}()
+
+	err := httpServer.Serve(listener)
+	if !errors.Is(err, http.ErrServerClosed) {
+		server.Close()
+		return err
+	}
+	// Serve returns as soon as Shutdown starts, Shutdown once the requests in progress are answered:
+	// the server must not be closed while they can still submit jobs.
+	<-shutdown
+	return server.Close()
+}
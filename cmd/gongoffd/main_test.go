@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/gongoffhttp"
+	"github.com/paolo96/gongoff/gongofftest"
+)
+
+func TestParsePrinter(t *testing.T) {
+
+	name, connection, err := parsePrinter("till=net:192.168.1.100")
+	if err != nil || name != "till" || connection.Address != "192.168.1.100" {
+		t.Fatalf("Expected till at 192.168.1.100, got %s %+v %v", name, connection, err)
+	}
+	name, connection, err = parsePrinter("bar=serial:/dev/ttyUSB0")
+	if err != nil || name != "bar" || connection.Serial != "/dev/ttyUSB0" {
+		t.Fatalf("Expected bar on /dev/ttyUSB0, got %s %+v %v", name, connection, err)
+	}
+
+	for _, value := range []string{"till", "=net:host", "till=usb:1", "till=serial:", "till=net:host:port"} {
+		_, _, err := parsePrinter(value)
+		if err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+
+	flags := printerFlags{}
+	flags.Set("till=net:192.168.1.100:9101")
+	if err := flags.Set("till=net:192.168.1.101"); err == nil {
+		t.Errorf("Expected error for duplicate printer, got nil")
+	}
+
+	fmt.Println("Completed testParsePrinter")
+}
+
+func TestServeShutdown(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(listener, gongoffhttp.NewServer(map[string]gongoff.Printer{"till": emulator.Printer()}), stop)
+	}()
+
+	// The signal arrives while a document is being uploaded.
+	body, upload := io.Pipe()
+	responses := make(chan *http.Response, 1)
+	go func() {
+		response, err := http.Post("http://"+listener.Addr().String()+"/printers/till/documents", "application/json", body)
+		if err != nil {
+			t.Error(err)
+			close(responses)
+			return
+		}
+		response.Body.Close()
+		responses <- response
+	}()
+	document := `{"version":1,"type":"commercial","commands":[{"type":"product","description":"BREAD","unitPrice":750},{"type":"payment","method":"1T"}]}`
+	upload.Write([]byte(document[:10]))
+	time.Sleep(20 * time.Millisecond)
+	stop <- syscall.SIGTERM
+	time.Sleep(20 * time.Millisecond)
+	upload.Write([]byte(document[10:]))
+	upload.Close()
+
+	response := <-responses
+	if response == nil || response.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected the document accepted during the shutdown, got %+v", response)
+	}
+	err = <-served
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.DailyTotal != 750 {
+		t.Errorf("Expected the queued receipt printed before exiting, got %s", state)
+	}
+
+	fmt.Println("Completed testServeShutdown")
+}
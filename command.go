@@ -1,7 +1,7 @@
 package gongoff
 
 import (
-	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -10,8 +10,11 @@ import (
 // Command anatomy:
 // Data(variable, separator), Data(variable, separator), ..., Terminator(*variable, terminatorType)
 
+// Command is anything that can be sent to the printer.
+// Commands defined outside this package only need to implement Encode.
 type Command interface {
-	get() (string, error)
+	// Encode returns the bytes sent to the printer for the command.
+	Encode() ([]byte, error)
 }
 
 type CommandGeneric struct {
@@ -23,6 +26,23 @@ func NewCommandGeneric(data []Data, terminator Terminator) *CommandGeneric {
 	return &CommandGeneric{data, terminator}
 }
 
+// Data returns the data pieces of the command.
+func (c *CommandGeneric) Data() []Data {
+	return c.data
+}
+
+func (c *CommandGeneric) Terminator() Terminator {
+	return c.terminator
+}
+
+func (c *CommandGeneric) Encode() ([]byte, error) {
+	command, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(command), nil
+}
+
 func (c *CommandGeneric) get() (string, error) {
 	var command string
 	for _, d := range c.data {
@@ -43,14 +63,36 @@ func (c *CommandGeneric) get() (string, error) {
 type CommandProduct struct {
 	CommandGeneric
 	product    *string
-	unitPrice  int
-	quantity   *int
+	unitPrice  Amount
+	quantity   *Quantity
 	department *int
 }
 
 // NewCommandProduct prints a product with the given parameters.
 // Ex. ("BREAD", 750, 2, 3) -> "BREAD"2*750H3R -> Sold 2 loaves of bread for 7,50€ each in department 3.
-func NewCommandProduct(unitPrice int, product *string, quantity *int, department *int) *CommandProduct {
+func NewCommandProduct(unitPrice Amount, product *string, quantity *int, department *int) *CommandProduct {
+	var productQuantity *Quantity
+	if quantity != nil {
+		pieces := NewQuantity(*quantity)
+		productQuantity = &pieces
+	}
+	return newCommandProduct(unitPrice, product, productQuantity, department)
+}
+
+// NewCommandProductQuantity prints a product sold by weight or by a fractional quantity, unitPrice is the price of one unit.
+// Ex. ("CHEESE", 1890, 0.750, 2) -> "CHEESE"0.750*1890H2R -> Sold 0,750 kg of cheese for 18,90€/kg in department 2.
+// The amount of the line is rounded to the cent, see Amount.MultiplyQuantity.
+func NewCommandProductQuantity(unitPrice Amount, product *string, quantity Quantity, department *int) (*CommandProduct, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if quantity > maxQuantity {
+		return nil, ErrQuantityTooLarge
+	}
+	return newCommandProduct(unitPrice, product, &quantity, department), nil
+}
+
+func newCommandProduct(unitPrice Amount, product *string, quantity *Quantity, department *int) *CommandProduct {
 
 	if product != nil && len(*product) > 38 {
 		productDesc := (*product)[:38]
@@ -70,11 +112,11 @@ func NewCommandProduct(unitPrice int, product *string, quantity *int, department
 	}
 
 	if quantity != nil {
-		commandProduct.data = append(commandProduct.data, Data{variable: strconv.Itoa(*quantity), separator: SeparatorTypeMultiply})
+		commandProduct.data = append(commandProduct.data, Data{variable: quantity.encode(), separator: SeparatorTypeMultiply})
 	}
 
 	if unitPrice != 0 {
-		commandProduct.data = append(commandProduct.data, Data{variable: strconv.Itoa(unitPrice), separator: SeparatorTypeValue})
+		commandProduct.data = append(commandProduct.data, Data{variable: unitPrice.encode(), separator: SeparatorTypeValue})
 	}
 
 	if department != nil {
@@ -88,6 +130,175 @@ func NewCommandProduct(unitPrice int, product *string, quantity *int, department
 	return commandProduct
 }
 
+// departmentNumber returns the department of the product, 1 if not given.
+func (c *CommandProduct) departmentNumber() int {
+	if c.department != nil {
+		return *c.department
+	}
+	return 1
+}
+
+// quantityNumber returns the sold quantity, 1 if not given.
+func (c *CommandProduct) quantityNumber() Quantity {
+	if c.quantity != nil {
+		return *c.quantity
+	}
+	return quantityUnit
+}
+
+// sameSale reports whether c and other sell the same quantity of the same product at the same price, regardless of the department.
+func (c *CommandProduct) sameSale(other *CommandProduct) bool {
+	description, otherDescription := "", ""
+	if c.product != nil {
+		description = *c.product
+	}
+	if other.product != nil {
+		otherDescription = *other.product
+	}
+	return description == otherDescription && c.quantityNumber() == other.quantityNumber() && c.unitPrice == other.unitPrice
+}
+
+// amount returns the price of the product for the sold quantity.
+func (c *CommandProduct) amount() Amount {
+	if c.quantity != nil {
+		return c.unitPrice.MultiplyQuantity(*c.quantity)
+	}
+	return c.unitPrice
+}
+
+type CommandVoidItem struct {
+	CommandGeneric
+	product *CommandProduct
+}
+
+// NewCommandVoidItem voids a sale of the open receipt, together with the discounts and increases applied to it.
+// Ex. (nil) -> 0M -> Void the last sale.
+// Ex. ("BREAD", 750, 2) -> "BREAD"2*750H0M -> Void the last sale of 2 loaves of bread for 7,50€ each.
+// The printer refuses voids after a subtotal discount or increase.
+func NewCommandVoidItem(product *CommandProduct) *CommandVoidItem {
+	commandVoidItem := &CommandVoidItem{
+		product: product,
+	}
+	commandVoidItem.data = []Data{}
+	if product != nil {
+		commandVoidItem.data = append(commandVoidItem.data, product.data...)
+	}
+	commandVoidItem.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeCancellation}
+	return commandVoidItem
+}
+
+type CommandProductPLU struct {
+	CommandGeneric
+	plu       int
+	quantity  *Quantity
+	unitPrice *Amount
+}
+
+// NewCommandProductPLU sells a PLU programmed in the printer, with its description, price and department.
+// Ex. (12, 2, nil) -> 2*12P -> Sold 2 units of PLU 12 at the programmed price.
+// Ex. (12, nil, 650) -> 650H12P -> Sold PLU 12 for 6,50€ instead of the programmed price.
+func NewCommandProductPLU(plu int, quantity *int, unitPrice *Amount) (*CommandProductPLU, error) {
+	var pluQuantity *Quantity
+	if quantity != nil {
+		pieces := NewQuantity(*quantity)
+		pluQuantity = &pieces
+	}
+	return newCommandProductPLU(plu, pluQuantity, unitPrice)
+}
+
+// NewCommandProductPLUQuantity sells a PLU programmed in the printer by weight or by a fractional quantity.
+// Ex. (12, 0.750, nil) -> 0.750*12P -> Sold 0,750 kg of PLU 12 at the programmed price per kg.
+func NewCommandProductPLUQuantity(plu int, quantity Quantity, unitPrice *Amount) (*CommandProductPLU, error) {
+	return newCommandProductPLU(plu, &quantity, unitPrice)
+}
+
+func newCommandProductPLU(plu int, quantity *Quantity, unitPrice *Amount) (*CommandProductPLU, error) {
+
+	if plu <= 0 {
+		return nil, ErrInvalidPLU
+	}
+	if quantity != nil && *quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if quantity != nil && *quantity > maxQuantity {
+		return nil, ErrQuantityTooLarge
+	}
+	if unitPrice != nil && *unitPrice <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	commandProductPLU := &CommandProductPLU{
+		plu:       plu,
+		quantity:  quantity,
+		unitPrice: unitPrice,
+	}
+	commandProductPLU.data = []Data{}
+
+	if quantity != nil {
+		commandProductPLU.data = append(commandProductPLU.data, Data{variable: quantity.encode(), separator: SeparatorTypeMultiply})
+	}
+
+	if unitPrice != nil {
+		commandProductPLU.data = append(commandProductPLU.data, Data{variable: unitPrice.encode(), separator: SeparatorTypeValue})
+	}
+
+	pluString := strconv.Itoa(plu)
+	commandProductPLU.terminator = Terminator{variable: &pluString, terminatorType: TerminatorTypeSoldPLU}
+
+	return commandProductPLU, nil
+}
+
+// amount returns the price of the PLU for the sold quantity, false if the price is the one programmed in the printer.
+func (c *CommandProductPLU) amount() (Amount, bool) {
+	if c.unitPrice == nil {
+		return 0, false
+	}
+	if c.quantity != nil {
+		return c.unitPrice.MultiplyQuantity(*c.quantity), true
+	}
+	return *c.unitPrice, true
+}
+
+type CommandDiscountDepartment struct {
+	CommandGeneric
+	discountAmount Amount
+	description    *string
+	department     int
+}
+
+// NewCommandDiscountDepartment subtracts an amount from the sales of a department, ex. returned bottle deposits.
+// Ex. (100, "BOTTLE", 2) -> "BOTTLE"100H2r -> 1,00€ discount on the sales of department 2.
+func NewCommandDiscountDepartment(discountAmount Amount, description *string, department int) (*CommandDiscountDepartment, error) {
+
+	if discountAmount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if department <= 0 {
+		return nil, ErrInvalidDepartment
+	}
+	if description != nil && len(*description) > 38 {
+		descriptionShort := (*description)[:38]
+		description = &descriptionShort
+	}
+
+	commandDiscountDepartment := &CommandDiscountDepartment{
+		discountAmount: discountAmount,
+		description:    description,
+		department:     department,
+	}
+	commandDiscountDepartment.data = []Data{}
+
+	if description != nil {
+		commandDiscountDepartment.data = append(commandDiscountDepartment.data, Data{variable: *description, separator: SeparatorTypeDescription})
+	}
+	commandDiscountDepartment.data = append(commandDiscountDepartment.data, Data{variable: discountAmount.encode(), separator: SeparatorTypeValue})
+
+	departmentString := strconv.Itoa(department)
+	commandDiscountDepartment.terminator = Terminator{variable: &departmentString, terminatorType: TerminatorTypeDiscountDepartment}
+
+	return commandDiscountDepartment, nil
+}
+
 type CommandTrailer struct {
 	CommandGeneric
 	trailer string
@@ -117,23 +328,25 @@ func NewCommandTrailer(trailer string) *CommandTrailer {
 type CommandPayment struct {
 	CommandGeneric
 	paymentMethod TerminatorType
+	amount        *Amount
 }
 
 // NewCommandPayment prints a payment with the given parameters.
-// Ex. (terminatorTypePaymentCards, 750) -> 750H3T -> Paid 750€ with cards.
+// Ex. (terminatorTypePaymentCards, 750) -> 750H3T -> Paid 7,50€ with cards.
 // If no amount is given, the receipt is considered to be paid entirely with the given payment method.
 // If the amount is given, change is applied accordingly.
-func NewCommandPayment(paymentMethod TerminatorType, amount *int, paymentMethodDescription *string) (*CommandPayment, error) {
+func NewCommandPayment(paymentMethod TerminatorType, amount *Amount, paymentMethodDescription *string) (*CommandPayment, error) {
 	if !strings.HasSuffix(string(paymentMethod), "T") {
-		return nil, errors.New("payment method Terminator must end with 'T'")
+		return nil, ErrInvalidPaymentMethod
 	}
 	commandPayment := &CommandPayment{
 		paymentMethod: paymentMethod,
+		amount:        amount,
 	}
 	commandPayment.data = []Data{}
 
 	if amount != nil {
-		commandPayment.data = append(commandPayment.data, Data{variable: strconv.Itoa(*amount), separator: SeparatorTypeValue})
+		commandPayment.data = append(commandPayment.data, Data{variable: amount.encode(), separator: SeparatorTypeValue})
 	}
 	if paymentMethodDescription != nil {
 		commandPayment.data = append(commandPayment.data, Data{variable: *paymentMethodDescription, separator: SeparatorTypeDescription})
@@ -155,7 +368,7 @@ type CommandCustomerIdentifier struct {
 // LotteryTicket (lottery ticket) is a 8-character string.
 func NewCommandCustomerIdentifier(customerIdentifier string) (*CommandCustomerIdentifier, error) {
 	if len(customerIdentifier) != 16 && len(customerIdentifier) != 11 && len(customerIdentifier) != 8 {
-		return nil, errors.New("customer identifier must be 16, 11 or 8 characters long")
+		return nil, ErrInvalidCustomerIdentifier
 	}
 
 	commandCustomerIdentifier := &CommandCustomerIdentifier{
@@ -173,9 +386,12 @@ type CommandDiscountPercentage struct {
 	discountPercentage float64
 }
 
-// NewCommandDiscountPercentage adds a discount percentage to the receipt.
-// Ex. (10) -> 10.001M -> 10% discount on whole transaction.
-func NewCommandDiscountPercentage(discountPercentage float64) *CommandDiscountPercentage {
+// NewCommandDiscountPercentage adds a discount percentage to the last product.
+// Ex. (10) -> 10.001M -> 10% discount on the last product, see NewCommandDiscountPercentageSubtotal for the whole receipt.
+func NewCommandDiscountPercentage(discountPercentage float64) (*CommandDiscountPercentage, error) {
+	if err := checkPercentage("discount", discountPercentage); err != nil {
+		return nil, err
+	}
 	commandDiscountPercentage := &CommandDiscountPercentage{
 		discountPercentage: discountPercentage,
 	}
@@ -183,25 +399,175 @@ func NewCommandDiscountPercentage(discountPercentage float64) *CommandDiscountPe
 		{variable: strconv.FormatFloat(discountPercentage, 'f', 2, 64), separator: SeparatorTypeDecimal},
 	}
 	commandDiscountPercentage.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeDiscountPercentTransaction}
-	return commandDiscountPercentage
+	return commandDiscountPercentage, nil
 }
 
 type CommandDiscountAmount struct {
 	CommandGeneric
-	discountAmount int
+	discountAmount Amount
 }
 
-// NewCommandDiscountAmount adds a fixed value discount to the receipt.
-// Ex. (1000) -> 10003M -> 10.00€ discount on whole transaction.
-func NewCommandDiscountAmount(discountAmount int) *CommandDiscountAmount {
+// NewCommandDiscountAmount adds a fixed value discount to the last product.
+// Ex. (1000) -> 1000H3M -> 10,00€ discount on the last product, see NewCommandDiscountAmountSubtotal for the whole receipt.
+func NewCommandDiscountAmount(discountAmount Amount) (*CommandDiscountAmount, error) {
+	if err := checkAmount("discount", discountAmount); err != nil {
+		return nil, err
+	}
 	commandDiscountAmount := &CommandDiscountAmount{
 		discountAmount: discountAmount,
 	}
 	commandDiscountAmount.data = []Data{
-		{variable: strconv.Itoa(discountAmount), separator: SeparatorTypeValue},
+		{variable: discountAmount.encode(), separator: SeparatorTypeValue},
 	}
 	commandDiscountAmount.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeDiscountValueTransaction}
-	return commandDiscountAmount
+	return commandDiscountAmount, nil
+}
+
+type CommandIncreaseAmount struct {
+	CommandGeneric
+	increaseAmount Amount
+}
+
+// NewCommandIncreaseAmount adds a fixed value increase (surcharge) to the last product.
+// Ex. (150) -> 150H7M -> 1,50€ increase on the last product.
+func NewCommandIncreaseAmount(increaseAmount Amount) (*CommandIncreaseAmount, error) {
+	if err := checkAmount("surcharge", increaseAmount); err != nil {
+		return nil, err
+	}
+	commandIncreaseAmount := &CommandIncreaseAmount{
+		increaseAmount: increaseAmount,
+	}
+	commandIncreaseAmount.data = []Data{
+		{variable: increaseAmount.encode(), separator: SeparatorTypeValue},
+	}
+	commandIncreaseAmount.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeIncreaseValueTransaction}
+	return commandIncreaseAmount, nil
+}
+
+type CommandIncreasePercentage struct {
+	CommandGeneric
+	increasePercentage float64
+}
+
+// NewCommandIncreasePercentage adds an increase percentage (surcharge) to the last product.
+// Ex. (10) -> 10.005M -> 10% increase on the last product.
+func NewCommandIncreasePercentage(increasePercentage float64) (*CommandIncreasePercentage, error) {
+	if err := checkPercentage("surcharge", increasePercentage); err != nil {
+		return nil, err
+	}
+	commandIncreasePercentage := &CommandIncreasePercentage{
+		increasePercentage: increasePercentage,
+	}
+	commandIncreasePercentage.data = []Data{
+		{variable: strconv.FormatFloat(increasePercentage, 'f', 2, 64), separator: SeparatorTypeDecimal},
+	}
+	commandIncreasePercentage.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeIncreasePercentTransaction}
+	return commandIncreasePercentage, nil
+}
+
+type CommandSubtotal struct {
+	CommandGeneric
+}
+
+// NewCommandSubtotal prints the subtotal of the open document, subtotal discounts and increases usually follow it.
+// Ex. () -> =
+func NewCommandSubtotal() *CommandSubtotal {
+	commandSubtotal := &CommandSubtotal{}
+	commandSubtotal.data = []Data{}
+	commandSubtotal.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeSubtotal}
+	return commandSubtotal
+}
+
+type CommandDiscountPercentageSubtotal struct {
+	CommandGeneric
+	discountPercentage float64
+}
+
+// NewCommandDiscountPercentageSubtotal adds a discount percentage to the subtotal of the receipt.
+// Ex. (10) -> 10.002M -> 10% discount on the subtotal.
+func NewCommandDiscountPercentageSubtotal(discountPercentage float64) (*CommandDiscountPercentageSubtotal, error) {
+	if err := checkPercentage("subtotal discount", discountPercentage); err != nil {
+		return nil, err
+	}
+	return newCommandDiscountPercentageSubtotal(discountPercentage), nil
+}
+
+func newCommandDiscountPercentageSubtotal(discountPercentage float64) *CommandDiscountPercentageSubtotal {
+	commandDiscountPercentageSubtotal := &CommandDiscountPercentageSubtotal{
+		discountPercentage: discountPercentage,
+	}
+	commandDiscountPercentageSubtotal.data = []Data{
+		{variable: strconv.FormatFloat(discountPercentage, 'f', 2, 64), separator: SeparatorTypeDecimal},
+	}
+	commandDiscountPercentageSubtotal.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeDiscountPercentSubtotal}
+	return commandDiscountPercentageSubtotal
+}
+
+type CommandDiscountAmountSubtotal struct {
+	CommandGeneric
+	discountAmount Amount
+}
+
+// NewCommandDiscountAmountSubtotal adds a fixed value discount to the subtotal of the receipt.
+// Ex. (1000) -> 1000H4M -> 10,00€ discount on the subtotal.
+func NewCommandDiscountAmountSubtotal(discountAmount Amount) (*CommandDiscountAmountSubtotal, error) {
+	if err := checkAmount("subtotal discount", discountAmount); err != nil {
+		return nil, err
+	}
+	return newCommandDiscountAmountSubtotal(discountAmount), nil
+}
+
+func newCommandDiscountAmountSubtotal(discountAmount Amount) *CommandDiscountAmountSubtotal {
+	commandDiscountAmountSubtotal := &CommandDiscountAmountSubtotal{
+		discountAmount: discountAmount,
+	}
+	commandDiscountAmountSubtotal.data = []Data{
+		{variable: discountAmount.encode(), separator: SeparatorTypeValue},
+	}
+	commandDiscountAmountSubtotal.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeDiscountValueSubtotal}
+	return commandDiscountAmountSubtotal
+}
+
+type CommandIncreasePercentageSubtotal struct {
+	CommandGeneric
+	increasePercentage float64
+}
+
+// NewCommandIncreasePercentageSubtotal adds an increase percentage (surcharge) to the subtotal of the receipt.
+// Ex. (10) -> 10.006M -> 10% increase on the subtotal.
+func NewCommandIncreasePercentageSubtotal(increasePercentage float64) (*CommandIncreasePercentageSubtotal, error) {
+	if err := checkPercentage("subtotal surcharge", increasePercentage); err != nil {
+		return nil, err
+	}
+	commandIncreasePercentageSubtotal := &CommandIncreasePercentageSubtotal{
+		increasePercentage: increasePercentage,
+	}
+	commandIncreasePercentageSubtotal.data = []Data{
+		{variable: strconv.FormatFloat(increasePercentage, 'f', 2, 64), separator: SeparatorTypeDecimal},
+	}
+	commandIncreasePercentageSubtotal.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeIncreasePercentSubtotal}
+	return commandIncreasePercentageSubtotal, nil
+}
+
+type CommandIncreaseAmountSubtotal struct {
+	CommandGeneric
+	increaseAmount Amount
+}
+
+// NewCommandIncreaseAmountSubtotal adds a fixed value increase (surcharge) to the subtotal of the receipt.
+// Ex. (150) -> 150H8M -> 1,50€ increase on the subtotal.
+func NewCommandIncreaseAmountSubtotal(increaseAmount Amount) (*CommandIncreaseAmountSubtotal, error) {
+	if err := checkAmount("subtotal surcharge", increaseAmount); err != nil {
+		return nil, err
+	}
+	commandIncreaseAmountSubtotal := &CommandIncreaseAmountSubtotal{
+		increaseAmount: increaseAmount,
+	}
+	commandIncreaseAmountSubtotal.data = []Data{
+		{variable: increaseAmount.encode(), separator: SeparatorTypeValue},
+	}
+	commandIncreaseAmountSubtotal.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeIncreaseValueSubtotal}
+	return commandIncreaseAmountSubtotal, nil
 }
 
 type CommandBarcode struct {
@@ -224,7 +590,7 @@ func NewCommandBarcode(barcode string) (*CommandBarcode, error) {
 	} else if len(barcode) == 8 {
 		commandBarcode.terminator = Terminator{variable: nil, terminatorType: TerminatorTypePrintBarcodeEAN8}
 	} else {
-		return nil, errors.New("barcode must be 13 or 8 characters long")
+		return nil, ErrInvalidBarcode
 	}
 	return commandBarcode, nil
 }
@@ -398,3 +764,102 @@ func NewCommandDisplayMessage(message string, line int) *CommandDisplayMessage {
 	commandDisplayMessage.terminator = Terminator{variable: nil, terminatorType: lineTerminator}
 	return commandDisplayMessage
 }
+
+type CommandFinancialReport struct {
+	CommandGeneric
+	closure bool
+}
+
+// NewCommandFinancialReport prints the daily financial report.
+// Ex. (false) -> 1f -> X report, the daily totals are kept.
+// Ex. (true) -> 8F -> Z report, the daily totals are zeroed and the fiscal closure is done.
+func NewCommandFinancialReport(closure bool) *CommandFinancialReport {
+	commandFinancialReport := &CommandFinancialReport{
+		closure: closure,
+	}
+	commandFinancialReport.data = []Data{}
+	terminatorType := TerminatorTypeFinancialReportNoZeroing
+	if closure {
+		terminatorType = TerminatorTypeFinancialReportAndFiscalClosureZeroing
+	}
+	commandFinancialReport.terminator = Terminator{variable: nil, terminatorType: terminatorType}
+	return commandFinancialReport
+}
+
+type CommandOpenCashDrawer struct {
+	CommandGeneric
+}
+
+// NewCommandOpenCashDrawer opens the cash drawer connected to the printer.
+// Ex. () -> a
+func NewCommandOpenCashDrawer() *CommandOpenCashDrawer {
+	commandOpenCashDrawer := &CommandOpenCashDrawer{}
+	commandOpenCashDrawer.data = []Data{}
+	commandOpenCashDrawer.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeOpenCashRegister}
+	return commandOpenCashDrawer
+}
+
+// dateTimeLayout is the format of the date and time set with TerminatorTypeSetDateTime (DDMMYYHHMM).
+const dateTimeLayout = "0201061504"
+
+type CommandSetDateTime struct {
+	CommandGeneric
+	dateTime time.Time
+}
+
+// NewCommandSetDateTime sets the clock of the printer, seconds are ignored.
+// Ex. (17/05/2023 15:30) -> 1705231530D
+// The printer refuses the command if a document is open or after a fiscal closure with a later date.
+func NewCommandSetDateTime(dateTime time.Time) *CommandSetDateTime {
+	dateTime = dateTime.Truncate(time.Minute)
+	commandSetDateTime := &CommandSetDateTime{
+		dateTime: dateTime,
+	}
+	commandSetDateTime.data = []Data{}
+	formatted := dateTime.Format(dateTimeLayout)
+	commandSetDateTime.terminator = Terminator{variable: &formatted, terminatorType: TerminatorTypeSetDateTime}
+	return commandSetDateTime
+}
+
+type CommandCancelDocument struct {
+	CommandGeneric
+}
+
+// NewCommandCancelDocument cancels the open commercial document or invoice, the sales registered are voided.
+// Ex. () -> k
+func NewCommandCancelDocument() *CommandCancelDocument {
+	commandCancelDocument := &CommandCancelDocument{}
+	commandCancelDocument.data = []Data{}
+	commandCancelDocument.terminator = Terminator{variable: nil, terminatorType: TerminatorTypeCancelDocumentOrInvoice}
+	return commandCancelDocument
+}
+
+type CommandStatusRequest struct {
+	CommandGeneric
+}
+
+// NewCommandStatusRequest asks the printer for its status, see GenericPrinter.Status.
+// It is not an Epson Xon-Xoff command, only devices answering with replies support it.
+// Ex. () -> ?
+func NewCommandStatusRequest() *CommandStatusRequest {
+	commandStatusRequest := &CommandStatusRequest{}
+	commandStatusRequest.data = []Data{}
+	commandStatusRequest.terminator = Terminator{variable: nil, terminatorType: terminatorTypeStatusRequest}
+	return commandStatusRequest
+}
+
+// checkPercentage returns an error if the percentage of a discount or increase is not between 0 and 100.
+func checkPercentage(kind string, percentage float64) error {
+	if percentage <= 0 || percentage > 100 {
+		return fmt.Errorf("%w: %s percentage %v must be between 0 and 100", ErrInvalidData, kind, percentage)
+	}
+	return nil
+}
+
+// checkAmount returns an error if the amount of a discount, increase or payment is not greater than zero.
+func checkAmount(kind string, amount Amount) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: %s %s must be greater than zero", ErrInvalidAmount, kind, amount)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package gongoff
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -29,9 +30,119 @@ func TestCommandProduct(t *testing.T) {
 		t.Errorf("Expected 750H1R, got %s", commandDefaults)
 	}
 
+	cheese := "CHEESE"
+	commandProductWeight, err := NewCommandProductQuantity(1890, &cheese, 750, &department)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err = commandProductWeight.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "\"CHEESE\"0.750*1890H3R" {
+		t.Errorf("Expected \"CHEESE\"0.750*1890H3R, got %s", command)
+	}
+	if commandProductWeight.amount() != 1418 {
+		t.Errorf("Expected 1418, got %d", commandProductWeight.amount())
+	}
+	_, err = NewCommandProductQuantity(1890, &cheese, 0, nil)
+	if !errors.Is(err, ErrInvalidQuantity) {
+		t.Errorf("Expected ErrInvalidQuantity, got %v", err)
+	}
+	_, err = NewCommandProductQuantity(1890, &cheese, 10000000, nil)
+	if !errors.Is(err, ErrQuantityTooLarge) {
+		t.Errorf("Expected ErrQuantityTooLarge, got %v", err)
+	}
+
 	fmt.Println("Completed testCommandProduct")
 }
 
+// TestCommandVoidItem tests the creation of a CommandVoidItem command.
+func TestCommandVoidItem(t *testing.T) {
+	command, err := NewCommandVoidItem(nil).get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "0M" {
+		t.Errorf("Expected 0M, got %s", command)
+	}
+
+	productName := "BREAD"
+	quantity := 2
+	department := 3
+	command, err = NewCommandVoidItem(NewCommandProduct(750, &productName, &quantity, &department)).get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "\"BREAD\"2*750H0M" {
+		t.Errorf("Expected \"BREAD\"2*750H0M, got %s", command)
+	}
+
+	fmt.Println("Completed testCommandVoidItem")
+}
+
+// TestCommandProductPLU tests the creation of a CommandProductPLU command.
+func TestCommandProductPLU(t *testing.T) {
+	quantity := 2
+	price := Amount(650)
+	commandProductPLU, err := NewCommandProductPLU(12, &quantity, &price)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandProductPLU.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "2*650H12P" {
+		t.Errorf("Expected 2*650H12P, got %s", command)
+	}
+
+	commandProductPLU, err = NewCommandProductPLU(12, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err = commandProductPLU.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "12P" {
+		t.Errorf("Expected 12P, got %s", command)
+	}
+
+	commandProductPLU, err = NewCommandProductPLUQuantity(12, 1250, nil)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err = commandProductPLU.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "1.250*12P" {
+		t.Errorf("Expected 1.250*12P, got %s", command)
+	}
+	_, err = NewCommandProductPLUQuantity(12, 10000000, nil)
+	if !errors.Is(err, ErrQuantityTooLarge) {
+		t.Errorf("Expected ErrQuantityTooLarge, got %v", err)
+	}
+
+	zero := 0
+	_, err = NewCommandProductPLU(0, nil, nil)
+	if !errors.Is(err, ErrInvalidPLU) {
+		t.Errorf("Expected ErrInvalidPLU, got %v", err)
+	}
+	_, err = NewCommandProductPLU(12, &zero, nil)
+	if !errors.Is(err, ErrInvalidQuantity) {
+		t.Errorf("Expected ErrInvalidQuantity, got %v", err)
+	}
+	zeroPrice := Amount(0)
+	_, err = NewCommandProductPLU(12, nil, &zeroPrice)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandProductPLU")
+}
+
 // TestCommandTrailer tests the creation of a CommandMessage command.
 func TestCommandTrailer(t *testing.T) {
 	commandMessage := NewCommandTrailer("Hello World!")
@@ -58,7 +169,7 @@ func TestCommandTrailer(t *testing.T) {
 // TestCommandPayment tests the creation of a CommandPayment command.
 func TestCommandPayment(t *testing.T) {
 	testDesc := "test"
-	testAmount := 100
+	testAmount := Amount(100)
 	commandPayment, err := NewCommandPayment(TerminatorTypePaymentCards, &testAmount, &testDesc)
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
@@ -72,8 +183,8 @@ func TestCommandPayment(t *testing.T) {
 	}
 
 	_, err = NewCommandPayment(TerminatorTypeSold, nil, nil)
-	if err == nil {
-		t.Errorf("Expected error != nil, got nil")
+	if !errors.Is(err, ErrInvalidPaymentMethod) {
+		t.Errorf("Expected ErrInvalidPaymentMethod, got %v", err)
 	}
 
 	fmt.Println("Completed testCommandPayment")
@@ -93,15 +204,18 @@ func TestCommandCustomerIdentifier(t *testing.T) {
 	}
 
 	_, err = NewCommandCustomerIdentifier("test")
-	if err == nil {
-		t.Errorf("Expected error != nil, got nil")
+	if !errors.Is(err, ErrInvalidCustomerIdentifier) {
+		t.Errorf("Expected ErrInvalidCustomerIdentifier, got %v", err)
 	}
 
 	fmt.Println("Completed testNewCommandCustomerIdentifier")
 }
 
 func TestCommandDiscountPercentage(t *testing.T) {
-	commandDiscountPercentage := NewCommandDiscountPercentage(50.12)
+	commandDiscountPercentage, err := NewCommandDiscountPercentage(50.12)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
 	command, err := commandDiscountPercentage.get()
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
@@ -110,11 +224,23 @@ func TestCommandDiscountPercentage(t *testing.T) {
 		t.Errorf("Expected 50.121M, got %s", command)
 	}
 
+	_, err = NewCommandDiscountPercentage(0)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+	_, err = NewCommandDiscountPercentage(-1)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+
 	fmt.Println("Completed testCommandDiscountPercentage")
 }
 
 func TestCommandDiscountAmount(t *testing.T) {
-	commandDiscountAmount := NewCommandDiscountAmount(1126)
+	commandDiscountAmount, err := NewCommandDiscountAmount(1126)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
 	command, err := commandDiscountAmount.get()
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
@@ -123,9 +249,207 @@ func TestCommandDiscountAmount(t *testing.T) {
 		t.Errorf("Expected 1126H3M, got %s", command)
 	}
 
+	_, err = NewCommandDiscountAmount(0)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+	_, err = NewCommandDiscountAmount(-1)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
 	fmt.Println("Completed testCommandDiscountAmount")
 }
 
+func TestCommandDiscountDepartment(t *testing.T) {
+	description := "BOTTLE"
+	commandDiscountDepartment, err := NewCommandDiscountDepartment(100, &description, 2)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandDiscountDepartment.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "\"BOTTLE\"100H2r" {
+		t.Errorf("Expected \"BOTTLE\"100H2r, got %s", command)
+	}
+
+	_, err = NewCommandDiscountDepartment(0, nil, 2)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+	_, err = NewCommandDiscountDepartment(100, nil, 0)
+	if !errors.Is(err, ErrInvalidDepartment) {
+		t.Errorf("Expected ErrInvalidDepartment, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandDiscountDepartment")
+}
+
+func TestCommandIncreaseAmount(t *testing.T) {
+	commandIncreaseAmount, err := NewCommandIncreaseAmount(150)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandIncreaseAmount.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "150H7M" {
+		t.Errorf("Expected 150H7M, got %s", command)
+	}
+
+	_, err = NewCommandIncreaseAmount(0)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+	_, err = NewCommandIncreaseAmount(-1)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandIncreaseAmount")
+}
+
+func TestCommandIncreasePercentage(t *testing.T) {
+	commandIncreasePercentage, err := NewCommandIncreasePercentage(10)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandIncreasePercentage.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "10.005M" {
+		t.Errorf("Expected 10.005M, got %s", command)
+	}
+
+	_, err = NewCommandIncreasePercentage(0)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+	_, err = NewCommandIncreasePercentage(-1)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandIncreasePercentage")
+}
+
+func TestCommandSubtotal(t *testing.T) {
+	commandSubtotal := NewCommandSubtotal()
+	command, err := commandSubtotal.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "=" {
+		t.Errorf("Expected =, got %s", command)
+	}
+
+	fmt.Println("Completed testCommandSubtotal")
+}
+
+func TestCommandDiscountPercentageSubtotal(t *testing.T) {
+	commandDiscountPercentageSubtotal, err := NewCommandDiscountPercentageSubtotal(12.5)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandDiscountPercentageSubtotal.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "12.502M" {
+		t.Errorf("Expected 12.502M, got %s", command)
+	}
+
+	_, err = NewCommandDiscountPercentageSubtotal(0)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+	_, err = NewCommandDiscountPercentageSubtotal(-1)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandDiscountPercentageSubtotal")
+}
+
+func TestCommandDiscountAmountSubtotal(t *testing.T) {
+	commandDiscountAmountSubtotal, err := NewCommandDiscountAmountSubtotal(1000)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandDiscountAmountSubtotal.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "1000H4M" {
+		t.Errorf("Expected 1000H4M, got %s", command)
+	}
+
+	_, err = NewCommandDiscountAmountSubtotal(0)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+	_, err = NewCommandDiscountAmountSubtotal(-1)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandDiscountAmountSubtotal")
+}
+
+func TestCommandIncreasePercentageSubtotal(t *testing.T) {
+	commandIncreasePercentageSubtotal, err := NewCommandIncreasePercentageSubtotal(5)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandIncreasePercentageSubtotal.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "5.006M" {
+		t.Errorf("Expected 5.006M, got %s", command)
+	}
+
+	_, err = NewCommandIncreasePercentageSubtotal(0)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+	_, err = NewCommandIncreasePercentageSubtotal(-1)
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandIncreasePercentageSubtotal")
+}
+
+func TestCommandIncreaseAmountSubtotal(t *testing.T) {
+	commandIncreaseAmountSubtotal, err := NewCommandIncreaseAmountSubtotal(150)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	command, err := commandIncreaseAmountSubtotal.get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "150H8M" {
+		t.Errorf("Expected 150H8M, got %s", command)
+	}
+
+	_, err = NewCommandIncreaseAmountSubtotal(0)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+	_, err = NewCommandIncreaseAmountSubtotal(-1)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandIncreaseAmountSubtotal")
+}
+
 func TestCommandBarcode(t *testing.T) {
 	commandBarcode, err := NewCommandBarcode("1234567890123")
 	if err != nil {
@@ -140,8 +464,8 @@ func TestCommandBarcode(t *testing.T) {
 	}
 
 	_, err = NewCommandBarcode("test")
-	if err == nil {
-		t.Errorf("Expected error != nil, got nil")
+	if !errors.Is(err, ErrInvalidBarcode) {
+		t.Errorf("Expected ErrInvalidBarcode, got %v", err)
 	}
 
 	fmt.Println("Completed testCommandBarcode")
@@ -237,3 +561,73 @@ func TestCommandDisplayMessage(t *testing.T) {
 
 	fmt.Println("Completed testCommandDisplayMessage")
 }
+
+func TestCommandFinancialReport(t *testing.T) {
+	for closure, expected := range map[bool]string{false: "1f", true: "8F"} {
+		command, err := NewCommandFinancialReport(closure).get()
+		if err != nil {
+			t.Errorf("Expected error = nil, got %s", err)
+		}
+		if command != expected {
+			t.Errorf("Expected %s, got %s", expected, command)
+		}
+	}
+
+	fmt.Println("Completed testCommandFinancialReport")
+}
+
+func TestCommandOpenCashDrawer(t *testing.T) {
+	command, err := NewCommandOpenCashDrawer().get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "a" {
+		t.Errorf("Expected a, got %s", command)
+	}
+
+	fmt.Println("Completed testCommandOpenCashDrawer")
+}
+
+func TestCommandSetDateTime(t *testing.T) {
+	command, err := NewCommandSetDateTime(time.Date(2023, 5, 17, 15, 30, 45, 0, time.Local)).get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "1705231530D" {
+		t.Errorf("Expected 1705231530D, got %s", command)
+	}
+
+	fmt.Println("Completed testCommandSetDateTime")
+}
+
+func TestCommandCancelDocument(t *testing.T) {
+	command, err := NewCommandCancelDocument().get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "k" {
+		t.Errorf("Expected k, got %s", command)
+	}
+
+	fmt.Println("Completed testCommandCancelDocument")
+}
+
+func TestCommandStatusRequest(t *testing.T) {
+	command, err := NewCommandStatusRequest().get()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command != "?" {
+		t.Errorf("Expected ?, got %s", command)
+	}
+
+	fmt.Println("Completed testCommandStatusRequest")
+}
+
+// mustCommand returns the command built by a constructor from valid arguments, for the command lists of the tests.
+func mustCommand(command Command, err error) Command {
+	if err != nil {
+		panic(err)
+	}
+	return command
+}
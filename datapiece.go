@@ -15,17 +15,39 @@ type Data struct {
 	separator SeparatorType
 }
 
+func NewData(variable string, separator SeparatorType) *Data {
+	return &Data{
+		variable:  variable,
+		separator: separator,
+	}
+}
+
+// Variable returns the value of the data piece, without separator.
+func (d *Data) Variable() string {
+	return d.variable
+}
+
+func (d *Data) Separator() SeparatorType {
+	return d.separator
+}
+
 func (d *Data) get() (string, error) {
 	switch d.separator {
-	case SeparatorTypeValue, SeparatorTypeMultiply:
+	case SeparatorTypeValue:
 		_, err := strconv.Atoi(d.variable)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidData, err)
+		}
+		return d.variable + string(d.separator), nil
+	case SeparatorTypeMultiply:
+		_, err := decodeQuantity(d.variable)
 		if err != nil {
 			return "", err
 		}
 		return d.variable + string(d.separator), nil
 	case SeparatorTypeDecimal:
 		if !strings.Contains(d.variable, ".") {
-			return "", fmt.Errorf("decimal variable must contain '.'")
+			return "", fmt.Errorf("%w: decimal variable must contain '.'", ErrInvalidData)
 		}
 		return d.variable, nil
 	case SeparatorTypeDescription:
@@ -33,7 +55,7 @@ func (d *Data) get() (string, error) {
 	case SeparatorTypeDescriptionDoubleHeight:
 		return string(d.separator) + d.variable + string(SeparatorTypeDescription), nil
 	default:
-		return "", fmt.Errorf("separatorType %s is not supported", d.separator)
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedSeparator, d.separator)
 	}
 }
 
@@ -59,6 +81,15 @@ func NewTerminator(variable *string, terminatorType TerminatorType) *Terminator
 	}
 }
 
+// Variable returns the value preceding the terminator type, nil if there is none.
+func (t *Terminator) Variable() *string {
+	return t.variable
+}
+
+func (t *Terminator) Type() TerminatorType {
+	return t.terminatorType
+}
+
 func (t *Terminator) get() (string, error) {
 	if t.variable != nil {
 		return *t.variable + string(t.terminatorType), nil
@@ -156,10 +187,13 @@ const (
 	TerminatorTypeDisableXonXoff2                        TerminatorType = "1492E"
 )
 
+// terminatorTypeStatusRequest is not an Epson terminator, it is sent by NewCommandStatusRequest to devices answering with replies.
+const terminatorTypeStatusRequest TerminatorType = "?"
+
 // GetTerminatorTypePaymentLight is used for custom payments
 func GetTerminatorTypePaymentLight(paymentMethodCode string) (TerminatorType, error) {
 	if len(paymentMethodCode) != 3 {
-		return "", fmt.Errorf("paymentMethodCode must be 3 characters long")
+		return "", ErrInvalidPaymentMethodCode
 	}
 	return TerminatorType(paymentMethodCode + "T"), nil
 }
@@ -1,20 +1,21 @@
 package gongoff
 
 import (
-	"errors"
 	"fmt"
 	"time"
 )
 
+// Document is a set of commands sent to the printer together.
 type Document interface {
-	get() []Command
+	// Commands returns the commands sent to the printer for the document, in order.
+	Commands() []Command
 }
 
 type DocumentGeneric struct {
 	commands []Command
 }
 
-func (d *DocumentGeneric) get() []Command {
+func (d *DocumentGeneric) Commands() []Command {
 	return d.commands
 }
 
@@ -50,6 +51,10 @@ type DocumentCommercial struct {
 	DocumentGeneric
 }
 
+// NewDocumentCommercial creates a commercial document selling the products, paid with the payments.
+// The discounts are optional and apply to the subtotal of the products: they are printed after a subtotal
+// as the equivalent subtotal discounts, since the commands themselves apply to the last product only.
+// Use NewDocumentCommercialWithItems to discount single products.
 func NewDocumentCommercial(
 	commandsProduct []CommandProduct,
 	commandsPayment []CommandPayment,
@@ -62,11 +67,14 @@ func NewDocumentCommercial(
 	for i := range commandsProduct {
 		commands = append(commands, &commandsProduct[i])
 	}
+	if commandDiscountAmount != nil || commandDiscountPercentage != nil {
+		commands = append(commands, NewCommandSubtotal())
+	}
 	if commandDiscountAmount != nil {
-		commands = append(commands, commandDiscountAmount)
+		commands = append(commands, newCommandDiscountAmountSubtotal(commandDiscountAmount.discountAmount))
 	}
 	if commandDiscountPercentage != nil {
-		commands = append(commands, commandDiscountPercentage)
+		commands = append(commands, newCommandDiscountPercentageSubtotal(commandDiscountPercentage.discountPercentage))
 	}
 	if commandCI != nil {
 		commands = append(commands, commandCI)
@@ -84,6 +92,73 @@ func NewDocumentCommercial(
 	}
 }
 
+// DocumentItem is a sale of a commercial document with the discounts and increases applied to it.
+// Sale is a *CommandProduct, *CommandProductPLU or *CommandDiscountDepartment.
+// Adjustments are printed right after the sale, they can be *CommandDiscountPercentage, *CommandDiscountAmount,
+// *CommandIncreasePercentage and *CommandIncreaseAmount.
+type DocumentItem struct {
+	Sale        Command
+	Adjustments []Command
+}
+
+// NewDocumentCommercialWithItems creates a commercial document attaching discounts and increases to specific items or to the subtotal.
+// Each item is followed by its adjustments. subtotalAdjustments (*CommandDiscountPercentageSubtotal, *CommandDiscountAmountSubtotal,
+// *CommandIncreasePercentageSubtotal and *CommandIncreaseAmountSubtotal) follow a CommandSubtotal printed after the last item.
+func NewDocumentCommercialWithItems(
+	items []DocumentItem,
+	subtotalAdjustments []Command,
+	commandsPayment []CommandPayment,
+	commandCI *CommandCustomerIdentifier,
+	commandTrailer *CommandTrailer) (*DocumentCommercial, error) {
+
+	if len(items) == 0 {
+		return nil, ErrMissingProducts
+	}
+
+	var commands []Command
+	for _, item := range items {
+		switch item.Sale.(type) {
+		case *CommandProduct, *CommandProductPLU, *CommandDiscountDepartment:
+		default:
+			return nil, fmt.Errorf("%w: %T is not a sale", ErrInvalidDocumentOrder, item.Sale)
+		}
+		commands = append(commands, item.Sale)
+		for _, adjustment := range item.Adjustments {
+			switch adjustment.(type) {
+			case *CommandDiscountPercentage, *CommandDiscountAmount, *CommandIncreasePercentage, *CommandIncreaseAmount:
+			default:
+				return nil, fmt.Errorf("%w: %T cannot adjust an item", ErrInvalidDocumentOrder, adjustment)
+			}
+			commands = append(commands, adjustment)
+		}
+	}
+	if len(subtotalAdjustments) > 0 {
+		commands = append(commands, NewCommandSubtotal())
+	}
+	for _, adjustment := range subtotalAdjustments {
+		switch adjustment.(type) {
+		case *CommandDiscountPercentageSubtotal, *CommandDiscountAmountSubtotal, *CommandIncreasePercentageSubtotal, *CommandIncreaseAmountSubtotal:
+		default:
+			return nil, fmt.Errorf("%w: %T cannot adjust the subtotal", ErrInvalidDocumentOrder, adjustment)
+		}
+		commands = append(commands, adjustment)
+	}
+	if commandCI != nil {
+		commands = append(commands, commandCI)
+	}
+	if commandTrailer != nil {
+		commands = append(commands, commandTrailer)
+	}
+	for i := range commandsPayment {
+		commands = append(commands, &commandsPayment[i])
+	}
+	return &DocumentCommercial{
+		DocumentGeneric: DocumentGeneric{
+			commands: commands,
+		},
+	}, nil
+}
+
 // DocumentManagement is a generic document useful for testing purposes and generic text print.
 type DocumentManagement struct {
 	DocumentGeneric
@@ -113,6 +188,7 @@ func NewDocumentManagement(rows []string) *DocumentManagement {
 		DocumentGeneric: DocumentGeneric{
 			commands: commands,
 		},
+		rows: rows,
 	}
 
 }
@@ -237,13 +313,13 @@ func NewDocumentInvoice(
 	payments []CommandPayment) (*DocumentInvoice, error) {
 
 	if len(customerDetails) == 0 || len(customerDetails) > 5 {
-		return nil, errors.New("invalid number of customer details commands, must be between 1 and 5")
+		return nil, ErrInvalidCustomerDetails
 	}
 	if len(products) == 0 {
-		return nil, errors.New("invalid number of products commands, must be at least 1")
+		return nil, ErrMissingProducts
 	}
 	if len(payments) == 0 {
-		return nil, errors.New("invalid number of payments commands, must be at least 1")
+		return nil, ErrMissingPayments
 	}
 
 	var commands []Command
@@ -1,6 +1,7 @@
 package gongoff
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -27,35 +28,105 @@ func TestDocumentCommercial(t *testing.T) {
 		NewCommandTrailer("Hello World!"),
 	)
 
-	commands := commercialDoc.get()
+	commands := commercialDoc.Commands()
 	if len(commands) != 3 {
 		t.Errorf("Expected 3 commands, got %d", len(commands))
 	}
 
+	discountAmount, _ := NewCommandDiscountAmount(50)
+	discountPercentage, _ := NewCommandDiscountPercentage(10)
+	commercialDoc = NewDocumentCommercial(
+		[]CommandProduct{*commandProduct, *NewCommandProduct(300, nil, nil, nil)},
+		[]CommandPayment{*commandPayment},
+		discountAmount,
+		discountPercentage,
+		nil,
+		nil,
+	)
+	// The discounts apply to the subtotal, not to the last product.
+	expected := []string{"\"BREAD\"750H1R", "300H1R", "=", "50H4M", "10.002M"}
+	commands = commercialDoc.Commands()
+	if len(commands) != 6 {
+		t.Fatalf("Expected 6 commands, got %d", len(commands))
+	}
+	for i, e := range expected {
+		encoded, _ := commands[i].Encode()
+		if string(encoded) != e {
+			t.Errorf("Expected command %d = %s, got %s", i, e, encoded)
+		}
+	}
+
 	fmt.Println("Completed testDocumentCommercial")
 }
 
+func TestDocumentCommercialWithItems(t *testing.T) {
+
+	commandPayment, err := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	commercialDoc, err := NewDocumentCommercialWithItems(
+		[]DocumentItem{
+			{Sale: NewCommandProduct(750, nil, nil, nil), Adjustments: []Command{mustCommand(NewCommandDiscountAmount(50))}},
+			{Sale: NewCommandProduct(300, nil, nil, nil)},
+		},
+		[]Command{mustCommand(NewCommandDiscountPercentageSubtotal(10))},
+		[]CommandPayment{*commandPayment},
+		nil,
+		NewCommandTrailer("Hello World!"),
+	)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	expected := []string{"750H1R", "50H3M", "300H1R", "=", "10.002M"}
+	commands := commercialDoc.Commands()
+	if len(commands) != 7 {
+		t.Fatalf("Expected 7 commands, got %d", len(commands))
+	}
+	for i, e := range expected {
+		encoded, _ := commands[i].Encode()
+		if string(encoded) != e {
+			t.Errorf("Expected command %d = %s, got %s", i, e, encoded)
+		}
+	}
+
+	_, err = NewDocumentCommercialWithItems(
+		[]DocumentItem{{Sale: NewCommandProduct(750, nil, nil, nil), Adjustments: []Command{mustCommand(NewCommandDiscountAmountSubtotal(50))}}},
+		nil, nil, nil, nil,
+	)
+	if !errors.Is(err, ErrInvalidDocumentOrder) {
+		t.Errorf("Expected ErrInvalidDocumentOrder, got %v", err)
+	}
+	_, err = NewDocumentCommercialWithItems(nil, nil, nil, nil, nil)
+	if !errors.Is(err, ErrMissingProducts) {
+		t.Errorf("Expected ErrMissingProducts, got %v", err)
+	}
+
+	fmt.Println("Completed testDocumentCommercialWithItems")
+}
+
 func TestDocumentManagement(t *testing.T) {
 
 	managementDoc := NewDocumentManagement([]string{"test", "test2", "test3"})
-	commands := managementDoc.get()
+	commands := managementDoc.Commands()
 	if len(commands) != 5 {
 		t.Errorf("Expected 5 commands, got %d", len(commands))
 	}
 
-	openCommand, err := commands[0].get()
+	openCommand, err := commands[0].Encode()
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
 	}
-	if openCommand != "j" {
+	if string(openCommand) != "j" {
 		t.Errorf("Expected j, got %s", openCommand)
 	}
 
-	closeCommand, err := commands[4].get()
+	closeCommand, err := commands[4].Encode()
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
 	}
-	if closeCommand != "J" {
+	if string(closeCommand) != "J" {
 		t.Errorf("Expected J, got %s", closeCommand)
 	}
 
@@ -67,7 +138,7 @@ func TestDocumentCommercialReturn(t *testing.T) {
 	documentId := NewDocumentId(1, 2, time.Now(), nil)
 	commandOpenDocumentCommercialReturn := NewCommandOpenDocumentCommercialReturn(*documentId)
 	documentCommercialReturn := NewDocumentCommercialReturn(*commandOpenDocumentCommercialReturn, nil, nil)
-	commands := documentCommercialReturn.get()
+	commands := documentCommercialReturn.Commands()
 	if len(commands) != 1 {
 		t.Errorf("Expected 1 commands, got %d", len(commands))
 	}
@@ -80,7 +151,7 @@ func TestDocumentCommercialCancellation(t *testing.T) {
 	documentId := NewDocumentId(1, 2, time.Now(), nil)
 	commandOpenDocumentCommercialCancellation := NewCommandOpenDocumentCommercialCancellation(*documentId)
 	documentCommercialCancellation := NewDocumentCommercialCancellation(*commandOpenDocumentCommercialCancellation, nil, nil)
-	commands := documentCommercialCancellation.get()
+	commands := documentCommercialCancellation.Commands()
 	if len(commands) != 1 {
 		t.Errorf("Expected 1 commands, got %d", len(commands))
 	}
@@ -92,7 +163,7 @@ func TestDocumentPOSReturn(t *testing.T) {
 
 	commandOpenDocumentPOSReturn := NewCommandOpenDocumentPOSReturn(time.Now())
 	documentPOSReturn := NewDocumentPOSReturn(*commandOpenDocumentPOSReturn, nil, nil)
-	commands := documentPOSReturn.get()
+	commands := documentPOSReturn.Commands()
 	if len(commands) != 1 {
 		t.Errorf("Expected 1 commands, got %d", len(commands))
 	}
@@ -104,7 +175,7 @@ func TestDocumentPOSCancellation(t *testing.T) {
 
 	commandOpenDocumentPOSCancellation := NewCommandOpenDocumentPOSCancellation(time.Now())
 	documentPOSCancellation := NewDocumentPOSCancellation(*commandOpenDocumentPOSCancellation, nil, nil)
-	commands := documentPOSCancellation.get()
+	commands := documentPOSCancellation.Commands()
 	if len(commands) != 1 {
 		t.Errorf("Expected 1 commands, got %d", len(commands))
 	}
@@ -134,7 +205,7 @@ func TestDocumentInvoice(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
 	}
-	commands := documentInvoice.get()
+	commands := documentInvoice.Commands()
 	if len(commands) != 4 {
 		t.Errorf("Expected 4 commands, got %d", len(commands))
 	}
@@ -173,7 +244,7 @@ func TestDocumentCommercialWithInvoice(t *testing.T) {
 	if err != nil {
 		t.Errorf("Expected error = nil, got %s", err)
 	}
-	commands := documentCommercial.get()
+	commands := documentCommercial.Commands()
 	if len(commands) != 2 {
 		t.Errorf("Expected 3 commands, got %d", len(commands))
 	}
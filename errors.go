@@ -0,0 +1,150 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validation errors returned by the command and document constructors.
+var (
+	ErrInvalidPaymentMethod      = errors.New("payment method Terminator must end with 'T'")
+	ErrInvalidPaymentMethodCode  = errors.New("paymentMethodCode must be 3 characters long")
+	ErrInvalidCustomerIdentifier = errors.New("customer identifier must be 16, 11 or 8 characters long")
+	ErrInvalidBarcode            = errors.New("barcode must be 13 or 8 characters long")
+	ErrInvalidPLU                = errors.New("PLU number must be greater than zero")
+	ErrInvalidDepartment         = errors.New("department must be greater than zero")
+	ErrInvalidQuantity           = errors.New("quantity must be greater than zero")
+	ErrQuantityPrecision         = errors.New("quantity can have at most 3 decimal digits")
+	ErrQuantityTooLarge          = errors.New("quantity can have at most 4 integer digits")
+	ErrInvalidCustomerDetails    = errors.New("invalid number of customer details commands, must be between 1 and 5")
+	ErrMissingProducts           = errors.New("invalid number of products commands, must be at least 1")
+	ErrMissingPayments           = errors.New("invalid number of payments commands, must be at least 1")
+	ErrDiscountWithoutProduct    = errors.New("discount must follow a product")
+	ErrVoidWithoutItem           = errors.New("no registered item matches the void")
+	ErrNegativeAmount            = errors.New("amount cannot be negative")
+	ErrMissingVATRate            = errors.New("missing VAT rate")
+	ErrInvalidDocumentOrder      = errors.New("commands are not in the order required by the printer")
+	ErrZeroPrice                 = errors.New("product price must be greater than zero")
+	ErrNegativeTotal             = errors.New("document total cannot be negative")
+	ErrInsufficientPayment       = errors.New("payments do not cover the document total")
+	ErrNonCashOverpayment        = errors.New("only cash payments can exceed the amount due")
+	ErrProgrammedPLU             = errors.New("price and department of the PLU are programmed in the printer")
+	ErrInvalidData               = errors.New("invalid data")
+	ErrInvalidAmount             = errors.New("invalid amount")
+	ErrUnsupportedSeparator      = errors.New("separatorType is not supported")
+)
+
+// Parsing errors returned by ParseCommand, ParseCommands and ParseNext.
+var (
+	ErrInvalidCommand    = errors.New("invalid command")
+	ErrIncompleteCommand = errors.New("incomplete command")
+)
+
+// Schema errors returned when decoding documents and commands.
+var (
+	ErrInvalidSchema            = errors.New("invalid document schema")
+	ErrUnsupportedSchemaVersion = errors.New("unsupported document schema version")
+	ErrUnknownDocumentType      = errors.New("unknown document type")
+	ErrUnknownCommandType       = errors.New("unknown command type")
+)
+
+// Connection errors returned by the printers.
+var (
+	ErrNoSerialPorts        = errors.New("no serial ports found")
+	ErrSerialPortNotFound   = errors.New("chosen serial port not found")
+	ErrInvalidSerialOptions = errors.New("invalid serial options")
+	ErrPrinterNotOpen       = errors.New("printer is not open")
+	ErrConnectionLost       = errors.New("connection with the printer lost")
+	ErrConnectionBroken     = errors.New("a command was interrupted halfway, the printer must be closed and opened again")
+	ErrSessionEnded         = errors.New("printer session has ended")
+	ErrReceiptClosed        = errors.New("receipt session is closed")
+	ErrReceiptUncertain     = errors.New("receipt session lost track of the printer, the last command may have been printed")
+	ErrResponseTimeout      = errors.New("timed out waiting for printer response")
+	ErrRepliesDisabled      = errors.New("printer replies are disabled")
+	ErrStatusUnsupported    = errors.New("printer does not support status requests")
+	ErrFlowControlTimeout   = errors.New("timed out waiting for XON from printer")
+)
+
+// Conditions reported by PrinterStatus.ReadyForSale.
+var (
+	ErrPaperOut       = errors.New("printer is out of paper")
+	ErrCoverOpen      = errors.New("printer cover is open")
+	ErrClosureOverdue = errors.New("daily closure is overdue")
+	ErrDocumentOpen   = errors.New("a document is open on the printer")
+)
+
+// ErrorCode is the code of a command refused with an ERR reply, see GenericPrinter.SetReplies.
+// The codes are not interpreted: their meaning depends on the device answering, ex. gongofftest.Emulator,
+// classify them with an ErrorTable describing the device.
+// ErrorCode implements error so that errors.Is(err, ErrorCode(8)) matches any PrinterError with that code.
+type ErrorCode int
+
+func (c ErrorCode) Error() string {
+	return fmt.Sprintf("printer error %d", int(c))
+}
+
+// PrinterError is returned when the printer refuses a command.
+type PrinterError struct {
+	Code ErrorCode
+	// Message is the text following the code in the reply, if any.
+	Message string
+	// Command is the command refused by the printer, nil if unknown.
+	Command Command
+}
+
+func newPrinterError(code ErrorCode, message string, command Command) *PrinterError {
+	return &PrinterError{
+		Code:    code,
+		Message: message,
+		Command: command,
+	}
+}
+
+func (e *PrinterError) Error() string {
+	if e.Message == "" {
+		return e.Code.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Code.Error(), e.Message)
+}
+
+// Is makes errors.Is match a PrinterError against its ErrorCode or against a PrinterError with the same code.
+func (e *PrinterError) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorCode:
+		return e.Code == t
+	case *PrinterError:
+		return e.Code == t.Code
+	default:
+		return false
+	}
+}
+
+// ErrorDescription describes an error code of a device, see ErrorTable.
+type ErrorDescription struct {
+	Message string
+	// Recoverable codes are refusals caused by a condition of the printer, ex. the paper end or the cover open:
+	// the command can be sent again once the condition is fixed. Other codes refuse the command itself.
+	Recoverable bool
+}
+
+// ErrorTable classifies the codes of the refused commands. The codes are not defined by the Epson protocol,
+// so the table describes the device or gateway answering, ex. gongofftest.ErrorTable for the emulator.
+type ErrorTable map[ErrorCode]ErrorDescription
+
+// Describe returns the description of the code of the *PrinterError in err,
+// false if err is not a *PrinterError or its code is not in the table.
+func (t ErrorTable) Describe(err error) (ErrorDescription, bool) {
+	var printerErr *PrinterError
+	if !errors.As(err, &printerErr) {
+		return ErrorDescription{}, false
+	}
+	description, ok := t[printerErr.Code]
+	return description, ok
+}
+
+// Recoverable reports whether err is a *PrinterError whose code is recoverable in the table.
+// Unknown codes are not recoverable.
+func (t ErrorTable) Recoverable(err error) bool {
+	description, ok := t.Describe(err)
+	return ok && description.Recoverable
+}
@@ -0,0 +1,89 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPrinterError(t *testing.T) {
+
+	command := NewCommandDisplayMessage("test", 1)
+	var err error = newPrinterError(ErrorCode(8), "PAPER END", command)
+
+	if !errors.Is(err, ErrorCode(8)) {
+		t.Errorf("Expected errors.Is(err, ErrorCode(8))")
+	}
+	if errors.Is(err, ErrorCode(9)) {
+		t.Errorf("Expected !errors.Is(err, ErrorCode(9))")
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", err), &PrinterError{Code: ErrorCode(8)}) {
+		t.Errorf("Expected wrapped error to match PrinterError with the same code")
+	}
+
+	var printerError *PrinterError
+	if !errors.As(err, &printerError) {
+		t.Fatalf("Expected *PrinterError, got %T", err)
+	}
+	if printerError.Message != "PAPER END" || printerError.Command != command {
+		t.Errorf("Expected PAPER END error for the command, got %+v", printerError)
+	}
+	if err.Error() != "printer error 8: PAPER END" {
+		t.Errorf("Expected printer error 8: PAPER END, got %s", err.Error())
+	}
+	if err = newPrinterError(ErrorCode(999), "", nil); err.Error() != "printer error 999" {
+		t.Errorf("Expected printer error 999, got %s", err.Error())
+	}
+
+	fmt.Println("Completed testPrinterError")
+}
+
+func TestErrorTable(t *testing.T) {
+
+	table := ErrorTable{
+		8: {Message: "PAPER END", Recoverable: true},
+		2: {Message: "INVALID VALUE"},
+	}
+
+	paperEnd := fmt.Errorf("wrapped: %w", newPrinterError(8, "", nil))
+	description, ok := table.Describe(paperEnd)
+	if !ok || description.Message != "PAPER END" {
+		t.Errorf("Expected PAPER END, got %+v", description)
+	}
+	if !table.Recoverable(paperEnd) {
+		t.Errorf("Expected paper end to be recoverable")
+	}
+	if table.Recoverable(newPrinterError(2, "", nil)) {
+		t.Errorf("Expected invalid value not to be recoverable")
+	}
+	if _, ok := table.Describe(newPrinterError(99, "", nil)); ok || table.Recoverable(newPrinterError(99, "", nil)) {
+		t.Errorf("Expected unknown code not to be described nor recoverable")
+	}
+	if table.Recoverable(ErrPrinterNotOpen) {
+		t.Errorf("Expected errors other than PrinterError not to be recoverable")
+	}
+
+	fmt.Println("Completed testErrorTable")
+}
+
+func TestDataErrors(t *testing.T) {
+
+	data := Data{variable: "abc", separator: SeparatorTypeValue}
+	_, err := data.get()
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData, got %v", err)
+	}
+
+	data = Data{variable: "1", separator: SeparatorType("?")}
+	_, err = data.get()
+	if !errors.Is(err, ErrUnsupportedSeparator) {
+		t.Errorf("Expected ErrUnsupportedSeparator, got %v", err)
+	}
+
+	_, err = GetTerminatorTypePaymentLight("12")
+	if !errors.Is(err, ErrInvalidPaymentMethodCode) {
+		t.Errorf("Expected ErrInvalidPaymentMethodCode, got %v", err)
+	}
+
+	fmt.Println("Completed testDataErrors")
+}
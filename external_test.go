@@ -0,0 +1,409 @@
+package gongoff_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/gongofftest"
+)
+
+// openDrawer is a command implemented outside the package.
+type openDrawer struct{}
+
+func (openDrawer) Encode() ([]byte, error) {
+	return []byte(gongoff.TerminatorTypeOpenCashRegister), nil
+}
+
+// recordingPrinter is a printer implemented outside the package, recording the encoded commands.
+type recordingPrinter struct {
+	open    bool
+	printed []string
+}
+
+var _ gongoff.Printer = (*recordingPrinter)(nil)
+
+func (p *recordingPrinter) Open() error  { p.open = true; return nil }
+func (p *recordingPrinter) IsOpen() bool { return p.open }
+func (p *recordingPrinter) Close() error { p.open = false; return nil }
+
+func (p *recordingPrinter) PrintDocument(doc gongoff.Document) error {
+	return p.PrintCommands(doc.Commands())
+}
+
+func (p *recordingPrinter) PrintCommands(commands []gongoff.Command) error {
+	for _, command := range commands {
+		encoded, err := command.Encode()
+		if err != nil {
+			return err
+		}
+		p.printed = append(p.printed, string(encoded))
+	}
+	return nil
+}
+
+func TestExternalCommand(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := emulator.Printer()
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	err = printer.PrintCommands([]gongoff.Command{openDrawer{}})
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.DrawerOpened != 1 {
+		t.Errorf("Expected drawer opened once, got %d", state.DrawerOpened)
+	}
+
+	fmt.Println("Completed testExternalCommand")
+}
+
+func TestExternalPrinter(t *testing.T) {
+
+	printer := &recordingPrinter{}
+	err := printer.PrintDocument(gongoff.NewDocumentManagement([]string{"test"}))
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if len(printer.printed) != 3 || printer.printed[1] != "\"test\"@" {
+		t.Errorf("Expected 3 commands, got %v", printer.printed)
+	}
+
+	// The optional interfaces are not implemented, the helpers fall back to the Printer methods.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = gongoff.PrintCommandsContext(ctx, printer, []gongoff.Command{openDrawer{}})
+	if !errors.Is(err, context.Canceled) || len(printer.printed) != 3 {
+		t.Errorf("Expected context.Canceled and nothing printed, got %v", err)
+	}
+	_, err = gongoff.StatusContext(context.Background(), printer)
+	if !errors.Is(err, gongoff.ErrStatusUnsupported) {
+		t.Errorf("Expected ErrStatusUnsupported, got %v", err)
+	}
+	err = gongoff.WithSession(printer, func(session gongoff.Session) error {
+		return session.PrintCommands([]gongoff.Command{openDrawer{}})
+	})
+	if err != nil || len(printer.printed) != 4 {
+		t.Errorf("Expected the session to print on the printer, got %v", err)
+	}
+
+	fmt.Println("Completed testExternalPrinter")
+}
+
+func TestExternalCommandSchema(t *testing.T) {
+
+	schema, err := gongoff.NewCommandSchema(openDrawer{})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if schema.Type != gongoff.CommandTypeRaw {
+		t.Errorf("Expected raw command, got %s", schema.Type)
+	}
+	command, err := schema.Command()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	encoded, _ := command.Encode()
+	if string(encoded) != string(gongoff.TerminatorTypeOpenCashRegister) {
+		t.Errorf("Expected %s, got %s", gongoff.TerminatorTypeOpenCashRegister, encoded)
+	}
+
+	fmt.Println("Completed testExternalCommandSchema")
+}
+
+func TestNetworkPrinterReconnect(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+
+	var transitions []bool
+	options := gongoff.NetworkOptions{
+		ReconnectDelay:       5 * time.Millisecond,
+		MaxReconnectDelay:    20 * time.Millisecond,
+		MaxReconnectAttempts: 3,
+		OnConnectionChange:   func(connected bool) { transitions = append(transitions, connected) },
+	}
+	printer := gongoff.NewNetworkPrinterWithOptions(emulator.Host(), emulator.Port(), options)
+	printer.SetReplies(true)
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	// The connection drops between two commands, the next one is sent on a new connection.
+	emulator.Disconnect()
+	time.Sleep(20 * time.Millisecond)
+	payment, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, nil, nil)
+	err = printer.PrintCommands([]gongoff.Command{payment})
+	if err != nil {
+		t.Fatalf("Expected error = nil after reconnecting, got %s", err)
+	}
+	if state := emulator.State(); state.Document != gongofftest.DocumentNone || state.DailyTotal != 750 {
+		t.Errorf("Expected closed receipt of 7,50, got %s", state)
+	}
+	err = printer.Ping(context.Background())
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	// The printer is gone, reconnecting gives up after the maximum attempts.
+	emulator.Close()
+	time.Sleep(20 * time.Millisecond)
+	err = printer.Ping(context.Background())
+	var opErr *net.OpError
+	if !errors.Is(err, gongoff.ErrConnectionLost) || !errors.As(err, &opErr) || opErr.Op != "dial" {
+		t.Errorf("Expected ErrConnectionLost caused by the dial, got %v", err)
+	}
+	if printer.IsOpen() {
+		t.Errorf("Expected printer to be disconnected")
+	}
+
+	expected := []bool{true, false, true, false}
+	if fmt.Sprint(transitions) != fmt.Sprint(expected) {
+		t.Errorf("Expected transitions %v, got %v", expected, transitions)
+	}
+
+	fmt.Println("Completed testNetworkPrinterReconnect")
+}
+
+func TestNetworkPrinterSessionConnectionLost(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	options := gongoff.NetworkOptions{ReconnectDelay: 5 * time.Millisecond, MaxReconnectAttempts: 3}
+	printer := gongoff.NewNetworkPrinterWithOptions(emulator.Host(), emulator.Port(), options)
+	printer.SetReplies(true)
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	// The connection drops in the middle of the session, the document is not continued on a new connection.
+	payment, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, nil, nil)
+	err = printer.Session(func(session gongoff.Session) error {
+		err := session.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		emulator.Disconnect()
+		time.Sleep(20 * time.Millisecond)
+		return session.PrintCommands([]gongoff.Command{payment})
+	})
+	if !errors.Is(err, gongoff.ErrConnectionLost) {
+		t.Fatalf("Expected ErrConnectionLost, got %v", err)
+	}
+	if received := emulator.Received(); len(received) != 1 {
+		t.Errorf("Expected only the first command sent, got %v", received)
+	}
+
+	// The next session reconnects.
+	err = printer.Session(func(session gongoff.Session) error {
+		return session.PrintCommands([]gongoff.Command{payment})
+	})
+	if err != nil {
+		t.Errorf("Expected error = nil after reconnecting, got %s", err)
+	}
+
+	fmt.Println("Completed testNetworkPrinterSessionConnectionLost")
+}
+func TestNetworkPrinterNoReconnect(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := gongoff.NewNetworkPrinterWithOptions(emulator.Host(), emulator.Port(), gongoff.NetworkOptions{})
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	emulator.Disconnect()
+	time.Sleep(20 * time.Millisecond)
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandOpenCashDrawer()})
+	if !errors.Is(err, gongoff.ErrConnectionLost) {
+		t.Errorf("Expected ErrConnectionLost, got %v", err)
+	}
+	if len(emulator.Received()) != 0 {
+		t.Errorf("Expected no command received, got %v", emulator.Received())
+	}
+
+	// Opening again connects as usual.
+	err = printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandOpenCashDrawer()})
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	fmt.Println("Completed testNetworkPrinterNoReconnect")
+}
+
+func TestNetworkPrinterProbe(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	options := gongoff.DefaultNetworkOptions
+	options.ProbeInterval = time.Millisecond
+	printer := gongoff.NewNetworkPrinterWithOptions(emulator.Host(), emulator.Port(), options)
+	printer.SetReplies(true)
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	time.Sleep(5 * time.Millisecond)
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandOpenCashDrawer()})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	received := emulator.Received()
+	if len(received) != 2 || received[0] != "?" || received[1] != "a" {
+		t.Errorf("Expected status probe before the command, got %v", received)
+	}
+
+	fmt.Println("Completed testNetworkPrinterProbe")
+}
+
+func TestPrinterConcurrentDocuments(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := emulator.Printer()
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	// Interleaved commands would be refused since a management document is already open.
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			errs <- printer.PrintDocument(gongoff.NewDocumentManagement([]string{fmt.Sprint("line ", i), "end"}))
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		err = <-errs
+		if err != nil {
+			t.Errorf("Expected error = nil, got %s", err)
+		}
+	}
+	if state := emulator.State(); state.DocumentNumber != 10 {
+		t.Errorf("Expected 10 documents, got %s", state)
+	}
+
+	fmt.Println("Completed testPrinterConcurrentDocuments")
+}
+
+func TestPrinterSession(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := emulator.Printer()
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	started := make(chan struct{})
+	drawer := make(chan error)
+	var ended gongoff.Session
+	err = printer.Session(func(session gongoff.Session) error {
+		ended = session
+		err := session.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+		if err != nil {
+			return err
+		}
+		go func() {
+			close(started)
+			drawer <- printer.PrintCommands([]gongoff.Command{gongoff.NewCommandOpenCashDrawer()})
+		}()
+		<-started
+		time.Sleep(20 * time.Millisecond)
+		payment, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, nil, nil)
+		return session.PrintCommands([]gongoff.Command{payment})
+	})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = <-drawer
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	received := emulator.Received()
+	if len(received) != 3 || received[2] != "a" {
+		t.Errorf("Expected the drawer to open after the session, got %v", received)
+	}
+
+	err = ended.PrintCommands([]gongoff.Command{gongoff.NewCommandOpenCashDrawer()})
+	if !errors.Is(err, gongoff.ErrSessionEnded) {
+		t.Errorf("Expected ErrSessionEnded, got %v", err)
+	}
+
+	fmt.Println("Completed testPrinterSession")
+}
+
+func TestPrinterPoolFailoverLostPrinter(t *testing.T) {
+
+	first := gongofftest.NewEmulator()
+	defer first.Close()
+	second := gongofftest.NewEmulator()
+	defer second.Close()
+	options := gongoff.NetworkOptions{ReconnectDelay: 5 * time.Millisecond, MaxReconnectAttempts: 2}
+	var printers []gongoff.Printer
+	for _, emulator := range []*gongofftest.Emulator{first, second} {
+		printer := gongoff.NewNetworkPrinterWithOptions(emulator.Host(), emulator.Port(), options)
+		printer.SetReplies(true)
+		printers = append(printers, printer)
+	}
+	pool := gongoff.NewPrinterPool(printers, gongoff.PoolOptions{Policy: gongoff.PoolFailover})
+	err := pool.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer pool.Close()
+
+	// The first printer goes down after Open, the document is printed by the second one.
+	first.Close()
+	time.Sleep(20 * time.Millisecond)
+	doc, err := gongoff.NewReceiptBuilder().AddItem("BREAD", 750, 1).PayRest(gongoff.TerminatorTypePaymentCash).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pool.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if len(first.Received()) != 0 {
+		t.Errorf("Expected nothing received by the first printer, got %v", first.Received())
+	}
+	if state := second.State(); state.DailyTotal != 750 {
+		t.Errorf("Expected receipt of 7,50 on the second printer, got %s", state)
+	}
+	if health := pool.Health(); health[0].Healthy || !health[1].Healthy {
+		t.Errorf("Expected only the first printer unhealthy, got %+v", health)
+	}
+
+	fmt.Println("Completed testPrinterPoolFailoverLostPrinter")
+}
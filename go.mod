@@ -3,7 +3,9 @@ module github.com/paolo96/gongoff
 go 1.18
 
 require (
-	github.com/creack/goselect v0.1.2 // indirect
-	go.bug.st/serial v1.3.5 // indirect
-	golang.org/x/sys v0.0.0-20210823070655-63515b42dcdf // indirect
+	go.bug.st/serial v1.3.5
+	golang.org/x/sys v0.0.0-20210823070655-63515b42dcdf
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/creack/goselect v0.1.2 // indirect
@@ -0,0 +1,563 @@
+// Package gongoffhttp exposes fiscal printers to non-Go clients through a REST API.
+//
+// Endpoints:
+//
+//	GET  /health                          state of every printer, 503 Service Unavailable if one has a device fault
+//	POST /printers/{name}/documents       print the JSON document in the body (see gongoff.DocumentSchema)
+//	POST /printers/{name}/reports/{x|z}   print the X report or the Z report with fiscal closure
+//	GET  /jobs/{id}                       state of a job
+//
+// Documents and reports are queued as jobs and the endpoints answer 202 Accepted with the job.
+// Adding ?wait=true waits for the job to finish and answers 200 OK, or 502 Bad Gateway if the job failed or is uncertain.
+// Each printer executes one job at a time, so concurrent requests never interleave commands.
+// A job whose connection failed after part of it may have reached the printer is uncertain, not failed:
+// check the printer before submitting it again, since it could be printed twice.
+// Job IDs are random, so they are not reused after a restart of the server.
+//
+// /health opens and checks the idle printers, the busy ones report the outcome of their last job.
+// Device faults, ex. an unreachable printer or out of paper, are reported as error; the refusal of a document
+// by the printer only as lastError, since the printer can still print the next one.
+package gongoffhttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paolo96/gongoff"
+)
+
+const (
+	// maxDocumentSize is the maximum size of a document in a request body.
+	maxDocumentSize = 1 << 20
+	// jobRetention is how long finished jobs can be queried.
+	jobRetention = time.Hour
+	// checkTimeout is the maximum time /health waits for a printer check.
+	checkTimeout = 5 * time.Second
+	queueSize    = 64
+)
+
+// JobStatus is the state of a job.
+type JobStatus string
+
+const (
+	JobStatusQueued   JobStatus = "queued"
+	JobStatusPrinting JobStatus = "printing"
+	JobStatusPrinted  JobStatus = "printed"
+	JobStatusFailed   JobStatus = "failed"
+	// JobStatusUncertain jobs failed after part of their commands may have reached the printer,
+	// the printer may hold the document open or have printed it.
+	JobStatusUncertain JobStatus = "uncertain"
+)
+
+// Job is a document or report queued on a printer.
+type Job struct {
+	ID      string    `json:"id"`
+	Printer string    `json:"printer"`
+	Status  JobStatus `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	// Sent is the number of commands accepted by the printer.
+	Sent int `json:"sent"`
+	// Code is the printer error code when the printer refused a command.
+	Code     int        `json:"code,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	commands []gongoff.Command
+	done     chan struct{}
+}
+
+// PrinterHealth is the state of a printer reported by /health.
+type PrinterHealth struct {
+	Open   bool `json:"open"`
+	Queued int  `json:"queued"`
+	// Error is the device fault preventing the printer from printing, if any.
+	Error string `json:"error,omitempty"`
+	// LastError is the error of the last job, including the documents refused by the printer.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Server is an http.Handler owning the printers, create it with NewServer.
+type Server struct {
+	mu       sync.Mutex
+	printers map[string]*worker
+	jobs     map[string]*Job
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// worker executes the jobs of a printer one at a time.
+type worker struct {
+	name    string
+	printer gongoff.Printer
+	queue   chan *Job
+	checks  chan *check
+	// open, queued, fault and lastError are updated by the worker goroutine, the printer is only used by it.
+	open      bool
+	queued    int
+	fault     error
+	lastError error
+}
+
+// check asks the worker to check the printer between two jobs, done is closed when the health is updated.
+type check struct {
+	ctx  context.Context
+	done chan struct{}
+}
+
+// pinger is implemented by the printers able to check the connection without replies, like gongoff.NetworkPrinter.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// NewServer starts serving the given printers by name. The printers are opened when the first job arrives.
+func NewServer(printers map[string]gongoff.Printer) *Server {
+	s := &Server{
+		printers: map[string]*worker{},
+		jobs:     map[string]*Job{},
+	}
+	for name, printer := range printers {
+		w := &worker{name: name, printer: printer, queue: make(chan *Job, queueSize), checks: make(chan *check)}
+		s.printers[name] = w
+		s.wg.Add(1)
+		go s.run(w)
+	}
+	return s
+}
+
+// Close stops accepting jobs, waits for the queued ones and closes the printers.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, w := range s.printers {
+		close(w.queue)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	var err error
+	for _, w := range s.printers {
+		if w.printer.IsOpen() {
+			closeErr := w.printer.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
+
+func (s *Server) run(w *worker) {
+	defer s.wg.Done()
+	for {
+		select {
+		case job, ok := <-w.queue:
+			if !ok {
+				return
+			}
+			s.print(w, job)
+		case c := <-w.checks:
+			s.check(w, c)
+		}
+	}
+}
+
+func (s *Server) print(w *worker, job *Job) {
+	s.setStatus(job, JobStatusPrinting, nil)
+	sent, uncertain, err := printCommands(w.printer, job.commands)
+	open := w.printer.IsOpen()
+	s.mu.Lock()
+	w.open = open
+	w.queued--
+	w.fault = deviceFault(err)
+	w.lastError = err
+	job.Sent = sent
+	s.mu.Unlock()
+	switch {
+	case err == nil:
+		s.setStatus(job, JobStatusPrinted, nil)
+	case uncertain:
+		s.setStatus(job, JobStatusUncertain, err)
+	default:
+		s.setStatus(job, JobStatusFailed, err)
+	}
+	close(job.done)
+}
+
+// check checks the printer and updates its health, unless the client went away.
+// A printer not answering before checkTimeout has a fault.
+func (s *Server) check(w *worker, c *check) {
+	defer close(c.done)
+	if c.ctx.Err() != nil {
+		return
+	}
+	err := checkPrinter(c.ctx, w.printer)
+	if errors.Is(c.ctx.Err(), context.Canceled) {
+		return
+	}
+	open := w.printer.IsOpen()
+	s.mu.Lock()
+	w.open = open
+	w.fault = err
+	s.mu.Unlock()
+}
+
+// checkPrinter opens the printer if needed and returns the device fault preventing it from printing a sale.
+// Printers refusing the status request, or without replies, are checked by opening them or by Ping.
+func checkPrinter(ctx context.Context, printer gongoff.Printer) error {
+	if !printer.IsOpen() {
+		err := gongoff.OpenContext(ctx, printer)
+		if err != nil {
+			return err
+		}
+	}
+	status, err := gongoff.StatusContext(ctx, printer)
+	var printerErr *gongoff.PrinterError
+	switch {
+	case errors.Is(err, gongoff.ErrRepliesDisabled), errors.Is(err, gongoff.ErrStatusUnsupported):
+		if p, ok := printer.(pinger); ok {
+			return p.Ping(ctx)
+		}
+		return nil
+	case errors.As(err, &printerErr):
+		return nil
+	case err != nil:
+		printer.Close()
+		return err
+	}
+	return status.ReadyForSale()
+}
+
+// deviceFault returns the error of a job if it comes from the device, not from the printer refusing the document.
+func deviceFault(err error) error {
+	var printerErr *gongoff.PrinterError
+	if errors.As(err, &printerErr) {
+		return nil
+	}
+	return err
+}
+
+// printCommands opens the printer if needed and sends the commands one at a time in a session, returning
+// the number of commands accepted and whether the failed commands may be partially printed, see JobStatusUncertain.
+// The printer is closed after a connection error, so that the next job reconnects.
+func printCommands(printer gongoff.Printer, commands []gongoff.Command) (int, bool, error) {
+	if !printer.IsOpen() {
+		err := printer.Open()
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	sent := 0
+	err := gongoff.WithSession(printer, func(session gongoff.Session) error {
+		for _, command := range commands {
+			err := session.PrintCommands([]gongoff.Command{command})
+			if err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	})
+	var printerErr *gongoff.PrinterError
+	if err == nil || errors.As(err, &printerErr) {
+		// A refusal leaves the document as it was.
+		return sent, false, err
+	}
+	printer.Close()
+	return sent, sent > 0 || !unsent(err), err
+}
+
+// unsent reports whether err proves that the command was not written, like a failed dial or
+// a connection lost and not replaced before the command.
+func unsent(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, gongoff.ErrPrinterNotOpen) || errors.Is(err, gongoff.ErrConnectionLost) ||
+		(errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+func (s *Server) setStatus(job *Job, status JobStatus, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.Status = status
+	if err != nil {
+		job.Error = err.Error()
+		var printerErr *gongoff.PrinterError
+		if errors.As(err, &printerErr) {
+			job.Code = int(printerErr.Code)
+		}
+	}
+	if status == JobStatusPrinted || status == JobStatusFailed || status == JobStatusUncertain {
+		finished := time.Now()
+		job.Finished = &finished
+	}
+}
+
+// submit queues commands on the printer.
+func (s *Server) submit(name string, commands []gongoff.Command) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errServerClosed
+	}
+	w, ok := s.printers[name]
+	if !ok {
+		return nil, errPrinterNotFound
+	}
+	s.prune()
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:       id,
+		Printer:  name,
+		Status:   JobStatusQueued,
+		Created:  time.Now(),
+		commands: commands,
+		done:     make(chan struct{}),
+	}
+	select {
+	case w.queue <- job:
+	default:
+		return nil, errQueueFull
+	}
+	w.queued++
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+// newJobID returns a random job ID.
+func newJobID() (string, error) {
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// prune forgets the jobs finished more than jobRetention ago.
+func (s *Server) prune() {
+	for id, job := range s.jobs {
+		if job.Finished != nil && time.Since(*job.Finished) > jobRetention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// snapshot returns a copy of the job safe to encode.
+func (s *Server) snapshot(job *Job) Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *job
+}
+
+var (
+	errServerClosed    = errors.New("server is closed")
+	errPrinterNotFound = errors.New("printer not found")
+	errQueueFull       = errors.New("printer queue is full")
+	errJobNotFound     = errors.New("job not found")
+)
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "health":
+		s.allow(w, r, http.MethodGet, s.health)
+	case len(parts) == 2 && parts[0] == "jobs":
+		s.allow(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			s.job(w, r, parts[1])
+		})
+	case len(parts) == 3 && parts[0] == "printers" && parts[2] == "documents":
+		s.allow(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			s.document(w, r, parts[1])
+		})
+	case len(parts) == 4 && parts[0] == "printers" && parts[2] == "reports":
+		s.allow(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			s.report(w, r, parts[1], parts[3])
+		})
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	handler(w, r)
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+	s.checkIdle(ctx)
+
+	s.mu.Lock()
+	health := map[string]PrinterHealth{}
+	healthy := true
+	for name, worker := range s.printers {
+		h := PrinterHealth{Open: worker.open, Queued: worker.queued}
+		if worker.fault != nil {
+			h.Error = worker.fault.Error()
+			healthy = false
+		}
+		if worker.lastError != nil {
+			h.LastError = worker.lastError.Error()
+		}
+		health[name] = h
+	}
+	s.mu.Unlock()
+
+	status := http.StatusOK
+	if !healthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]interface{}{"printers": health})
+}
+
+// checkIdle checks in parallel the printers without queued jobs, a printer becoming busy meanwhile checks
+// after its jobs or not at all if ctx expires first.
+func (s *Server) checkIdle(ctx context.Context) {
+	s.mu.Lock()
+	var idle []*worker
+	if !s.closed {
+		for _, w := range s.printers {
+			if w.queued == 0 {
+				idle = append(idle, w)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, w := range idle {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			c := &check{ctx: ctx, done: make(chan struct{})}
+			select {
+			case w.checks <- c:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func (s *Server) job(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errJobNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(job))
+}
+
+func (s *Server) document(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxDocumentSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(data) > maxDocumentSize {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New("document too large"))
+		return
+	}
+	document, err := gongoff.UnmarshalDocument(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if commercial, ok := document.(*gongoff.DocumentCommercial); ok {
+		err = commercial.Validate()
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+	s.enqueue(w, r, name, document.Commands())
+}
+
+func (s *Server) report(w http.ResponseWriter, r *http.Request, name string, kind string) {
+	switch kind {
+	case "x":
+		s.enqueue(w, r, name, []gongoff.Command{gongoff.NewCommandFinancialReport(false)})
+	case "z":
+		s.enqueue(w, r, name, []gongoff.Command{gongoff.NewCommandFinancialReport(true)})
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown report %q", kind))
+	}
+}
+
+// enqueue submits the commands and answers with the job, waiting for it if requested.
+func (s *Server) enqueue(w http.ResponseWriter, r *http.Request, name string, commands []gongoff.Command) {
+	job, err := s.submit(name, commands)
+	switch {
+	case errors.Is(err, errPrinterNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+
+	if r.URL.Query().Get("wait") != "true" {
+		writeJSON(w, http.StatusAccepted, s.snapshot(job))
+		return
+	}
+	err = wait(r.Context(), job)
+	if err != nil {
+		// The client went away, the job stays queued.
+		return
+	}
+	snapshot := s.snapshot(job)
+	status := http.StatusOK
+	if snapshot.Status == JobStatusFailed || snapshot.Status == JobStatusUncertain {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, snapshot)
+}
+
+func wait(ctx context.Context, job *Job) error {
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,286 @@
+package gongoffhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/gongofftest"
+)
+
+const receipt = `{"version":1,"type":"commercial","commands":[
+	{"type":"product","description":"BREAD","unitPrice":750},
+	{"type":"payment","method":"1T"}
+]}`
+
+func newTestServer(t *testing.T) (*gongofftest.Emulator, *httptest.Server) {
+	t.Helper()
+	emulator := gongofftest.NewEmulator()
+	server := NewServer(map[string]gongoff.Printer{"till": emulator.Printer()})
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Close()
+		emulator.Close()
+	})
+	return emulator, httpServer
+}
+
+func getHealth(t *testing.T, url string) (int, map[string]PrinterHealth) {
+	t.Helper()
+	response, err := http.Get(url + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var health struct {
+		Printers map[string]PrinterHealth `json:"printers"`
+	}
+	json.NewDecoder(response.Body).Decode(&health)
+	return response.StatusCode, health.Printers
+}
+
+func post(t *testing.T, url string, body string) (int, Job) {
+	t.Helper()
+	response, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var job Job
+	json.NewDecoder(response.Body).Decode(&job)
+	return response.StatusCode, job
+}
+
+// droppingPrinter accepts the first commands, then fails every command with err.
+// Like every printer of a server it is only used by the goroutine of its worker.
+type droppingPrinter struct {
+	open   bool
+	accept int
+	err    error
+}
+
+func (p *droppingPrinter) Open() error  { p.open = true; return nil }
+func (p *droppingPrinter) IsOpen() bool { return p.open }
+func (p *droppingPrinter) Close() error { p.open = false; return nil }
+
+func (p *droppingPrinter) PrintDocument(doc gongoff.Document) error {
+	return p.PrintCommands(doc.Commands())
+}
+
+func (p *droppingPrinter) PrintCommands(commands []gongoff.Command) error {
+	for range commands {
+		if p.accept == 0 {
+			return p.err
+		}
+		p.accept--
+	}
+	return nil
+}
+
+func TestServerDocument(t *testing.T) {
+
+	emulator, server := newTestServer(t)
+
+	status, job := post(t, server.URL+"/printers/till/documents?wait=true", receipt)
+	if status != http.StatusOK || job.Status != JobStatusPrinted || job.Finished == nil {
+		t.Errorf("Expected printed job, got %d %+v", status, job)
+	}
+	if state := emulator.State(); state.DailyTotal != 750 {
+		t.Errorf("Expected daily total 7,50, got %s", state)
+	}
+
+	status, job = post(t, server.URL+"/printers/till/documents", receipt)
+	if status != http.StatusAccepted || job.ID == "" {
+		t.Fatalf("Expected accepted job, got %d %+v", status, job)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != JobStatusPrinted && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		response, err := http.Get(server.URL + "/jobs/" + job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(response.Body).Decode(&job)
+		response.Body.Close()
+	}
+	if job.Status != JobStatusPrinted {
+		t.Errorf("Expected printed job, got %+v", job)
+	}
+
+	fmt.Println("Completed testServerDocument")
+}
+
+func TestServerErrors(t *testing.T) {
+
+	emulator, server := newTestServer(t)
+
+	tests := []struct {
+		url      string
+		body     string
+		expected int
+	}{
+		{"/printers/missing/documents", receipt, http.StatusNotFound},
+		{"/printers/till/documents", "{", http.StatusBadRequest},
+		{"/printers/till/documents", `{"version":1,"type":"commercial","commands":[{"type":"payment","method":"1T"}]}`, http.StatusUnprocessableEntity},
+		{"/printers/till/reports/y", "", http.StatusNotFound},
+		{"/unknown", "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		status, _ := post(t, server.URL+test.url, test.body)
+		if status != test.expected {
+			t.Errorf("Expected %d for %s, got %d", test.expected, test.url, status)
+		}
+	}
+
+	response, err := http.Get(server.URL + "/printers/till/documents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", response.StatusCode)
+	}
+
+	emulator.FailNext(gongofftest.ErrorCodePaperEnd)
+	status, job := post(t, server.URL+"/printers/till/documents?wait=true", receipt)
+	if status != http.StatusBadGateway || job.Status != JobStatusFailed || job.Code != int(gongofftest.ErrorCodePaperEnd) {
+		t.Errorf("Expected failed job with paper end, got %d %+v", status, job)
+	}
+
+	// A refused document is not a device fault.
+	status, health := getHealth(t, server.URL)
+	if status != http.StatusOK || health["till"].Error != "" || health["till"].LastError == "" {
+		t.Errorf("Expected healthy printer with last error, got %d %+v", status, health)
+	}
+
+	fmt.Println("Completed testServerErrors")
+}
+
+func TestServerUncertain(t *testing.T) {
+
+	server := NewServer(map[string]gongoff.Printer{
+		// The connection drops while waiting for the reply of the second command.
+		"till": &droppingPrinter{accept: 1, err: io.ErrUnexpectedEOF},
+		// The connection is lost before the first command.
+		"bar": &droppingPrinter{err: gongoff.ErrConnectionLost},
+	})
+	httpServer := httptest.NewServer(server)
+	defer server.Close()
+	defer httpServer.Close()
+
+	status, uncertain := post(t, httpServer.URL+"/printers/till/documents?wait=true", receipt)
+	if status != http.StatusBadGateway || uncertain.Status != JobStatusUncertain || uncertain.Sent != 1 {
+		t.Errorf("Expected uncertain job after the first command, got %d %+v", status, uncertain)
+	}
+	status, failed := post(t, httpServer.URL+"/printers/bar/documents?wait=true", receipt)
+	if status != http.StatusBadGateway || failed.Status != JobStatusFailed || failed.Sent != 0 {
+		t.Errorf("Expected failed job, got %d %+v", status, failed)
+	}
+
+	// The IDs are not a counter restarting with the server.
+	restarted := NewServer(map[string]gongoff.Printer{"till": &droppingPrinter{accept: 2}})
+	defer restarted.Close()
+	job, err := restarted.submit("till", nil)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if job.ID == uncertain.ID || job.ID == failed.ID || uncertain.ID == failed.ID {
+		t.Errorf("Expected unique job IDs, got %s %s %s", uncertain.ID, failed.ID, job.ID)
+	}
+
+	fmt.Println("Completed testServerUncertain")
+}
+
+func TestServerHealth(t *testing.T) {
+
+	emulator, server := newTestServer(t)
+
+	// The idle printers are opened and checked.
+	status, health := getHealth(t, server.URL)
+	if status != http.StatusOK || !health["till"].Open || health["till"].Error != "" {
+		t.Errorf("Expected open healthy printer, got %d %+v", status, health)
+	}
+
+	emulator.Update(func(state *gongofftest.State) {
+		state.PaperOut = true
+	})
+	status, health = getHealth(t, server.URL)
+	if status != http.StatusServiceUnavailable || health["till"].Error == "" {
+		t.Errorf("Expected paper fault, got %d %+v", status, health)
+	}
+	emulator.Update(func(state *gongofftest.State) {
+		state.PaperOut = false
+	})
+	status, _ = getHealth(t, server.URL)
+	if status != http.StatusOK {
+		t.Errorf("Expected 200 after the paper is loaded, got %d", status)
+	}
+
+	// Nothing listens on the port of a closed emulator.
+	closed := gongofftest.NewEmulator()
+	closed.Close()
+	down := NewServer(map[string]gongoff.Printer{"bar": gongoff.NewNetworkPrinter(closed.Host(), closed.Port())})
+	defer down.Close()
+	downServer := httptest.NewServer(down)
+	defer downServer.Close()
+	status, health = getHealth(t, downServer.URL)
+	if status != http.StatusServiceUnavailable || health["bar"].Open || health["bar"].Error == "" {
+		t.Errorf("Expected unreachable printer, got %d %+v", status, health)
+	}
+
+	fmt.Println("Completed testServerHealth")
+}
+
+func TestServerConcurrentDocuments(t *testing.T) {
+
+	emulator, server := newTestServer(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := http.Post(server.URL+"/printers/till/documents?wait=true", "application/json", strings.NewReader(receipt))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("Expected 200, got %d", response.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if state := emulator.State(); state.DocumentNumber != 10 || state.DailyTotal != 7500 {
+		t.Errorf("Expected 10 receipts, got %s", state)
+	}
+
+	status, job := post(t, server.URL+"/printers/till/reports/z?wait=true", "")
+	if status != http.StatusOK || job.Status != JobStatusPrinted {
+		t.Errorf("Expected printed report, got %d %+v", status, job)
+	}
+	if state := emulator.State(); state.ClosureNumber != 1 || state.DailyTotal != 0 {
+		t.Errorf("Expected fiscal closure, got %s", state)
+	}
+
+	response, err := http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected healthy printers, got %d", response.StatusCode)
+	}
+
+	fmt.Println("Completed testServerConcurrentDocuments")
+}
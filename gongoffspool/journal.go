@@ -0,0 +1,169 @@
+package gongoffspool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paolo96/gongoff"
+)
+
+// Journal anatomy:
+// Every job is stored in its own file named after the job ID (ex. 000042.json) in the spool directory.
+// Files are replaced atomically (written to a temporary file, synced and renamed), so after a crash
+// a job file contains either the previous or the new state of the job, never a partial one.
+// The sequence file holds the last job ID assigned, so IDs are never reused after a job is removed.
+
+const (
+	journalExtension = ".json"
+	tempExtension    = ".tmp"
+	sequenceFile     = "sequence"
+)
+
+// record is the content of a job file.
+type record struct {
+	Job
+	Document *gongoff.DocumentSchema `json:"document"`
+	// Exact is true for RecoveryChecking jobs whose command following the sent ones was not written.
+	Exact bool `json:"exact,omitempty"`
+}
+
+type journal struct {
+	dir string
+}
+
+func openJournal(dir string) (*journal, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{dir: dir}, nil
+}
+
+func (j *journal) path(id string) string {
+	return filepath.Join(j.dir, id+journalExtension)
+}
+
+// write atomically replaces the file of the job.
+func (j *journal) write(r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return j.writeFile(r.ID+journalExtension, data)
+}
+
+// sequence returns the last job ID assigned, 0 if none was.
+func (j *journal) sequence() (int, error) {
+	data, err := os.ReadFile(filepath.Join(j.dir, sequenceFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("corrupted journal file %s: %w", sequenceFile, err)
+	}
+	return id, nil
+}
+
+// writeSequence records id as the last job ID assigned.
+func (j *journal) writeSequence(id int) error {
+	return j.writeFile(sequenceFile, []byte(strconv.Itoa(id)))
+}
+
+// writeFile atomically replaces the file name of the journal with data.
+func (j *journal) writeFile(name string, data []byte) error {
+	temp, err := os.CreateTemp(j.dir, name+"-*"+tempExtension)
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Sync()
+	}
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), filepath.Join(j.dir, name))
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return j.syncDir()
+}
+
+// syncDir makes the rename durable. Directories cannot be synced on every platform, errors are ignored.
+func (j *journal) syncDir() error {
+	dir, err := os.Open(j.dir)
+	if err != nil {
+		return nil
+	}
+	dir.Sync()
+	return dir.Close()
+}
+
+func (j *journal) remove(id string) error {
+	err := os.Remove(j.path(id))
+	if err != nil {
+		return err
+	}
+	return j.syncDir()
+}
+
+// load reads every job of the journal ordered by ID, removing the temporary files left by a crash.
+func (j *journal) load() ([]*record, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*record
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, tempExtension) {
+			os.Remove(filepath.Join(j.dir, name))
+			continue
+		}
+		if !strings.HasSuffix(name, journalExtension) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		r := &record{}
+		err = json.Unmarshal(data, r)
+		if err != nil {
+			return nil, fmt.Errorf("corrupted journal file %s: %w", name, err)
+		}
+		records = append(records, r)
+	}
+	sort.Slice(records, func(a, b int) bool {
+		return lessID(records[a].ID, records[b].ID)
+	})
+	return records, nil
+}
+
+// lessID orders the job IDs numerically, they have more than 6 digits after job 999999.
+func lessID(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x < y
+}
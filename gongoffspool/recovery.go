@@ -0,0 +1,152 @@
+package gongoffspool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/paolo96/gongoff"
+)
+
+// RecoveryAction is what happened to the document of a job interrupted while it was being sent.
+type RecoveryAction string
+
+const (
+	// RecoveryChecking jobs were interrupted after part of their document may have reached the printer, by a
+	// transport error or by a restart of the process. The next attempt asks the printer status in the session
+	// sending the job, the document open on the printer is taken for the one of the job:
+	// it is resumed if the spooler knows that the command following the sent ones was not written, see RecoveryResumed,
+	// otherwise it is cancelled and the job printed again from the first command, see RecoveryCancelled.
+	RecoveryChecking RecoveryAction = "checking"
+	// RecoveryResumed jobs had their interrupted document still open and are resumed from the first command not sent.
+	RecoveryResumed RecoveryAction = "resumed"
+	// RecoveryManual jobs were interrupted and the status of the printer could not settle them: the printer cannot
+	// tell its status, ex. without replies, or no document is open and the last command may or may not have been
+	// printed. The job fails with ErrDocumentInterrupted instead of being printed again, which could print it twice.
+	// Check the printer, then call Retry if no document is open or Recover to cancel the document of the job.
+	RecoveryManual RecoveryAction = "manual"
+	// RecoveryCancelling jobs were queued again by Recover, their document is cancelled before printing them.
+	// If the printer refuses the cancellation the job fails again: check the printer, then Retry or Recover it.
+	RecoveryCancelling RecoveryAction = "cancelling"
+	// RecoveryCancelled jobs had their interrupted document cancelled and are printed again from the first command.
+	RecoveryCancelled RecoveryAction = "cancelled"
+)
+
+// Recovery reports a job interrupted while sending, resumed or its document cancelled, see Options.OnRecovery.
+type Recovery struct {
+	Action RecoveryAction
+	// Job is the interrupted job, Job.Sent the commands sent before the interruption.
+	Job Job
+	// Err is the error which interrupted the job, or made it fail with RecoveryManual,
+	// nil for RecoveryResumed and RecoveryCancelled.
+	Err error
+}
+
+// manualRecovery is returned by recoverDocument when the job must be recovered by the operator.
+type manualRecovery struct {
+	err error
+}
+
+func (e *manualRecovery) Error() string {
+	return e.err.Error()
+}
+
+// errNoDocumentOpen fails a job whose document is not open after an interruption, see RecoveryManual.
+var errNoDocumentOpen = errors.New("no document is open on the printer, the last command may have been printed")
+
+// unsent reports whether err proves that nothing was written to the printer, like a failed dial or
+// a connection lost and not replaced before the command.
+func unsent(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, gongoff.ErrPrinterNotOpen) || errors.Is(err, gongoff.ErrConnectionLost) ||
+		(errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// check queues again a job interrupted while sending, its document is checked by the next attempt,
+// see RecoveryChecking. exact tells that the command following the sent ones was not written.
+func (s *Spooler) check(r *record, err error, exact bool) {
+	s.mu.Lock()
+	r.Recovery = RecoveryChecking
+	r.Exact = exact
+	job := r.Job
+	s.mu.Unlock()
+	s.report(Recovery{Action: RecoveryChecking, Job: job, Err: err})
+	s.update(r, StatusPending, fmt.Errorf("%w: %v", ErrDocumentInterrupted, err))
+}
+
+// recoverDocument resumes or cancels the document of a job interrupted while sending, depending on the
+// printer status, see RecoveryChecking. A *manualRecovery is returned if the status cannot settle the job.
+func (s *Spooler) recoverDocument(session gongoff.Session, r *record) error {
+	status, err := session.StatusContext(s.ctx)
+	var printerErr *gongoff.PrinterError
+	if errors.Is(err, gongoff.ErrRepliesDisabled) || errors.Is(err, gongoff.ErrStatusUnsupported) || errors.As(err, &printerErr) {
+		return &manualRecovery{err: err}
+	}
+	if err != nil {
+		return err
+	}
+	if !status.DocumentOpen() {
+		return &manualRecovery{err: errNoDocumentOpen}
+	}
+	if r.Exact {
+		s.mu.Lock()
+		r.Recovery = RecoveryResumed
+		r.Exact = false
+		job := r.Job
+		s.mu.Unlock()
+		s.report(Recovery{Action: RecoveryResumed, Job: job})
+		s.update(r, StatusSending, nil)
+		return nil
+	}
+	err = session.PrintCommandsContext(s.ctx, []gongoff.Command{gongoff.NewCommandCancelDocument()})
+	if errors.As(err, &printerErr) {
+		return &manualRecovery{err: err}
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r.Sent = 0
+	r.Exact = false
+	s.mu.Unlock()
+	s.cancelled(r)
+	return nil
+}
+
+// interrupt fails a job whose document may be partially printed, see RecoveryManual.
+func (s *Spooler) interrupt(r *record, err error) {
+	s.mu.Lock()
+	r.Recovery = RecoveryManual
+	r.Exact = false
+	job := r.Job
+	s.mu.Unlock()
+	s.report(Recovery{Action: RecoveryManual, Job: job, Err: err})
+	s.update(r, StatusFailed, fmt.Errorf("%w: %v", ErrDocumentInterrupted, err))
+}
+
+// cancelDocument cancels the document of a job queued by Recover.
+func (s *Spooler) cancelDocument(session gongoff.Session, r *record) error {
+	err := session.PrintCommandsContext(s.ctx, []gongoff.Command{gongoff.NewCommandCancelDocument()})
+	if err != nil {
+		return err
+	}
+	s.cancelled(r)
+	return nil
+}
+
+// cancelled records that the document of the job was cancelled, see RecoveryCancelled.
+func (s *Spooler) cancelled(r *record) {
+	s.mu.Lock()
+	r.Recovery = RecoveryCancelled
+	job := r.Job
+	s.mu.Unlock()
+	s.report(Recovery{Action: RecoveryCancelled, Job: job})
+	s.update(r, StatusSending, nil)
+}
+
+// report calls Options.OnRecovery, before the job is updated so that Wait returns after it.
+func (s *Spooler) report(recovery Recovery) {
+	if s.options.OnRecovery != nil {
+		s.options.OnRecovery(recovery)
+	}
+}
@@ -0,0 +1,530 @@
+// Package gongoffspool queues documents on disk and prints them in the background.
+//
+// Submitted documents are written to a journal before Submit returns and are printed in order by a
+// background goroutine. Every command sent to the printer is recorded, jobs failing before their document
+// reached the printer are retried, documents are never silently dropped by the spooler.
+// Each document is sent in one printer session, which is not continued on a new connection if the connection is lost.
+//
+// Delivery is at least once only with the printer replies, see gongoff.GenericPrinter.SetReplies: without them
+// a command counts as sent once written, and a command lost or refused by the printer is not detected.
+//
+// A document interrupted after part of it may have reached the printer is resumed or cancelled depending
+// on the printer status, see RecoveryChecking. Printers unable to tell their status need the operator, see RecoveryManual.
+package gongoffspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/paolo96/gongoff"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	// StatusPending jobs are waiting to be printed, or to be retried after an error.
+	StatusPending Status = "pending"
+	// StatusSending jobs are being sent to the printer.
+	StatusSending Status = "sending"
+	// StatusPrinted jobs had every command sent, and acknowledged by the printer with replies.
+	StatusPrinted Status = "printed"
+	// StatusFailed jobs were refused by the printer or exceeded the maximum attempts, see Retry.
+	StatusFailed Status = "failed"
+)
+
+// Job is a document queued in the spooler.
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	// Sent is the number of commands written to the printer, and acknowledged by it with replies.
+	Sent     int `json:"sent"`
+	Commands int `json:"commands"`
+	Attempts int `json:"attempts"`
+	// Error is the last error, Code the printer error code if the printer refused a command.
+	Error string `json:"error,omitempty"`
+	Code  int    `json:"code,omitempty"`
+	// Recovery is set when the job was interrupted while sending, see RecoveryAction.
+	Recovery RecoveryAction `json:"recovery,omitempty"`
+	Created  time.Time      `json:"created"`
+	Updated  time.Time      `json:"updated"`
+}
+
+// Options configures the retries of a Spooler.
+type Options struct {
+	// RetryDelay is the delay before the first retry, doubled after each failed attempt up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// MaxAttempts marks a job as failed after the given number of attempts, 0 retries forever.
+	MaxAttempts int
+	// RetryRefused, if not nil, reports whether a job whose command was refused by the printer is retried,
+	// ex. for the error codes meaning paper end on the printers in use. Otherwise refused jobs fail, see Retry.
+	RetryRefused func(err *gongoff.PrinterError) bool
+	// OnRecovery, if not nil, is called when a job is interrupted, resumed or its document cancelled,
+	// by Open for the jobs interrupted by a restart and by the printing goroutine otherwise.
+	OnRecovery func(Recovery)
+}
+
+// DefaultOptions retries forever, waiting from 1 second to 1 minute between attempts.
+var DefaultOptions = Options{
+	RetryDelay:    time.Second,
+	MaxRetryDelay: time.Minute,
+}
+
+var (
+	ErrSpoolerClosed       = errors.New("spooler is closed")
+	ErrJobNotFound         = errors.New("job not found")
+	ErrJobNotFinished      = errors.New("job is not printed or failed")
+	ErrDocumentInterrupted = errors.New("document was interrupted while sending, it may be open or partially printed")
+)
+
+// Spooler prints the documents of a journal directory on a printer.
+// The printer is opened when needed. Each document is sent in a printer session, so other goroutines
+// using the printer never interleave their commands with it.
+type Spooler struct {
+	printer gongoff.Printer
+	journal *journal
+	options Options
+
+	mu     sync.Mutex
+	jobs   map[string]*record
+	order  []string
+	lastID int
+	closed bool
+	// changed is closed and replaced when a job changes, to wake up the worker and Wait.
+	changed chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Open loads the journal in dir, creating it if needed, and starts printing the pending jobs.
+// Jobs interrupted while sending by the previous process are checked first, see RecoveryChecking.
+// Zero retry delays are replaced by the ones of DefaultOptions.
+func Open(dir string, printer gongoff.Printer, options Options) (*Spooler, error) {
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = DefaultOptions.RetryDelay
+	}
+	if options.MaxRetryDelay < options.RetryDelay {
+		options.MaxRetryDelay = options.RetryDelay
+		if DefaultOptions.MaxRetryDelay > options.RetryDelay {
+			options.MaxRetryDelay = DefaultOptions.MaxRetryDelay
+		}
+	}
+	journal, err := openJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+	records, err := journal.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Spooler{
+		printer: printer,
+		journal: journal,
+		options: options,
+		jobs:    map[string]*record{},
+		changed: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	s.lastID, err = journal.sequence()
+	if err != nil {
+		return nil, err
+	}
+	var interrupted []*record
+	for _, r := range records {
+		if r.Status == StatusSending || (r.Status == StatusPending && r.Sent > 0 && r.Recovery != RecoveryChecking) {
+			interrupted = append(interrupted, r)
+		}
+		s.jobs[r.ID] = r
+		s.order = append(s.order, r.ID)
+		id, err := strconv.Atoi(r.ID)
+		if err == nil && id > s.lastID {
+			s.lastID = id
+		}
+	}
+	for _, r := range interrupted {
+		// A job stopped while its status was checked has still its commands exactly sent.
+		s.check(r, errors.New("process restarted"), r.Recovery == RecoveryChecking && r.Exact)
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Close stops printing and waits for the command being sent. Jobs not printed stay in the journal.
+func (s *Spooler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Submit writes the document to the journal and queues it. The document is printed even if the process
+// restarts before it is sent.
+func (s *Spooler) Submit(document gongoff.Document) (Job, error) {
+	schema, err := gongoff.NewDocumentSchema(document)
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Job{}, ErrSpoolerClosed
+	}
+	now := time.Now()
+	r := &record{
+		Job: Job{
+			ID:       fmt.Sprintf("%06d", s.lastID+1),
+			Status:   StatusPending,
+			Commands: len(document.Commands()),
+			Created:  now,
+			Updated:  now,
+		},
+		Document: schema,
+	}
+	err = s.journal.writeSequence(s.lastID + 1)
+	if err != nil {
+		return Job{}, err
+	}
+	s.lastID++
+	err = s.journal.write(r)
+	if err != nil {
+		return Job{}, err
+	}
+	s.jobs[r.ID] = r
+	s.order = append(s.order, r.ID)
+	s.notify()
+	return r.Job, nil
+}
+
+// Job returns the job with the given ID.
+func (s *Spooler) Job(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return r.Job, nil
+}
+
+// Jobs returns every job of the journal in submission order.
+func (s *Spooler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, s.jobs[id].Job)
+	}
+	return jobs
+}
+
+// Wait waits until the job is printed or failed.
+func (s *Spooler) Wait(ctx context.Context, id string) (Job, error) {
+	for {
+		s.mu.Lock()
+		r, ok := s.jobs[id]
+		if !ok {
+			s.mu.Unlock()
+			return Job{}, ErrJobNotFound
+		}
+		job := r.Job
+		changed := s.changed
+		s.mu.Unlock()
+
+		if job.Status == StatusPrinted || job.Status == StatusFailed {
+			return job, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return job, ctx.Err()
+		}
+	}
+}
+
+// Retry queues a failed job again, resuming it from the first command not sent.
+// A job interrupted while sending is printed again from the first command: check first that its document
+// is no longer open on the printer, or use Recover.
+func (s *Spooler) Retry(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if r.Status != StatusFailed {
+		return fmt.Errorf("job %s is %s, only failed jobs can be retried", id, r.Status)
+	}
+	r.Status = StatusPending
+	r.Attempts = 0
+	if r.Recovery == RecoveryManual || r.Recovery == RecoveryCancelling {
+		r.Sent = 0
+		r.Recovery = ""
+	}
+	r.Updated = time.Now()
+	err := s.journal.write(r)
+	if err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Recover queues a job interrupted while sending again, cancelling its document with
+// gongoff.NewCommandCancelDocument before printing it from the first command.
+// Call it only after checking that the document open on the printer belongs to the job.
+func (s *Spooler) Recover(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if r.Status != StatusFailed || (r.Recovery != RecoveryManual && r.Recovery != RecoveryCancelling) {
+		return fmt.Errorf("job %s was not interrupted while sending", id)
+	}
+	r.Status = StatusPending
+	r.Attempts = 0
+	r.Sent = 0
+	r.Recovery = RecoveryCancelling
+	r.Updated = time.Now()
+	err := s.journal.write(r)
+	if err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Remove deletes a printed or failed job from the journal.
+func (s *Spooler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if r.Status != StatusPrinted && r.Status != StatusFailed {
+		return ErrJobNotFinished
+	}
+	err := s.journal.remove(id)
+	if err != nil {
+		return err
+	}
+	delete(s.jobs, id)
+	for i, orderID := range s.order {
+		if orderID == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// notify wakes up the worker and the waiting goroutines, s.mu must be held.
+func (s *Spooler) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// next returns the first pending job, or the channel closed when a job changes.
+func (s *Spooler) next() (*record, chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.order {
+		if s.jobs[id].Status == StatusPending {
+			return s.jobs[id], nil
+		}
+	}
+	return nil, s.changed
+}
+
+func (s *Spooler) run() {
+	defer close(s.done)
+	delay := s.options.RetryDelay
+
+	for {
+		r, changed := s.next()
+		if r == nil {
+			select {
+			case <-changed:
+				continue
+			case <-s.ctx.Done():
+				return
+			}
+		}
+
+		retry := s.send(r)
+		if s.ctx.Err() != nil {
+			return
+		}
+		if !retry {
+			delay = s.options.RetryDelay
+			continue
+		}
+		select {
+		case <-time.After(delay):
+		case <-s.ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > s.options.MaxRetryDelay {
+			delay = s.options.MaxRetryDelay
+		}
+	}
+}
+
+// send prints the commands of the job not yet sent in one session, returning true if the job must be retried later.
+func (s *Spooler) send(r *record) bool {
+	document, err := r.Document.Document()
+	if err != nil {
+		s.update(r, StatusFailed, err)
+		return false
+	}
+	commands := document.Commands()
+
+	s.mu.Lock()
+	r.Attempts++
+	s.mu.Unlock()
+	s.update(r, StatusSending, nil)
+
+	if !s.printer.IsOpen() {
+		err = gongoff.OpenContext(s.ctx, s.printer)
+		if err != nil {
+			return s.retry(r, err)
+		}
+	}
+
+	err = gongoff.WithSession(s.printer, func(session gongoff.Session) error {
+		if r.Recovery == RecoveryChecking {
+			err := s.recoverDocument(session, r)
+			if err != nil {
+				return err
+			}
+		}
+		if r.Recovery == RecoveryCancelling {
+			err := s.cancelDocument(session, r)
+			if err != nil {
+				return err
+			}
+		}
+		for r.Sent < len(commands) {
+			err := session.PrintCommandsContext(s.ctx, commands[r.Sent:r.Sent+1])
+			if err != nil {
+				return err
+			}
+			s.mu.Lock()
+			r.Sent++
+			s.mu.Unlock()
+			s.update(r, StatusSending, nil)
+		}
+		return nil
+	})
+	var manual *manualRecovery
+	if errors.As(err, &manual) {
+		s.interrupt(r, manual.err)
+		return false
+	}
+	var printerErr *gongoff.PrinterError
+	if err != nil && (r.Recovery == RecoveryChecking || r.Recovery == RecoveryCancelling) && !errors.As(err, &printerErr) {
+		return s.retry(r, err)
+	}
+	if err != nil {
+		return s.fail(r, err)
+	}
+	s.update(r, StatusPrinted, nil)
+	return false
+}
+
+// fail records the error of a command of the job, returning true if the job must be retried later.
+// The printer refusing the command leaves the document as it was, the job fails unless Options.RetryRefused retries it.
+// Other errors interrupt the job, unless they prove that its document was not started, see RecoveryChecking.
+func (s *Spooler) fail(r *record, err error) bool {
+	var printerErr *gongoff.PrinterError
+	if errors.As(err, &printerErr) {
+		if s.options.RetryRefused == nil || !s.options.RetryRefused(printerErr) {
+			s.update(r, StatusFailed, err)
+			return false
+		}
+		return s.retry(r, err)
+	}
+	if r.Sent == 0 && unsent(err) {
+		return s.retry(r, err)
+	}
+	s.printer.Close()
+	if s.ctx.Err() != nil {
+		// The spooler is closing, the job is still sending and the next Open checks it.
+		return false
+	}
+	s.check(r, err, unsent(err))
+	return true
+}
+
+// retry records an error which left the document of the job as it was, returning true if the job must be
+// retried later. The printer is reopened after a transport error.
+func (s *Spooler) retry(r *record, err error) bool {
+	var printerErr *gongoff.PrinterError
+	if !errors.As(err, &printerErr) {
+		s.printer.Close()
+	}
+	if s.ctx.Err() != nil {
+		// Closing, the job is printed by the next Open.
+		s.update(r, StatusPending, nil)
+		return false
+	}
+	if s.options.MaxAttempts > 0 && r.Attempts >= s.options.MaxAttempts {
+		if r.Recovery == RecoveryChecking {
+			s.interrupt(r, err)
+			return false
+		}
+		s.update(r, StatusFailed, err)
+		return false
+	}
+	s.update(r, StatusPending, err)
+	return true
+}
+
+// update changes the status of the job and writes it to the journal.
+func (s *Spooler) update(r *record, status Status, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.Status = status
+	r.Updated = time.Now()
+	if err != nil {
+		r.Error = err.Error()
+		r.Code = 0
+		var printerErr *gongoff.PrinterError
+		if errors.As(err, &printerErr) {
+			r.Code = int(printerErr.Code)
+		}
+	} else if status == StatusPrinted {
+		r.Error = ""
+		r.Code = 0
+	}
+	// A job not written to the journal is resumed from an earlier command after a restart,
+	// which is still at-least-once delivery, so the error is only recorded.
+	writeErr := s.journal.write(r)
+	if writeErr != nil && r.Error == "" {
+		r.Error = "journal: " + writeErr.Error()
+	}
+	s.notify()
+}
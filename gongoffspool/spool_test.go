@@ -0,0 +1,479 @@
+package gongoffspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/gongofftest"
+)
+
+var testOptions = Options{RetryDelay: 5 * time.Millisecond, MaxRetryDelay: 20 * time.Millisecond}
+
+var (
+	errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	errConnectionReset   = &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+)
+
+// flakyPrinter fails with err while failing returns true, before sending the command.
+// A nil err blocks the command until the context is done.
+type flakyPrinter struct {
+	gongoff.Printer
+	mu      sync.Mutex
+	failing func(sent int) bool
+	err     error
+	sent    int
+}
+
+func (p *flakyPrinter) Session(fn func(gongoff.Session) error) error {
+	return gongoff.WithSession(p.Printer, func(session gongoff.Session) error {
+		return fn(&flakySession{Session: session, printer: p})
+	})
+}
+
+type flakySession struct {
+	gongoff.Session
+	printer *flakyPrinter
+}
+
+func (s *flakySession) PrintCommandsContext(ctx context.Context, commands []gongoff.Command) error {
+	p := s.printer
+	p.mu.Lock()
+	fail := p.failing(p.sent)
+	err := p.err
+	p.mu.Unlock()
+	if fail && err == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if fail {
+		return err
+	}
+	err = s.Session.PrintCommandsContext(ctx, commands)
+	if err == nil {
+		p.mu.Lock()
+		p.sent += len(commands)
+		p.mu.Unlock()
+	}
+	return err
+}
+
+func receipt(t *testing.T) gongoff.Document {
+	t.Helper()
+	doc, err := gongoff.NewReceiptBuilder().AddItem("BREAD", 750, 1).AddItem("MILK", 120, 1).PayRest(gongoff.TerminatorTypePaymentCash).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func wait(t *testing.T, s *Spooler, id string) Job {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := s.Wait(ctx, id)
+	if err != nil {
+		t.Fatalf("Expected error = nil waiting job %s, got %s (%+v)", id, err, job)
+	}
+	return job
+}
+
+func TestSpoolerRetry(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	// The connection is refused twice before the document starts, then it recovers.
+	failures := 0
+	printer := &flakyPrinter{Printer: emulator.Printer(), err: errConnectionRefused, failing: func(sent int) bool {
+		if failures < 2 {
+			failures++
+			return true
+		}
+		return false
+	}}
+
+	spooler, err := Open(t.TempDir(), printer, testOptions)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+
+	job, err := spooler.Submit(receipt(t))
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted || job.Sent != 3 || job.Attempts != 3 {
+		t.Errorf("Expected printed after 3 attempts, got %+v", job)
+	}
+	if state := emulator.State(); state.DailyTotal != 870 || state.DocumentNumber != 1 {
+		t.Errorf("Expected one receipt of 8,70, got %s", state)
+	}
+
+	fmt.Println("Completed testSpoolerRetry")
+}
+
+func TestSpoolerPrinterErrors(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	options := testOptions
+	options.RetryRefused = func(err *gongoff.PrinterError) bool { return err.Code == gongofftest.ErrorCodePaperEnd }
+	spooler, err := Open(t.TempDir(), emulator.Printer(), options)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+
+	// The refusals chosen by RetryRefused are retried.
+	emulator.FailNext(gongofftest.ErrorCodePaperEnd)
+	job, _ := spooler.Submit(receipt(t))
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted || job.Attempts != 2 {
+		t.Errorf("Expected printed after 2 attempts, got %+v", job)
+	}
+
+	// Other errors fail the job.
+	emulator.FailNext(gongofftest.ErrorCodeHardwareFailure)
+	job, _ = spooler.Submit(receipt(t))
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusFailed || job.Code != int(gongofftest.ErrorCodeHardwareFailure) || job.Sent != 0 {
+		t.Errorf("Expected failed job with hardware failure, got %+v", job)
+	}
+	err = spooler.Remove(job.ID)
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if _, err = spooler.Job(job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	fmt.Println("Completed testSpoolerPrinterErrors")
+}
+
+func TestSpoolerJobIDs(t *testing.T) {
+
+	dir := t.TempDir()
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	spooler, err := Open(dir, emulator.Printer(), testOptions)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	job, _ := spooler.Submit(receipt(t))
+	wait(t, spooler, job.ID)
+	err = spooler.Remove(job.ID)
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	spooler.Close()
+
+	// The ID of the removed job is not assigned again after a restart.
+	spooler, err = Open(dir, emulator.Printer(), testOptions)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+	next, err := spooler.Submit(receipt(t))
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if next.ID <= job.ID {
+		t.Errorf("Expected an ID after %s, got %s", job.ID, next.ID)
+	}
+
+	fmt.Println("Completed testSpoolerJobIDs")
+}
+
+func TestJournalOrder(t *testing.T) {
+
+	journal, err := openJournal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The IDs have 7 digits after job 999999.
+	for _, id := range []string{"1000000", "000002", "999999"} {
+		err = journal.write(&record{Job: Job{ID: id, Status: StatusPending}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := journal.load()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	var ids []string
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	if strings.Join(ids, " ") != "000002 999999 1000000" {
+		t.Errorf("Expected the jobs in numeric order, got %v", ids)
+	}
+
+	fmt.Println("Completed testJournalOrder")
+}
+
+// stopWhileSending returns the journal of a spooler stopped while the second command of a receipt was being sent.
+func stopWhileSending(t *testing.T, emulator *gongofftest.Emulator) (string, Job) {
+	t.Helper()
+	dir := t.TempDir()
+	printer := &flakyPrinter{Printer: emulator.Printer(), failing: func(sent int) bool { return sent >= 1 }}
+	spooler, err := Open(dir, printer, testOptions)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	job, err := spooler.Submit(receipt(t))
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Sent < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		job, _ = spooler.Job(job.ID)
+	}
+	spooler.Close()
+	if job, _ = spooler.Job(job.ID); job.Status != StatusSending || job.Sent != 1 {
+		t.Fatalf("Expected first command sent, got %+v", job)
+	}
+	return dir, job
+}
+
+// recordRecoveries returns options recording the recoveries of the spooler.
+func recordRecoveries() (Options, func() []Recovery) {
+	var recoveries []Recovery
+	var mu sync.Mutex
+	options := testOptions
+	options.OnRecovery = func(recovery Recovery) {
+		mu.Lock()
+		defer mu.Unlock()
+		recoveries = append(recoveries, recovery)
+	}
+	return options, func() []Recovery {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Recovery{}, recoveries...)
+	}
+}
+
+func TestSpoolerRestart(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	dir, job := stopWhileSending(t, emulator)
+
+	// After the restart the printer reports the receipt open, the second command may have been printed:
+	// the receipt is cancelled and the job printed again.
+	options, recoveries := recordRecoveries()
+	spooler, err := Open(dir, emulator.Printer(), options)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted || job.Sent != 3 || job.Recovery != RecoveryCancelled {
+		t.Errorf("Expected printed job after cancelling, got %+v", job)
+	}
+	if state := emulator.State(); state.DailyTotal != 870 || state.DocumentNumber != 1 {
+		t.Errorf("Expected one receipt of 8,70, got %s", state)
+	}
+	if r := recoveries(); len(r) != 2 || r[0].Action != RecoveryChecking || r[1].Action != RecoveryCancelled {
+		t.Errorf("Expected the job checked then cancelled, got %+v", r)
+	}
+	if err = spooler.Recover(job.ID); err == nil {
+		t.Errorf("Expected error recovering a printed job, got nil")
+	}
+
+	next, err := spooler.Submit(receipt(t))
+	if err != nil || next.ID <= job.ID {
+		t.Errorf("Expected a new job after %s, got %+v %v", job.ID, next, err)
+	}
+	if jobs := spooler.Jobs(); len(jobs) != 2 || jobs[0].ID != job.ID {
+		t.Errorf("Expected 2 jobs in order, got %+v", jobs)
+	}
+
+	fmt.Println("Completed testSpoolerRestart")
+}
+
+func TestSpoolerRestartWithoutReplies(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	dir, job := stopWhileSending(t, emulator)
+
+	// Without replies the printer cannot tell its status, the document open on the printer is left untouched.
+	options, recoveries := recordRecoveries()
+	printer := emulator.Printer()
+	printer.SetReplies(false)
+	spooler, err := Open(dir, printer, options)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusFailed || job.Recovery != RecoveryManual || !strings.HasPrefix(job.Error, ErrDocumentInterrupted.Error()) {
+		t.Errorf("Expected job interrupted, got %+v", job)
+	}
+	if state := emulator.State(); state.Document != gongofftest.DocumentCommercial || state.Total != 750 {
+		t.Errorf("Expected the receipt open on the printer, got %s", state)
+	}
+
+	// Recover cancels the document and prints the job again.
+	err = spooler.Recover(job.ID)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted || job.Sent != 3 || job.Recovery != RecoveryCancelled {
+		t.Errorf("Expected printed job after cancelling, got %+v", job)
+	}
+	// Without replies the job is printed once written, the emulator may still be executing it.
+	state := emulator.State()
+	for deadline := time.Now().Add(5 * time.Second); state.DocumentNumber == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		state = emulator.State()
+	}
+	if state.DailyTotal != 870 || state.DocumentNumber != 1 {
+		t.Errorf("Expected one receipt of 8,70, got %s", state)
+	}
+	r := recoveries()
+	if len(r) != 3 || r[0].Action != RecoveryChecking || r[1].Action != RecoveryManual || r[2].Action != RecoveryCancelled {
+		t.Errorf("Expected the job checked, interrupted then cancelled, got %+v", r)
+	}
+	if !errors.Is(r[1].Err, gongoff.ErrRepliesDisabled) {
+		t.Errorf("Expected the job interrupted by ErrRepliesDisabled, got %v", r[1].Err)
+	}
+
+	fmt.Println("Completed testSpoolerRestartWithoutReplies")
+}
+
+func TestSpoolerMaxAttempts(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := &flakyPrinter{Printer: emulator.Printer(), err: errConnectionRefused, failing: func(sent int) bool { return true }}
+	options := testOptions
+	options.MaxAttempts = 3
+	spooler, err := Open(t.TempDir(), printer, options)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+
+	job, _ := spooler.Submit(receipt(t))
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusFailed || job.Attempts != 3 || job.Error != errConnectionRefused.Error() {
+		t.Errorf("Expected failed after 3 attempts, got %+v", job)
+	}
+
+	printer.mu.Lock()
+	printer.failing = func(sent int) bool { return false }
+	printer.mu.Unlock()
+	err = spooler.Retry(job.ID)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted {
+		t.Errorf("Expected printed after retry, got %+v", job)
+	}
+
+	fmt.Println("Completed testSpoolerMaxAttempts")
+}
+
+// failOnce returns a failing function of flakyPrinter failing once, when sent commands were sent.
+func failOnce(sent int) func(int) bool {
+	failed := false
+	return func(n int) bool {
+		if n == sent && !failed {
+			failed = true
+			return true
+		}
+		return false
+	}
+}
+
+func TestSpoolerInterrupted(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+
+	// The connection drops while waiting for the reply of the second command, which may have been printed:
+	// the receipt is cancelled and printed again.
+	printer := &flakyPrinter{Printer: emulator.Printer(), err: errConnectionReset, failing: failOnce(1)}
+	options, recoveries := recordRecoveries()
+	spooler, err := Open(t.TempDir(), printer, options)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+	job, _ := spooler.Submit(receipt(t))
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted || job.Sent != 3 || job.Recovery != RecoveryCancelled || job.Attempts != 2 {
+		t.Errorf("Expected printed job after cancelling, got %+v", job)
+	}
+	r := recoveries()
+	if len(r) != 2 || r[0].Action != RecoveryChecking || r[0].Err != errConnectionReset || r[0].Job.Sent != 1 || r[1].Action != RecoveryCancelled {
+		t.Errorf("Expected the job checked then cancelled, got %+v", r)
+	}
+	if state := emulator.State(); state.DailyTotal != 870 || state.DocumentNumber != 1 {
+		t.Errorf("Expected one receipt of 8,70, got %s", state)
+	}
+
+	fmt.Println("Completed testSpoolerInterrupted")
+}
+
+func TestSpoolerResumed(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+
+	// The connection is lost before the second command, the receipt is resumed where it stopped.
+	printer := &flakyPrinter{Printer: emulator.Printer(), err: gongoff.ErrConnectionLost, failing: failOnce(1)}
+	options, recoveries := recordRecoveries()
+	spooler, err := Open(t.TempDir(), printer, options)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer spooler.Close()
+	job, _ := spooler.Submit(receipt(t))
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusPrinted || job.Sent != 3 || job.Recovery != RecoveryResumed {
+		t.Errorf("Expected resumed job, got %+v", job)
+	}
+	if r := recoveries(); len(r) != 2 || r[0].Action != RecoveryChecking || r[1].Action != RecoveryResumed {
+		t.Errorf("Expected the job checked then resumed, got %+v", r)
+	}
+	if state := emulator.State(); state.DailyTotal != 870 || state.DocumentNumber != 1 {
+		t.Errorf("Expected one receipt of 8,70, got %s", state)
+	}
+	cancel, _ := gongoff.NewCommandCancelDocument().Encode()
+	for _, command := range emulator.Received() {
+		if command == string(cancel) {
+			t.Errorf("Expected the receipt not cancelled, got %v", emulator.Received())
+		}
+	}
+
+	// The connection drops before the first command is acknowledged, the printer has no document open:
+	// the command may have been printed without opening one, the job needs the operator.
+	printer.mu.Lock()
+	printer.err = errConnectionReset
+	printer.failing = failOnce(0)
+	printer.sent = 0
+	printer.mu.Unlock()
+	job, _ = spooler.Submit(receipt(t))
+	job = wait(t, spooler, job.ID)
+	if job.Status != StatusFailed || job.Recovery != RecoveryManual {
+		t.Errorf("Expected job interrupted, got %+v", job)
+	}
+	if r := recoveries(); len(r) != 4 || r[3].Action != RecoveryManual || r[3].Err != errNoDocumentOpen {
+		t.Errorf("Expected the job interrupted without a document open, got %+v", r)
+	}
+
+	fmt.Println("Completed testSpoolerResumed")
+}
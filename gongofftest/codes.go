@@ -0,0 +1,36 @@
+package gongofftest
+
+import "github.com/paolo96/gongoff"
+
+// Error codes answered by the emulator in its ERR replies, they are specific to the emulator.
+const (
+	ErrorCodeUnknownCommand          gongoff.ErrorCode = 1
+	ErrorCodeInvalidValue            gongoff.ErrorCode = 2
+	ErrorCodeInvalidSequence         gongoff.ErrorCode = 3
+	ErrorCodeDocumentOpen            gongoff.ErrorCode = 4
+	ErrorCodeDocumentNotOpen         gongoff.ErrorCode = 5
+	ErrorCodeNegativeTotal           gongoff.ErrorCode = 7
+	ErrorCodePaperEnd                gongoff.ErrorCode = 8
+	ErrorCodeCoverOpen               gongoff.ErrorCode = 9
+	ErrorCodeDepartmentNotProgrammed gongoff.ErrorCode = 11
+	ErrorCodePLUNotProgrammed        gongoff.ErrorCode = 12
+	ErrorCodeDailyClosureRequired    gongoff.ErrorCode = 14
+	ErrorCodeHardwareFailure         gongoff.ErrorCode = 17
+)
+
+// ErrorTable describes the error codes of the emulator, its messages are the text following the code in the ERR replies.
+// The paper end, the cover open and the overdue daily closure are recoverable once fixed with Update or a closure.
+var ErrorTable = gongoff.ErrorTable{
+	ErrorCodeUnknownCommand:          {Message: "UNKNOWN COMMAND"},
+	ErrorCodeInvalidValue:            {Message: "INVALID VALUE"},
+	ErrorCodeInvalidSequence:         {Message: "COMMAND NOT ALLOWED IN THE CURRENT STATE"},
+	ErrorCodeDocumentOpen:            {Message: "A DOCUMENT IS ALREADY OPEN"},
+	ErrorCodeDocumentNotOpen:         {Message: "NO DOCUMENT IS OPEN"},
+	ErrorCodeNegativeTotal:           {Message: "NEGATIVE TOTAL"},
+	ErrorCodePaperEnd:                {Message: "PAPER END", Recoverable: true},
+	ErrorCodeCoverOpen:               {Message: "COVER OPEN", Recoverable: true},
+	ErrorCodeDepartmentNotProgrammed: {Message: "DEPARTMENT NOT PROGRAMMED"},
+	ErrorCodePLUNotProgrammed:        {Message: "PLU NOT PROGRAMMED"},
+	ErrorCodeDailyClosureRequired:    {Message: "DAILY CLOSURE REQUIRED", Recoverable: true},
+	ErrorCodeHardwareFailure:         {Message: "HARDWARE FAILURE"},
+}
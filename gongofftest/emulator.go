@@ -0,0 +1,240 @@
+// Package gongofftest provides an emulated fiscal printer speaking the Xon-Xoff protocol over TCP,
+// useful to test code using gongoff without real hardware.
+//
+// The emulator keeps the fiscal state of the printer (open document, totals, payments, counters),
+// enforces the protocol rules and answers every command with the reply lines read by the printers
+// with replies enabled, see gongoff.GenericPrinter.SetReplies.
+package gongofftest
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paolo96/gongoff"
+)
+
+// ambiguityDelay is how long the emulator waits for the rest of a command ending with '@'.
+const ambiguityDelay = 50 * time.Millisecond
+
+// Emulator is an emulated fiscal printer listening on a local TCP port.
+type Emulator struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	state    State
+	received []string
+	failures []gongoff.ErrorCode
+	conns    map[net.Conn]bool
+	closed   bool
+}
+
+// NewEmulator starts an emulated printer listening on the loopback interface.
+// It panics if the listener cannot be created, the caller should Close it when finished.
+func NewEmulator() *Emulator {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("gongofftest: failed to listen: " + err.Error())
+	}
+	e := &Emulator{
+		listener: listener,
+		conns:    map[net.Conn]bool{},
+	}
+	e.wg.Add(1)
+	go e.serve()
+	return e
+}
+
+// Host returns the address the emulator listens on, to be used with gongoff.NewNetworkPrinter.
+func (e *Emulator) Host() string {
+	return e.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the emulator listens on, to be used with gongoff.NewNetworkPrinter.
+func (e *Emulator) Port() int {
+	return e.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Printer returns a new, not yet opened, NetworkPrinter connected to the emulator, with replies enabled.
+func (e *Emulator) Printer() *gongoff.NetworkPrinter {
+	printer := gongoff.NewNetworkPrinter(e.Host(), e.Port())
+	printer.SetReplies(true)
+	return printer
+}
+
+// State returns a snapshot of the fiscal state.
+func (e *Emulator) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.clone()
+}
+
+// Received returns the raw commands received so far.
+func (e *Emulator) Received() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.received...)
+}
+
+// Update changes the state of the emulated printer, ex. to simulate the paper running out.
+func (e *Emulator) Update(fn func(state *State)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn(&e.state)
+}
+
+// FailNext makes the emulator refuse the next command with the given error code, without changing the state.
+// Multiple calls queue multiple failures.
+func (e *Emulator) FailNext(code gongoff.ErrorCode) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failures = append(e.failures, code)
+}
+
+// Disconnect closes the current connections, like a network failure, the emulator keeps accepting new ones.
+func (e *Emulator) Disconnect() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for conn := range e.conns {
+		_ = conn.Close()
+	}
+}
+
+// Close stops the emulator and closes all the connections.
+func (e *Emulator) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	for conn := range e.conns {
+		_ = conn.Close()
+	}
+	e.mu.Unlock()
+	err := e.listener.Close()
+	e.wg.Wait()
+	return err
+}
+
+func (e *Emulator) serve() {
+	defer e.wg.Done()
+	for {
+		conn, err := e.listener.Accept()
+		if err != nil {
+			return
+		}
+		e.mu.Lock()
+		if e.closed {
+			e.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		e.conns[conn] = true
+		e.mu.Unlock()
+
+		e.wg.Add(1)
+		go e.handle(conn)
+	}
+}
+
+// handle reads the commands sent on conn and answers each of them.
+func (e *Emulator) handle(conn net.Conn) {
+	defer e.wg.Done()
+	defer func() {
+		e.mu.Lock()
+		delete(e.conns, conn)
+		e.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	var pending string
+	buf := make([]byte, 1024)
+	final := false
+	for {
+		if final {
+			_ = conn.SetReadDeadline(time.Now().Add(ambiguityDelay))
+		}
+		n, err := conn.Read(buf)
+		_ = conn.SetReadDeadline(time.Time{})
+		if err != nil && !(final && errors.Is(err, os.ErrDeadlineExceeded)) {
+			return
+		}
+		pending += string(buf[:n])
+		// When no data followed a trailing '@' the command is complete.
+		complete := final && n == 0
+		final = false
+
+		for pending != "" {
+			cmd, rest, err := parseCommand(pending, complete)
+			if err == errIncomplete {
+				final = strings.HasSuffix(pending, "@")
+				break
+			}
+			var answer string
+			if err != nil {
+				answer = e.answer(pending, refuse(ErrorCodeUnknownCommand))
+				rest = ""
+			} else {
+				answer = e.execute(cmd)
+			}
+			pending = rest
+			_, err = conn.Write([]byte(answer + "\r\n"))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// execute applies cmd to the fiscal state and returns the response line.
+func (e *Emulator) execute(cmd *command) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.received = append(e.received, cmd.raw)
+
+	if len(e.failures) > 0 {
+		code := e.failures[0]
+		e.failures = e.failures[1:]
+		return formatError(code)
+	}
+
+	// Commands are applied to a copy so that a refused command leaves the state untouched.
+	state := e.state.clone()
+	err := state.apply(cmd)
+	if err != nil {
+		return e.formatErr(err)
+	}
+	e.state = state
+	if cmd.terminator == terminatorStatusRequest {
+		if status := state.status(); status != "" {
+			return "OK " + status
+		}
+	}
+	return "OK"
+}
+
+// answer records an unparsable command and returns the response line for err.
+func (e *Emulator) answer(raw string, err error) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.received = append(e.received, raw)
+	return e.formatErr(err)
+}
+
+func (e *Emulator) formatErr(err error) string {
+	var refusal *commandError
+	if errors.As(err, &refusal) {
+		return formatError(refusal.code)
+	}
+	return formatError(ErrorCodeHardwareFailure)
+}
+
+func formatError(code gongoff.ErrorCode) string {
+	answer := "ERR " + strconv.Itoa(int(code))
+	if description, ok := ErrorTable[code]; ok {
+		answer += " " + description.Message
+	}
+	return answer
+}
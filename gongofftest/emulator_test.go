@@ -0,0 +1,428 @@
+package gongofftest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/paolo96/gongoff"
+)
+
+// openPrinter starts an emulator and returns a printer connected to it.
+func openPrinter(t *testing.T) (*Emulator, *gongoff.NetworkPrinter) {
+	emulator := NewEmulator()
+	t.Cleanup(func() { _ = emulator.Close() })
+	printer := emulator.Printer()
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	t.Cleanup(func() { _ = printer.Close() })
+	return emulator, printer
+}
+
+func TestEmulatorDocumentCommercial(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	product := "BREAD"
+	quantity := 2
+	payment, err := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discount, err := gongoff.NewCommandDiscountAmount(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := gongoff.NewDocumentCommercial(
+		[]gongoff.CommandProduct{*gongoff.NewCommandProduct(750, &product, &quantity, nil)},
+		[]gongoff.CommandPayment{*payment},
+		discount,
+		nil,
+		nil,
+		gongoff.NewCommandTrailer("Thank you"),
+	)
+	err = printer.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	state := emulator.State()
+	if state.Document != DocumentNone || state.DailyTotal != 1400 || state.DocumentNumber != 1 {
+		t.Errorf("Expected closed document with daily total 1400, got %s", state)
+	}
+
+	fmt.Println("Completed testEmulatorDocumentCommercial")
+}
+
+func TestEmulatorReceiptBuilder(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	doc, err := gongoff.NewReceiptBuilder().
+		AddItemQty("MILK", 120, 2, 1).DiscountPercent(10).
+		AddItem("DELIVERY", 300, 2).Surcharge(50).
+		PayRest(gongoff.TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	err = printer.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	state := emulator.State()
+	if state.Document != DocumentNone || state.DailyTotal != totals.Total {
+		t.Errorf("Expected closed document with daily total %s, got %s", totals.Total, state)
+	}
+
+	fmt.Println("Completed testEmulatorReceiptBuilder")
+}
+
+func TestEmulatorPLU(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+	emulator.Update(func(state *State) {
+		state.PLUs = map[int]PLU{
+			12: {Description: "WATER", UnitPrice: 100, Department: 2},
+			7:  {Description: "COFFEE", UnitPrice: 120, Department: 1},
+		}
+	})
+
+	doc, err := gongoff.NewReceiptBuilder().
+		AddPLU(12, 3).
+		AddPLUPrice(7, 1, 150).
+		DiscountDepartment("BOTTLE", 50, 2).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = printer.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	state := emulator.State()
+	if len(state.Items) != 3 || state.Items[0].Description != "WATER" || state.Items[0].Amount != 300 || state.Items[1].Amount != 150 || state.Items[2].Amount != -50 {
+		t.Errorf("Expected water, coffee at 1,50 and bottle discount, got %+v", state.Items)
+	}
+	if state.Total != 400 {
+		t.Errorf("Expected total 400, got %s", state.Total)
+	}
+
+	plu, _ := gongoff.NewCommandProductPLU(99, nil, nil)
+	err = printer.PrintCommands([]gongoff.Command{plu})
+	if !errors.Is(err, ErrorCodePLUNotProgrammed) {
+		t.Errorf("Expected ErrorCodePLUNotProgrammed, got %v", err)
+	}
+	discount, _ := gongoff.NewCommandDiscountDepartment(500, nil, 2)
+	err = printer.PrintCommands([]gongoff.Command{discount})
+	if !errors.Is(err, ErrorCodeNegativeTotal) {
+		t.Errorf("Expected ErrorCodeNegativeTotal, got %v", err)
+	}
+
+	fmt.Println("Completed testEmulatorPLU")
+}
+
+func TestEmulatorQuantity(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+	emulator.Update(func(state *State) {
+		state.PLUs = map[int]PLU{12: {Description: "HAM", UnitPrice: 2400, Department: 2}}
+	})
+
+	doc, err := gongoff.NewReceiptBuilder().
+		AddItemWeight("CHEESE", 1890, 750, 2).
+		AddPLUWeight(12, 125).
+		AddItemWeight("OLIVES", 1200, 200, 2).
+		VoidLastItem().
+		PayRest(gongoff.TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	totals, err := doc.Totals()
+	if !errors.Is(err, gongoff.ErrProgrammedPLU) {
+		t.Errorf("Expected ErrProgrammedPLU, got %v, %+v", err, totals)
+	}
+	err = printer.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 0,750 kg * 18,90 = 14,18 and 0,125 kg * 24,00 = 3,00.
+	state := emulator.State()
+	if state.DailyTotal != 1718 {
+		t.Errorf("Expected daily total 17,18, got %s", state.DailyTotal)
+	}
+
+	fmt.Println("Completed testEmulatorQuantity")
+}
+
+func TestEmulatorSubtotal(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	doc, err := gongoff.NewReceiptBuilder().
+		AddItem("BREAD", 1000, 1).SurchargePercent(10).
+		AddItem("MILK", 500, 2).
+		SubtotalDiscountPercent(10).
+		SubtotalSurcharge(105).
+		PayRest(gongoff.TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = printer.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 11,00 + 5,00 = 16,00 - 1,60 + 1,05 = 15,45
+	state := emulator.State()
+	if state.DailyTotal != 1545 || totals.Total != 1545 {
+		t.Errorf("Expected daily total 1545, got %s (totals %s)", state, totals.Total)
+	}
+
+	subtotalDiscount, _ := gongoff.NewCommandDiscountAmountSubtotal(100)
+	err = printer.PrintCommands([]gongoff.Command{
+		gongoff.NewCommandProduct(1000, nil, nil, nil),
+		subtotalDiscount,
+	})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	state = emulator.State()
+	if state.Total != 900 || state.SubtotalAdjustment != -100 {
+		t.Errorf("Expected total 900 with subtotal adjustment -100, got %s (%s)", state, state.SubtotalAdjustment)
+	}
+	discount, _ := gongoff.NewCommandDiscountAmount(100)
+	err = printer.PrintCommands([]gongoff.Command{discount})
+	if !errors.Is(err, ErrorCodeInvalidSequence) {
+		t.Errorf("Expected ErrorCodeInvalidSequence, got %v", err)
+	}
+
+	fmt.Println("Completed testEmulatorSubtotal")
+}
+
+func TestEmulatorVoidItem(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	doc, err := gongoff.NewReceiptBuilder().
+		AddItem("BREAD", 750, 1).DiscountAmount(50).
+		AddItemQty("MILK", 120, 2, 1).
+		AddItem("BAG", 10, 1).
+		VoidLastItem().
+		VoidItem(0).
+		PayRest(gongoff.TerminatorTypePaymentCash).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = printer.PrintDocument(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	state := emulator.State()
+	if state.DailyTotal != 240 || totals.Total != 240 {
+		t.Errorf("Expected daily total 240, got %s (totals %s)", state, totals.Total)
+	}
+
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil), gongoff.NewCommandVoidItem(nil)})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	product := "BREAD"
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandVoidItem(gongoff.NewCommandProduct(750, &product, nil, nil))})
+	if !errors.Is(err, ErrorCodeInvalidSequence) {
+		t.Errorf("Expected ErrorCodeInvalidSequence, got %v", err)
+	}
+	if state := emulator.State(); len(state.Items) != 0 || state.Total != 0 {
+		t.Errorf("Expected no items, got %+v", state.Items)
+	}
+
+	fmt.Println("Completed testEmulatorVoidItem")
+}
+
+func TestEmulatorPayments(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	cash, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, nil, nil)
+	err := printer.PrintCommands([]gongoff.Command{cash})
+	if !errors.Is(err, ErrorCodeDocumentNotOpen) {
+		t.Errorf("Expected ErrorCodeDocumentNotOpen, got %v", err)
+	}
+
+	partial := gongoff.Amount(500)
+	partialPayment, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCards, &partial, nil)
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil), partialPayment})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	state := emulator.State()
+	if state.Document != DocumentCommercial || state.Total != 750 || state.Paid != 500 {
+		t.Errorf("Expected open document with 500 of 750 paid, got %s", state)
+	}
+
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(100, nil, nil, nil)})
+	if !errors.Is(err, ErrorCodeInvalidSequence) {
+		t.Errorf("Expected ErrorCodeInvalidSequence, got %v", err)
+	}
+
+	overpayment := gongoff.Amount(1000)
+	cardsOverpayment, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCards, &overpayment, nil)
+	err = printer.PrintCommands([]gongoff.Command{cardsOverpayment})
+	if !errors.Is(err, ErrorCodeInvalidValue) {
+		t.Errorf("Expected ErrorCodeInvalidValue, got %v", err)
+	}
+
+	cashOverpayment, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, &overpayment, nil)
+	err = printer.PrintCommands([]gongoff.Command{cashOverpayment})
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	state = emulator.State()
+	if state.Document != DocumentNone || state.DailyTotal != 750 {
+		t.Errorf("Expected closed document with daily total 750, got %s", state)
+	}
+
+	fmt.Println("Completed testEmulatorPayments")
+}
+
+func TestEmulatorDocumentManagement(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	rows := []string{"first", strings.Repeat("X", 46), "@40F is not a terminator here"}
+	err := printer.PrintDocument(gongoff.NewDocumentManagement(rows))
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	received := emulator.Received()
+	if len(received) != 5 || received[2] != "\""+rows[1]+"\"@" {
+		t.Errorf("Expected 5 commands, got %v", received)
+	}
+
+	err = printer.PrintCommands([]gongoff.Command{
+		gongoff.NewCommandGeneric([]gongoff.Data{}, *gongoff.NewTerminator(nil, gongoff.TerminatorTypeCloseManagementDocument)),
+	})
+	if !errors.Is(err, ErrorCodeDocumentNotOpen) {
+		t.Errorf("Expected ErrorCodeDocumentNotOpen, got %v", err)
+	}
+
+	fmt.Println("Completed testEmulatorDocumentManagement")
+}
+
+func TestEmulatorFiscalClosure(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+	closure := gongoff.NewCommandGeneric([]gongoff.Data{}, *gongoff.NewTerminator(nil, gongoff.TerminatorTypeFinancialReportAndFiscalClosureZeroing))
+
+	err := printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil), closure})
+	if !errors.Is(err, ErrorCodeDocumentOpen) {
+		t.Errorf("Expected ErrorCodeDocumentOpen, got %v", err)
+	}
+
+	cash, _ := gongoff.NewCommandPayment(gongoff.TerminatorTypePaymentCash, nil, nil)
+	err = printer.PrintCommands([]gongoff.Command{cash, closure})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	state := emulator.State()
+	if state.DailyTotal != 0 || state.DocumentNumber != 0 || state.ClosureNumber != 1 {
+		t.Errorf("Expected zeroed daily totals after closure 1, got %s", state)
+	}
+
+	fmt.Println("Completed testEmulatorFiscalClosure")
+}
+
+func TestEmulatorFailNext(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+	emulator.FailNext(ErrorCodePaperEnd)
+
+	err := printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+	var printerError *gongoff.PrinterError
+	if !errors.As(err, &printerError) || printerError.Code != ErrorCodePaperEnd || printerError.Message != "PAPER END" {
+		t.Errorf("Expected paper end error, got %v", err)
+	}
+	if !ErrorTable.Recoverable(err) {
+		t.Errorf("Expected paper end to be recoverable")
+	}
+	if state := emulator.State(); state.Document != DocumentNone {
+		t.Errorf("Expected refused command to leave the state untouched, got %s", state)
+	}
+
+	fmt.Println("Completed testEmulatorFailNext")
+}
+
+func TestEmulatorStatus(t *testing.T) {
+
+	emulator, printer := openPrinter(t)
+
+	status, err := printer.Status()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if status.DocumentOpen() {
+		t.Errorf("Expected no document open, got %q", status.Document)
+	}
+
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	status, err = printer.Status()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if status.Document != string(DocumentCommercial) {
+		t.Errorf("Expected commercial document open, got %q", status.Document)
+	}
+
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandCancelDocument()})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.Document != DocumentNone || state.DailyTotal != 0 {
+		t.Errorf("Expected cancelled document, got %s", state)
+	}
+
+	emulator.Update(func(state *State) {
+		state.PaperOut = true
+		state.ClosureOverdue = true
+	})
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+	if !errors.Is(err, ErrorCodePaperEnd) {
+		t.Errorf("Expected ErrorCodePaperEnd, got %v", err)
+	}
+	status, err = printer.Status()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if !status.PaperOut || !status.ClosureOverdue || status.CanPrint() {
+		t.Errorf("Expected paper out and closure overdue, got %+v", status)
+	}
+
+	emulator.Update(func(state *State) { state.PaperOut = false })
+	err = printer.PrintCommands([]gongoff.Command{gongoff.NewCommandProduct(750, nil, nil, nil)})
+	if !errors.Is(err, ErrorCodeDailyClosureRequired) {
+		t.Errorf("Expected ErrorCodeDailyClosureRequired, got %v", err)
+	}
+
+	fmt.Println("Completed testEmulatorStatus")
+}
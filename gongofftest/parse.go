@@ -0,0 +1,60 @@
+package gongofftest
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/paolo96/gongoff"
+)
+
+// errIncomplete is returned when the stream ends before the command terminator.
+var errIncomplete = errors.New("incomplete command")
+
+// terminatorStatusRequest terminates the status request, see gongoff.NewCommandStatusRequest.
+// It is not an Epson terminator, so gongoff does not export it.
+const terminatorStatusRequest gongoff.TerminatorType = "?"
+
+// command is a command received by the emulator.
+type command struct {
+	raw        string
+	data       []gongoff.Data
+	variable   string
+	terminator gongoff.TerminatorType
+}
+
+// decomposable is implemented by all the commands returned by gongoff.ParseNext.
+type decomposable interface {
+	Data() []gongoff.Data
+	Terminator() gongoff.Terminator
+}
+
+// parseCommand parses the first command of s and returns the remaining text.
+// Unless final is true, a trailing '@' is considered incomplete since it could be the beginning of "@40F".
+func parseCommand(s string, final bool) (*command, string, error) {
+	s = strings.TrimLeft(s, " \r\n")
+	parsed, rest, err := gongoff.ParseNext(s)
+	if errors.Is(err, gongoff.ErrIncompleteCommand) {
+		return nil, s, errIncomplete
+	}
+	if err != nil {
+		return nil, s, err
+	}
+	if rest == "" && !final && strings.HasSuffix(s, "@") {
+		return nil, s, errIncomplete
+	}
+
+	parts, ok := parsed.(decomposable)
+	if !ok {
+		return nil, s, gongoff.ErrInvalidCommand
+	}
+	terminator := parts.Terminator()
+	cmd := &command{
+		raw:        s[:len(s)-len(rest)],
+		data:       parts.Data(),
+		terminator: terminator.Type(),
+	}
+	if terminator.Variable() != nil {
+		cmd.variable = *terminator.Variable()
+	}
+	return cmd, rest, nil
+}
@@ -0,0 +1,49 @@
+package gongofftest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/paolo96/gongoff"
+)
+
+func TestParseCommand(t *testing.T) {
+
+	stream := "\"BREAD\"2*750H3R10.001M100H\"test\"3T\"Hello\"@40F\"row\"@j"
+	var commands []*command
+	for stream != "" {
+		cmd, rest, err := parseCommand(stream, true)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		commands = append(commands, cmd)
+		stream = rest
+	}
+	if len(commands) != 6 {
+		t.Fatalf("Expected 6 commands, got %d", len(commands))
+	}
+
+	product := commands[0]
+	if product.terminator != gongoff.TerminatorTypeSold || product.variable != "3" || len(product.data) != 3 {
+		t.Errorf("Expected product in department 3, got %+v", product)
+	}
+	discount := commands[1]
+	if discount.terminator != gongoff.TerminatorTypeDiscountPercentTransaction || discount.data[0].Variable() != "10.00" {
+		t.Errorf("Expected 10.00 percent discount, got %+v", discount)
+	}
+	if commands[2].terminator != gongoff.TerminatorTypePaymentCards || commands[3].terminator != gongoff.TerminatorTypePrintCourtesyMessage {
+		t.Errorf("Expected payment and trailer, got %+v %+v", commands[2], commands[3])
+	}
+	if commands[4].terminator != gongoff.TerminatorTypeAdditionalDescription || commands[5].terminator != gongoff.TerminatorTypeOpenManagementDocument {
+		t.Errorf("Expected description and open management, got %+v %+v", commands[4], commands[5])
+	}
+
+	for _, incomplete := range []string{"\"BREAD", "750", "10.00", "\"row\"@"} {
+		_, _, err := parseCommand(incomplete, false)
+		if err != errIncomplete {
+			t.Errorf("Expected %q to be incomplete, got %v", incomplete, err)
+		}
+	}
+
+	fmt.Println("Completed testParseCommand")
+}
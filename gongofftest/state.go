@@ -0,0 +1,714 @@
+package gongofftest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paolo96/gongoff"
+)
+
+// DocumentKind is the kind of document currently open on the emulated printer.
+type DocumentKind string
+
+const (
+	DocumentNone         DocumentKind = ""
+	DocumentCommercial   DocumentKind = "commercial"
+	DocumentManagement   DocumentKind = "management"
+	DocumentReturn       DocumentKind = "return"
+	DocumentCancellation DocumentKind = "cancellation"
+	DocumentInvoice      DocumentKind = "invoice"
+)
+
+// PLU is a product programmed in the emulated printer, sold by its number.
+type PLU struct {
+	Description string
+	UnitPrice   gongoff.Amount
+	Department  int
+}
+
+// Item is a sale registered in the open document.
+// Department discounts are registered as items with negative UnitPrice and Amount.
+type Item struct {
+	// PLU is the number of the PLU sold, 0 for sales by department.
+	PLU         int
+	Description string
+	// Quantity is the sold quantity, fractional for items sold by weight.
+	Quantity   gongoff.Quantity
+	UnitPrice  gongoff.Amount
+	Department int
+	// Amount is the line amount after its discounts.
+	Amount gongoff.Amount
+}
+
+// Payment is a payment registered in the open document.
+type Payment struct {
+	Method gongoff.TerminatorType
+	Amount gongoff.Amount
+}
+
+// State is a snapshot of the fiscal state of the emulated printer.
+type State struct {
+	Document DocumentKind
+	Items    []Item
+	Payments []Payment
+	// Total is the amount due for the open document.
+	Total gongoff.Amount
+	// Paid is the amount paid for the open document.
+	Paid gongoff.Amount
+	// SubtotalAdjustment is the sum of the subtotal increases minus the subtotal discounts of the open document, included in Total.
+	SubtotalAdjustment gongoff.Amount
+	// Lines are the text lines printed in the open management document.
+	Lines []string
+	// DailyTotal is the total of the commercial documents closed since the last fiscal closure.
+	DailyTotal gongoff.Amount
+	// DocumentNumber is the number of documents closed since the last fiscal closure.
+	DocumentNumber int
+	// ClosureNumber is the number of fiscal closures.
+	ClosureNumber int
+	InvoiceNumber int
+	Display       [2]string
+	DrawerOpened  int
+	// Clock is the date and time last set on the printer, zero if never set.
+	Clock time.Time
+	// PLUs are the products programmed in the printer by number, see Emulator.Update.
+	PLUs map[int]PLU
+
+	// Conditions reported by the status request, see Emulator.Update.
+	// PaperOut and CoverOpen make the printer refuse every command but the status request and the display,
+	// ClosureOverdue refuses new fiscal documents until the fiscal closure.
+	PaperLow               bool
+	PaperOut               bool
+	CoverOpen              bool
+	FiscalMemoryNearlyFull bool
+	ClosureOverdue         bool
+
+	// lineClosed is set by subtotal adjustments and voids until the next sale, line adjustments are refused.
+	lineClosed bool
+	// subtotalSpread is set by the first subtotal adjustment, voids are refused afterwards.
+	subtotalSpread bool
+}
+
+// clone returns a copy of the state not sharing slices and maps with s.
+func (s State) clone() State {
+	s.Items = append([]Item(nil), s.Items...)
+	s.Payments = append([]Payment(nil), s.Payments...)
+	s.Lines = append([]string(nil), s.Lines...)
+	if s.PLUs != nil {
+		plus := make(map[int]PLU, len(s.PLUs))
+		for number, plu := range s.PLUs {
+			plus[number] = plu
+		}
+		s.PLUs = plus
+	}
+	return s
+}
+
+// commandError is a refusal of the emulated printer.
+type commandError struct {
+	code gongoff.ErrorCode
+}
+
+func refuse(code gongoff.ErrorCode) error {
+	return &commandError{code: code}
+}
+
+func (e *commandError) Error() string {
+	return e.code.Error()
+}
+
+// returnKinds are the documents closed automatically by the first command they do not accept.
+var returnKinds = map[DocumentKind]bool{
+	DocumentReturn:       true,
+	DocumentCancellation: true,
+}
+
+// apply executes cmd on the state.
+func (s *State) apply(cmd *command) error {
+
+	switch cmd.terminator {
+	case terminatorStatusRequest,
+		gongoff.TerminatorTypeViewDescriptionOnDisplayFirstLine,
+		gongoff.TerminatorTypeViewDescriptionOnDisplaySecondLine:
+	default:
+		if s.PaperOut {
+			return refuse(ErrorCodePaperEnd)
+		}
+		if s.CoverOpen {
+			return refuse(ErrorCodeCoverOpen)
+		}
+	}
+
+	if returnKinds[s.Document] && !s.acceptedByReturn(cmd) {
+		s.closeDocument()
+	}
+
+	switch cmd.terminator {
+	case gongoff.TerminatorTypeSold:
+		return s.sell(cmd)
+	case gongoff.TerminatorTypeSoldPLU:
+		return s.sellPLU(cmd)
+	case gongoff.TerminatorTypeDiscountDepartment:
+		return s.discountDepartment(cmd)
+	case gongoff.TerminatorTypeDiscountPercentTransaction, gongoff.TerminatorTypeDiscountValueTransaction,
+		gongoff.TerminatorTypeIncreasePercentTransaction, gongoff.TerminatorTypeIncreaseValueTransaction:
+		return s.adjustLastItem(cmd)
+	case gongoff.TerminatorTypeDiscountPercentSubtotal, gongoff.TerminatorTypeDiscountValueSubtotal,
+		gongoff.TerminatorTypeIncreasePercentSubtotal, gongoff.TerminatorTypeIncreaseValueSubtotal:
+		return s.adjustSubtotal(cmd)
+	case gongoff.TerminatorTypeCancellation:
+		return s.void(cmd)
+	case gongoff.TerminatorTypeSubtotal:
+		if !s.saleOpen() {
+			return refuse(ErrorCodeDocumentNotOpen)
+		}
+		return nil
+	case gongoff.TerminatorTypeAdditionalDescription:
+		switch s.Document {
+		case DocumentManagement:
+			s.Lines = append(s.Lines, description(cmd))
+			return nil
+		case DocumentNone:
+			return refuse(ErrorCodeDocumentNotOpen)
+		}
+		return nil
+	case gongoff.TerminatorTypeOpenManagementDocument:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		s.Document = DocumentManagement
+		return nil
+	case gongoff.TerminatorTypeCloseManagementDocument:
+		if s.Document != DocumentManagement {
+			return refuse(ErrorCodeDocumentNotOpen)
+		}
+		s.closeDocument()
+		return nil
+	case gongoff.TerminatorTypeCancelDocumentOrInvoice:
+		if s.Document == DocumentNone {
+			return refuse(ErrorCodeDocumentNotOpen)
+		}
+		s.resetDocument()
+		return nil
+	case gongoff.TerminatorTypeOpenReturnDocumentCommercial, gongoff.TerminatorTypeOpenReturnDocumentPOS:
+		return s.open(DocumentReturn)
+	case gongoff.TerminatorTypeOpenCancellationDocumentCommercial, gongoff.TerminatorTypeOpenCancellationDocumentPOS:
+		return s.open(DocumentCancellation)
+	case gongoff.TerminatorTypeDirectInvoice:
+		return s.open(DocumentInvoice)
+	case gongoff.TerminatorTypeInvoiceCommercialDocument:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		s.InvoiceNumber++
+		return nil
+	case gongoff.TerminatorTypeResetInvoiceNumber:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		s.InvoiceNumber = 0
+		return nil
+	case gongoff.TerminatorTypeInvoiceCustomerDetails,
+		gongoff.TerminatorTypePrintCustomerIdentifier,
+		gongoff.TerminatorTypePrintCourtesyMessage,
+		gongoff.TerminatorTypePrintTrailerAfterLogo,
+		gongoff.TerminatorTypeLotteryCode,
+		gongoff.TerminatorTypePrintBarcodeEAN13,
+		gongoff.TerminatorTypePrintBarcodeEAN8,
+		gongoff.TerminatorTypePrintBarcodeCODE39,
+		gongoff.TerminatorTypePrintNotCalculated,
+		gongoff.TerminatorTypeClear,
+		gongoff.TerminatorTypeSelectOperator,
+		gongoff.TerminatorTypeLockKeyboard,
+		gongoff.TerminatorTypeUnlockKeyboard,
+		gongoff.TerminatorTypeDisableXonXoff,
+		gongoff.TerminatorTypeDisableXonXoff2:
+		return nil
+	case gongoff.TerminatorTypeViewDescriptionOnDisplayFirstLine:
+		s.Display[0] = description(cmd)
+		return nil
+	case gongoff.TerminatorTypeViewDescriptionOnDisplaySecondLine:
+		s.Display[1] = description(cmd)
+		return nil
+	case gongoff.TerminatorTypeSetDateTime:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		clock, err := time.ParseInLocation("0201061504", cmd.variable, time.Local)
+		if err != nil || len(cmd.data) != 0 {
+			return refuse(ErrorCodeInvalidValue)
+		}
+		s.Clock = clock
+		return nil
+	case gongoff.TerminatorTypeOpenCashRegister:
+		s.DrawerOpened++
+		return nil
+	case terminatorStatusRequest:
+		if len(cmd.data) != 0 {
+			return refuse(ErrorCodeInvalidValue)
+		}
+		return nil
+	case gongoff.TerminatorTypeFinancialReportNoZeroing,
+		gongoff.TerminatorTypeDepartmentReportNoZeroing,
+		gongoff.TerminatorTypePLUReportNoZeroing,
+		gongoff.TerminatorTypeOperatorsReportNoZeroing,
+		gongoff.TerminatorTypeDepartmentReportZeroing,
+		gongoff.TerminatorTypePLUReportZeroing,
+		gongoff.TerminatorTypeOperatorsReportZeroing,
+		gongoff.TerminatorTypePrintFiscalMemoryAll,
+		gongoff.TerminatorTypePrintFiscalMemoryByDate,
+		gongoff.TerminatorTypePrintFiscalMemoryByClosureNumber,
+		gongoff.TerminatorTypePrintDetailsMemoryAll,
+		gongoff.TerminatorTypePrintDetailsMemoryByDate,
+		gongoff.TerminatorTypePrintDetailsMemoryByClosureNumber:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		return nil
+	case gongoff.TerminatorTypeFinancialReportZeroing:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		s.DailyTotal = 0
+		return nil
+	case gongoff.TerminatorTypeFinancialReportAndFiscalClosureZeroing:
+		if s.Document != DocumentNone {
+			return refuse(ErrorCodeDocumentOpen)
+		}
+		s.DailyTotal = 0
+		s.DocumentNumber = 0
+		s.ClosureNumber++
+		s.ClosureOverdue = false
+		return nil
+	}
+
+	if strings.HasSuffix(string(cmd.terminator), "T") || cmd.terminator == gongoff.TerminatorTypePaymentWithCredit {
+		return s.pay(cmd)
+	}
+	return refuse(ErrorCodeUnknownCommand)
+}
+
+// acceptedByReturn reports whether cmd belongs to an open return or cancellation document.
+func (s *State) acceptedByReturn(cmd *command) bool {
+	return cmd.terminator == gongoff.TerminatorTypeSold ||
+		cmd.terminator == gongoff.TerminatorTypeSoldPLU ||
+		cmd.terminator == gongoff.TerminatorTypeCancelDocumentOrInvoice ||
+		strings.HasSuffix(string(cmd.terminator), "T")
+}
+
+// saleOpen reports whether the open document accepts sales.
+func (s *State) saleOpen() bool {
+	switch s.Document {
+	case DocumentCommercial, DocumentReturn, DocumentCancellation, DocumentInvoice:
+		return true
+	}
+	return false
+}
+
+func (s *State) open(kind DocumentKind) error {
+	if s.Document != DocumentNone {
+		return refuse(ErrorCodeDocumentOpen)
+	}
+	if s.ClosureOverdue {
+		return refuse(ErrorCodeDailyClosureRequired)
+	}
+	s.Document = kind
+	return nil
+}
+
+// openSale opens a commercial document on the first sale and checks that the open document accepts sales.
+func (s *State) openSale() error {
+	if s.Document == DocumentNone {
+		if s.ClosureOverdue {
+			return refuse(ErrorCodeDailyClosureRequired)
+		}
+		s.Document = DocumentCommercial
+	}
+	if !s.saleOpen() || s.Paid > 0 {
+		return refuse(ErrorCodeInvalidSequence)
+	}
+	s.lineClosed = false
+	return nil
+}
+
+func (s *State) sell(cmd *command) error {
+	err := s.openSale()
+	if err != nil {
+		return err
+	}
+
+	item := Item{Quantity: gongoff.NewQuantity(1), Department: 1}
+	for _, d := range cmd.data {
+		switch d.Separator() {
+		case gongoff.SeparatorTypeDescription, gongoff.SeparatorTypeDescriptionDoubleHeight:
+			item.Description = d.Variable()
+		case gongoff.SeparatorTypeMultiply:
+			quantity, err := gongoff.ParseQuantity(d.Variable())
+			if err != nil || quantity <= 0 {
+				return refuse(ErrorCodeInvalidValue)
+			}
+			item.Quantity = quantity
+		case gongoff.SeparatorTypeValue:
+			price, err := parseAmount(d.Variable())
+			if err != nil {
+				return refuse(ErrorCodeInvalidValue)
+			}
+			item.UnitPrice = price
+		}
+	}
+	if cmd.variable != "" {
+		department, err := strconv.Atoi(cmd.variable)
+		if err != nil || department <= 0 {
+			return refuse(ErrorCodeDepartmentNotProgrammed)
+		}
+		item.Department = department
+	}
+	if item.UnitPrice <= 0 {
+		return refuse(ErrorCodeInvalidValue)
+	}
+
+	item.Amount = item.UnitPrice.MultiplyQuantity(item.Quantity)
+	s.Items = append(s.Items, item)
+	s.Total += item.Amount
+	return nil
+}
+
+// sellPLU sells a programmed PLU, the quantity and the price can be given with the command.
+func (s *State) sellPLU(cmd *command) error {
+	number, err := strconv.Atoi(cmd.variable)
+	if err != nil || number <= 0 {
+		return refuse(ErrorCodePLUNotProgrammed)
+	}
+	plu, ok := s.PLUs[number]
+	if !ok {
+		return refuse(ErrorCodePLUNotProgrammed)
+	}
+	err = s.openSale()
+	if err != nil {
+		return err
+	}
+
+	item := Item{PLU: number, Description: plu.Description, Quantity: gongoff.NewQuantity(1), UnitPrice: plu.UnitPrice, Department: plu.Department}
+	for _, d := range cmd.data {
+		switch d.Separator() {
+		case gongoff.SeparatorTypeMultiply:
+			quantity, err := gongoff.ParseQuantity(d.Variable())
+			if err != nil || quantity <= 0 {
+				return refuse(ErrorCodeInvalidValue)
+			}
+			item.Quantity = quantity
+		case gongoff.SeparatorTypeValue:
+			price, err := parseAmount(d.Variable())
+			if err != nil {
+				return refuse(ErrorCodeInvalidValue)
+			}
+			item.UnitPrice = price
+		default:
+			return refuse(ErrorCodeInvalidValue)
+		}
+	}
+	if item.UnitPrice <= 0 {
+		return refuse(ErrorCodeInvalidValue)
+	}
+
+	item.Amount = item.UnitPrice.MultiplyQuantity(item.Quantity)
+	s.Items = append(s.Items, item)
+	s.Total += item.Amount
+	return nil
+}
+
+// discountDepartment registers a discount on the sales of a department, refused if it exceeds them.
+func (s *State) discountDepartment(cmd *command) error {
+	if !s.saleOpen() {
+		return refuse(ErrorCodeDocumentNotOpen)
+	}
+	if s.Paid > 0 {
+		return refuse(ErrorCodeInvalidSequence)
+	}
+	department, err := strconv.Atoi(cmd.variable)
+	if err != nil || department <= 0 {
+		return refuse(ErrorCodeDepartmentNotProgrammed)
+	}
+
+	item := Item{Quantity: gongoff.NewQuantity(1), Department: department}
+	for _, d := range cmd.data {
+		switch d.Separator() {
+		case gongoff.SeparatorTypeDescription, gongoff.SeparatorTypeDescriptionDoubleHeight:
+			item.Description = d.Variable()
+		case gongoff.SeparatorTypeValue:
+			value, err := parseAmount(d.Variable())
+			if err != nil || value <= 0 {
+				return refuse(ErrorCodeInvalidValue)
+			}
+			item.UnitPrice = -value
+		default:
+			return refuse(ErrorCodeInvalidValue)
+		}
+	}
+	if item.UnitPrice == 0 {
+		return refuse(ErrorCodeInvalidValue)
+	}
+
+	var sales gongoff.Amount
+	for _, sold := range s.Items {
+		if sold.Department == department {
+			sales += sold.Amount
+		}
+	}
+	if sales+item.UnitPrice < 0 {
+		return refuse(ErrorCodeNegativeTotal)
+	}
+
+	item.Amount = item.UnitPrice
+	s.Items = append(s.Items, item)
+	s.Total += item.Amount
+	return nil
+}
+
+// void removes the last item, or the last item with the description, quantity and price given with the command.
+func (s *State) void(cmd *command) error {
+	if !s.saleOpen() {
+		return refuse(ErrorCodeDocumentNotOpen)
+	}
+	if s.Paid > 0 || s.subtotalSpread {
+		return refuse(ErrorCodeInvalidSequence)
+	}
+
+	index := len(s.Items) - 1
+	if len(cmd.data) > 0 {
+		voided := Item{Quantity: gongoff.NewQuantity(1)}
+		for _, d := range cmd.data {
+			switch d.Separator() {
+			case gongoff.SeparatorTypeDescription, gongoff.SeparatorTypeDescriptionDoubleHeight:
+				voided.Description = d.Variable()
+			case gongoff.SeparatorTypeMultiply:
+				quantity, err := gongoff.ParseQuantity(d.Variable())
+				if err != nil || quantity <= 0 {
+					return refuse(ErrorCodeInvalidValue)
+				}
+				voided.Quantity = quantity
+			case gongoff.SeparatorTypeValue:
+				price, err := parseAmount(d.Variable())
+				if err != nil {
+					return refuse(ErrorCodeInvalidValue)
+				}
+				voided.UnitPrice = price
+			}
+		}
+		for index >= 0 {
+			item := s.Items[index]
+			if item.PLU == 0 && item.Description == voided.Description && item.Quantity == voided.Quantity && item.UnitPrice == voided.UnitPrice {
+				break
+			}
+			index--
+		}
+	}
+	if index < 0 {
+		return refuse(ErrorCodeInvalidSequence)
+	}
+
+	s.Total -= s.Items[index].Amount
+	s.Items = append(s.Items[:index], s.Items[index+1:]...)
+	s.lineClosed = true
+	return nil
+}
+
+// adjustLastItem applies a transaction discount or increase to the last registered sale.
+func (s *State) adjustLastItem(cmd *command) error {
+	if !s.saleOpen() {
+		return refuse(ErrorCodeDocumentNotOpen)
+	}
+	if len(s.Items) == 0 || s.Paid > 0 || s.lineClosed {
+		return refuse(ErrorCodeInvalidSequence)
+	}
+	last := &s.Items[len(s.Items)-1]
+
+	var discount gongoff.Amount
+	if len(cmd.data) != 1 {
+		return refuse(ErrorCodeInvalidValue)
+	}
+	switch cmd.terminator {
+	case gongoff.TerminatorTypeDiscountPercentTransaction, gongoff.TerminatorTypeIncreasePercentTransaction:
+		percentage, err := parsePercentage(cmd.data[0].Variable())
+		if err != nil {
+			return err
+		}
+		discount = last.Amount.Percentage(percentage)
+		if cmd.terminator == gongoff.TerminatorTypeIncreasePercentTransaction {
+			discount = -discount
+		}
+	case gongoff.TerminatorTypeIncreaseValueTransaction:
+		value, err := parseAmount(cmd.data[0].Variable())
+		if err != nil || value <= 0 {
+			return refuse(ErrorCodeInvalidValue)
+		}
+		discount = -value
+	default:
+		value, err := parseAmount(cmd.data[0].Variable())
+		if err != nil || value <= 0 {
+			return refuse(ErrorCodeInvalidValue)
+		}
+		discount = value
+	}
+	if discount > last.Amount {
+		return refuse(ErrorCodeNegativeTotal)
+	}
+	last.Amount -= discount
+	s.Total -= discount
+	return nil
+}
+
+// adjustSubtotal applies a subtotal discount or increase to the total of the open document.
+func (s *State) adjustSubtotal(cmd *command) error {
+	if !s.saleOpen() {
+		return refuse(ErrorCodeDocumentNotOpen)
+	}
+	if len(s.Items) == 0 || s.Paid > 0 {
+		return refuse(ErrorCodeInvalidSequence)
+	}
+	if len(cmd.data) != 1 {
+		return refuse(ErrorCodeInvalidValue)
+	}
+
+	var adjustment gongoff.Amount
+	switch cmd.terminator {
+	case gongoff.TerminatorTypeDiscountPercentSubtotal, gongoff.TerminatorTypeIncreasePercentSubtotal:
+		percentage, err := parsePercentage(cmd.data[0].Variable())
+		if err != nil {
+			return err
+		}
+		adjustment = s.Total.Percentage(percentage)
+	default:
+		value, err := parseAmount(cmd.data[0].Variable())
+		if err != nil || value <= 0 {
+			return refuse(ErrorCodeInvalidValue)
+		}
+		adjustment = value
+	}
+	if cmd.terminator == gongoff.TerminatorTypeDiscountPercentSubtotal || cmd.terminator == gongoff.TerminatorTypeDiscountValueSubtotal {
+		adjustment = -adjustment
+	}
+	if s.Total+adjustment < 0 {
+		return refuse(ErrorCodeNegativeTotal)
+	}
+	s.SubtotalAdjustment += adjustment
+	s.Total += adjustment
+	s.lineClosed = true
+	s.subtotalSpread = true
+	return nil
+}
+
+// cashMethods are the payment methods allowing change.
+var cashMethods = map[gongoff.TerminatorType]bool{
+	gongoff.TerminatorTypePaymentCash:  true,
+	gongoff.TerminatorTypePaymentCash2: true,
+}
+
+func (s *State) pay(cmd *command) error {
+	if !s.saleOpen() {
+		return refuse(ErrorCodeDocumentNotOpen)
+	}
+	due := s.Total - s.Paid
+	amount := due
+	for _, d := range cmd.data {
+		if d.Separator() == gongoff.SeparatorTypeValue {
+			value, err := parseAmount(d.Variable())
+			if err != nil || value <= 0 {
+				return refuse(ErrorCodeInvalidValue)
+			}
+			amount = value
+		}
+	}
+	if amount > due && !cashMethods[cmd.terminator] {
+		return refuse(ErrorCodeInvalidValue)
+	}
+
+	s.Payments = append(s.Payments, Payment{Method: cmd.terminator, Amount: amount})
+	s.Paid += amount
+	if s.Paid >= s.Total {
+		s.closeDocument()
+	}
+	return nil
+}
+
+// closeDocument closes the open document updating the daily counters.
+func (s *State) closeDocument() {
+	switch s.Document {
+	case DocumentCommercial, DocumentInvoice:
+		s.DailyTotal += s.Total
+	case DocumentReturn, DocumentCancellation:
+		s.DailyTotal -= s.Total
+	}
+	if s.Document == DocumentInvoice {
+		s.InvoiceNumber++
+	}
+	s.DocumentNumber++
+	s.resetDocument()
+}
+
+// resetDocument discards the open document.
+func (s *State) resetDocument() {
+	s.Document = DocumentNone
+	s.Items = nil
+	s.Payments = nil
+	s.Lines = nil
+	s.Total = 0
+	s.Paid = 0
+	s.SubtotalAdjustment = 0
+	s.lineClosed = false
+	s.subtotalSpread = false
+}
+
+// parseAmount parses an amount in cents as sent to the printer.
+func parseAmount(value string) (gongoff.Amount, error) {
+	cents, err := strconv.ParseInt(value, 10, 64)
+	return gongoff.Amount(cents), err
+}
+
+// parsePercentage parses a discount or increase percentage, refused if not between 0 and 100.
+func parsePercentage(value string) (float64, error) {
+	percentage, err := strconv.ParseFloat(value, 64)
+	if err != nil || percentage <= 0 || percentage > 100 {
+		return 0, refuse(ErrorCodeInvalidValue)
+	}
+	return percentage, nil
+}
+
+// description returns the first description of cmd.
+func description(cmd *command) string {
+	for _, d := range cmd.data {
+		if d.Separator() == gongoff.SeparatorTypeDescription || d.Separator() == gongoff.SeparatorTypeDescriptionDoubleHeight {
+			return d.Variable()
+		}
+	}
+	return ""
+}
+
+// status returns the fields of the response to a status request.
+func (s *State) status() string {
+	var fields []string
+	switch {
+	case s.PaperOut:
+		fields = append(fields, "paper=out")
+	case s.PaperLow:
+		fields = append(fields, "paper=low")
+	}
+	if s.CoverOpen {
+		fields = append(fields, "cover=open")
+	}
+	if s.FiscalMemoryNearlyFull {
+		fields = append(fields, "fiscalmemory=nearlyfull")
+	}
+	if s.ClosureOverdue {
+		fields = append(fields, "closure=overdue")
+	}
+	if s.Document != DocumentNone {
+		fields = append(fields, "document="+string(s.Document))
+	}
+	return strings.Join(fields, " ")
+}
+
+func (s State) String() string {
+	return fmt.Sprintf("document=%q total=%s paid=%s daily=%s documents=%d closures=%d", s.Document, s.Total, s.Paid, s.DailyTotal, s.DocumentNumber, s.ClosureNumber)
+}
@@ -0,0 +1,83 @@
+// Package printerflag parses the printer connections given on the command line of gongoff and gongoffd.
+package printerflag
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/paolo96/gongoff"
+)
+
+// DefaultNetworkPort is the port of the network printers given without port.
+const DefaultNetworkPort = 9100
+
+// Connection is the connection to a printer, either Serial or Address is set.
+type Connection struct {
+	// Serial is the serial port name or device path.
+	Serial string
+	// BaudRate of the serial port, zero uses gongoff.DefaultSerialOptions.
+	BaudRate int
+	// Address is HOST[:PORT] of a network printer.
+	Address string
+	// Replies waits for a reply after every command, see gongoff.GenericPrinter.SetReplies.
+	Replies bool
+}
+
+// Parse parses net:HOST[:PORT] or serial:PORT.
+func Parse(value string) (Connection, error) {
+	kind, address, _ := strings.Cut(value, ":")
+	switch kind {
+	case "net":
+		if address == "" {
+			return Connection{}, fmt.Errorf("missing address in %q", value)
+		}
+		_, _, err := SplitAddress(address)
+		if err != nil {
+			return Connection{}, err
+		}
+		return Connection{Address: address}, nil
+	case "serial":
+		if address == "" {
+			return Connection{}, fmt.Errorf("missing serial port in %q", value)
+		}
+		return Connection{Serial: address}, nil
+	default:
+		return Connection{}, fmt.Errorf("invalid connection %q, expected net:HOST[:PORT] or serial:PORT", value)
+	}
+}
+
+// Printer creates the printer of the connection, not yet opened, and a description for the messages.
+func (c Connection) Printer() (gongoff.Printer, string, error) {
+	if c.Serial != "" {
+		options := gongoff.DefaultSerialOptions
+		if c.BaudRate != 0 {
+			options.BaudRate = c.BaudRate
+		}
+		printer := gongoff.NewSerialPrinterWithOptions(c.Serial, options)
+		printer.SetReplies(c.Replies)
+		return printer, "serial port " + c.Serial, nil
+	}
+	host, port, err := SplitAddress(c.Address)
+	if err != nil {
+		return nil, "", err
+	}
+	printer := gongoff.NewNetworkPrinter(host, port)
+	printer.SetReplies(c.Replies)
+	return printer, net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// SplitAddress splits HOST[:PORT], using DefaultNetworkPort if the port is omitted.
+func SplitAddress(address string) (string, int, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		// No port, or an IPv6 address without brackets.
+		return strings.Trim(address, "[]"), DefaultNetworkPort, nil
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portString)
+	}
+	return host, port, nil
+}
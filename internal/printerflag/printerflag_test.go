@@ -0,0 +1,62 @@
+package printerflag
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/paolo96/gongoff"
+)
+
+func TestParse(t *testing.T) {
+
+	connection, err := Parse("net:[::1]:9101")
+	if err != nil || connection.Address != "[::1]:9101" {
+		t.Errorf("Expected [::1]:9101, got %+v %v", connection, err)
+	}
+	connection, err = Parse("serial:COM1")
+	if err != nil || connection.Serial != "COM1" {
+		t.Errorf("Expected COM1, got %+v %v", connection, err)
+	}
+	for _, value := range []string{"", "net", "usb:1", "serial:", "net:host:port", "net:host:70000"} {
+		_, err := Parse(value)
+		if err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+
+	fmt.Println("Completed testParse")
+}
+
+func TestConnectionPrinter(t *testing.T) {
+
+	printer, description, err := Connection{Address: "192.168.1.100"}.Printer()
+	if err != nil || description != "192.168.1.100:9100" {
+		t.Errorf("Expected 192.168.1.100:9100, got %s %v", description, err)
+	}
+	if _, ok := printer.(*gongoff.NetworkPrinter); !ok {
+		t.Errorf("Expected *NetworkPrinter, got %T", printer)
+	}
+	printer, description, err = Connection{Serial: "/dev/ttyUSB0", BaudRate: 19200}.Printer()
+	if err != nil || description != "serial port /dev/ttyUSB0" {
+		t.Errorf("Expected serial port /dev/ttyUSB0, got %s %v", description, err)
+	}
+	if _, ok := printer.(*gongoff.SerialPrinter); !ok {
+		t.Errorf("Expected *SerialPrinter, got %T", printer)
+	}
+
+	fmt.Println("Completed testConnectionPrinter")
+}
+
+func TestSplitAddress(t *testing.T) {
+
+	host, port, err := SplitAddress("[::1]:9101")
+	if err != nil || host != "::1" || port != 9101 {
+		t.Errorf("Expected ::1 9101, got %s %d %v", host, port, err)
+	}
+	host, port, err = SplitAddress("192.168.1.100")
+	if err != nil || host != "192.168.1.100" || port != DefaultNetworkPort {
+		t.Errorf("Expected 192.168.1.100 %d, got %s %d %v", DefaultNetworkPort, host, port, err)
+	}
+
+	fmt.Println("Completed testSplitAddress")
+}
@@ -0,0 +1,563 @@
+package gongoff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// terminatorTypes are all the terminators defined by the protocol.
+var terminatorTypes = []TerminatorType{
+	TerminatorTypeSold,
+	TerminatorTypeDiscountDepartment,
+	TerminatorTypeSoldPLU,
+	TerminatorTypeCancellation,
+	TerminatorTypeDiscountPercentTransaction,
+	TerminatorTypeDiscountPercentSubtotal,
+	TerminatorTypeDiscountValueTransaction,
+	TerminatorTypeDiscountValueSubtotal,
+	TerminatorTypeIncreasePercentTransaction,
+	TerminatorTypeIncreasePercentSubtotal,
+	TerminatorTypeIncreaseValueTransaction,
+	TerminatorTypeIncreaseValueSubtotal,
+	TerminatorTypeReturn,
+	TerminatorTypeCashIncome,
+	TerminatorTypeCashOutflow,
+	TerminatorTypePaymentWithCredit,
+	TerminatorTypeCashCreditRecovery,
+	TerminatorTypeAdvancePayment,
+	TerminatorTypeGift,
+	TerminatorTypeOneTimeCoupon,
+	TerminatorTypeDirectInvoice,
+	TerminatorTypeOpenCreditNote,
+	TerminatorTypeOpenReturnDocumentCommercial,
+	TerminatorTypeOpenCancellationDocumentCommercial,
+	TerminatorTypeOpenReturnDocumentPOS,
+	TerminatorTypeOpenCancellationDocumentPOS,
+	TerminatorTypeInvoiceCommercialDocument,
+	TerminatorTypeCancelDocumentOrInvoice,
+	TerminatorTypeSubtotal,
+	TerminatorTypePaymentCash,
+	TerminatorTypePaymentCheck,
+	TerminatorTypePaymentCards,
+	TerminatorTypePaymentCredit,
+	TerminatorTypePaymentTicket,
+	TerminatorTypePaymentCash2,
+	TerminatorTypePaymentUncollectedAssets,
+	TerminatorTypePaymentTicket2,
+	TerminatorTypePaymentTicket3,
+	TerminatorTypePaymentTicket4,
+	TerminatorTypePaymentUncollectedServices,
+	TerminatorTypePaymentUncollectedInvoice,
+	TerminatorTypePaymentUncollectedSSN,
+	TerminatorTypePaymentDiscountGeneric,
+	TerminatorTypePaymentOneTimeCoupon,
+	TerminatorTypeAdditionalDescription,
+	TerminatorTypeLotteryCode,
+	TerminatorTypeInvoiceCustomerDetails,
+	TerminatorTypePrintCustomerIdentifier,
+	TerminatorTypePrintCourtesyMessage,
+	TerminatorTypePrintTrailerAfterLogo,
+	TerminatorTypePrintBarcodeEAN13,
+	TerminatorTypePrintBarcodeEAN8,
+	TerminatorTypePrintBarcodeCODE39,
+	TerminatorTypePrintNotCalculated,
+	TerminatorTypeOpenCashRegister,
+	TerminatorTypeClear,
+	TerminatorTypeSelectOperator,
+	TerminatorTypeLockKeyboard,
+	TerminatorTypeUnlockKeyboard,
+	TerminatorTypeOpenManagementDocument,
+	TerminatorTypePrintTextLine,
+	TerminatorTypeCloseManagementDocument,
+	TerminatorTypeViewDescriptionOnDisplayFirstLine,
+	TerminatorTypeViewDescriptionOnDisplaySecondLine,
+	TerminatorTypeFinancialReportNoZeroing,
+	TerminatorTypeDepartmentReportNoZeroing,
+	TerminatorTypePLUReportNoZeroing,
+	TerminatorTypeOperatorsReportNoZeroing,
+	TerminatorTypeFinancialReportZeroing,
+	TerminatorTypeDepartmentReportZeroing,
+	TerminatorTypePLUReportZeroing,
+	TerminatorTypeOperatorsReportZeroing,
+	TerminatorTypeFinancialReportAndFiscalClosureZeroing,
+	TerminatorTypeResetInvoiceNumber,
+	TerminatorTypePrintFiscalMemoryAll,
+	TerminatorTypePrintFiscalMemoryByDate,
+	TerminatorTypePrintFiscalMemoryByClosureNumber,
+	TerminatorTypePrintDetailsMemoryAll,
+	TerminatorTypePrintDetailsMemoryByDate,
+	TerminatorTypePrintDetailsMemoryByClosureNumber,
+	TerminatorTypeSetDateTime,
+	TerminatorTypeDisableXonXoff,
+	TerminatorTypeDisableXonXoff2,
+	terminatorTypeStatusRequest,
+}
+
+var knownTerminatorTypes = map[TerminatorType]bool{}
+
+func init() {
+	for _, terminatorType := range terminatorTypes {
+		knownTerminatorTypes[terminatorType] = true
+	}
+}
+
+// ParseCommands parses a string made of consecutive commands, as sent to the printer.
+// Ex. "\"BREAD\"2*750H3R1T" -> [CommandProduct, CommandPayment]
+func ParseCommands(s string) ([]Command, error) {
+	var commands []Command
+	for {
+		s = trimCommandSpace(s)
+		if s == "" {
+			return commands, nil
+		}
+		command, rest, err := ParseNext(s)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+		s = rest
+	}
+}
+
+// ParseCommand parses a string containing exactly one command.
+// Ex. "\"BREAD\"2*750H3R" -> CommandProduct("BREAD", 750, 2, 3)
+// The matching typed command is returned when possible, CommandGeneric otherwise.
+func ParseCommand(s string) (Command, error) {
+	command, rest, err := ParseNext(trimCommandSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	if trimCommandSpace(rest) != "" {
+		return nil, fmt.Errorf("%w: unexpected %q after command", ErrInvalidCommand, rest)
+	}
+	return command, nil
+}
+
+// ParseNext parses the first command of s and returns the text following it.
+// ErrIncompleteCommand is returned if s ends before the terminator of the command.
+// A trailing "@" is parsed as TerminatorTypeAdditionalDescription, stream readers should wait for more data
+// before parsing it since it could be the beginning of a terminator like "@40F".
+func ParseNext(s string) (Command, string, error) {
+	data, terminator, rest, err := tokenizeCommand(s)
+	if err != nil {
+		return nil, s, err
+	}
+	return typedCommand(data, terminator), rest, nil
+}
+
+func trimCommandSpace(s string) string {
+	return strings.TrimLeft(s, " \r\n")
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// tokenizeCommand splits the first command of s in its data pieces and terminator.
+func tokenizeCommand(s string) ([]Data, Terminator, string, error) {
+	var data []Data
+	i := 0
+	for i < len(s) {
+		switch {
+		case s[i] == '"' || strings.HasPrefix(s[i:], string(SeparatorTypeDescriptionDoubleHeight)):
+			separator := SeparatorTypeDescription
+			start := i + 1
+			if s[i] == '~' {
+				separator = SeparatorTypeDescriptionDoubleHeight
+				start = i + 2
+			}
+			end := strings.IndexByte(s[start:], '"')
+			if end < 0 {
+				return nil, Terminator{}, s, ErrIncompleteCommand
+			}
+			data = append(data, Data{variable: s[start : start+end], separator: separator})
+			i = start + end + 1
+		case isDigit(s[i]):
+			start := i
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			if i == len(s) {
+				return nil, Terminator{}, s, ErrIncompleteCommand
+			}
+			digits := s[start:i]
+			switch s[i] {
+			case 'H':
+				data = append(data, Data{variable: digits, separator: SeparatorTypeValue})
+				i++
+			case '*':
+				data = append(data, Data{variable: digits, separator: SeparatorTypeMultiply})
+				i++
+			case '.':
+				fraction := i + 1
+				end := fraction
+				for end < len(s) && isDigit(s[end]) {
+					end++
+				}
+				if end == len(s) {
+					return nil, Terminator{}, s, ErrIncompleteCommand
+				}
+				if s[end] == '*' {
+					data = append(data, Data{variable: s[start:end], separator: SeparatorTypeMultiply})
+					i = end + 1
+					break
+				}
+				// Decimal values have two fraction digits, the following digits belong to the terminator.
+				if end-fraction < 2 {
+					return nil, Terminator{}, s, fmt.Errorf("%w: decimal value must have two fraction digits", ErrInvalidCommand)
+				}
+				data = append(data, Data{variable: s[start : fraction+2], separator: SeparatorTypeDecimal})
+				i = fraction + 2
+			default:
+				full := TerminatorType(s[start : i+1])
+				if knownTerminatorTypes[full] || s[i] == 'T' {
+					return data, Terminator{terminatorType: full}, s[i+1:], nil
+				}
+				terminatorType := TerminatorType(s[i : i+1])
+				if !knownTerminatorTypes[terminatorType] {
+					return nil, Terminator{}, s, fmt.Errorf("%w: unknown terminator %q", ErrInvalidCommand, full)
+				}
+				return data, Terminator{variable: &digits, terminatorType: terminatorType}, s[i+1:], nil
+			}
+		case s[i] == '@':
+			end := i + 1
+			for end < len(s) && isDigit(s[end]) {
+				end++
+			}
+			if end > i+1 && end < len(s) && knownTerminatorTypes[TerminatorType(s[i:end+1])] {
+				return data, Terminator{terminatorType: TerminatorType(s[i : end+1])}, s[end+1:], nil
+			}
+			if end > i+1 && end == len(s) {
+				return nil, Terminator{}, s, ErrIncompleteCommand
+			}
+			return data, Terminator{terminatorType: TerminatorTypeAdditionalDescription}, s[i+1:], nil
+		default:
+			terminatorType := TerminatorType(s[i : i+1])
+			if !knownTerminatorTypes[terminatorType] {
+				return nil, Terminator{}, s, fmt.Errorf("%w: unexpected %q", ErrInvalidCommand, s[i:i+1])
+			}
+			return data, Terminator{terminatorType: terminatorType}, s[i+1:], nil
+		}
+	}
+	return nil, Terminator{}, s, ErrIncompleteCommand
+}
+
+// typedCommand returns the typed command matching data and terminator, a CommandGeneric if there is none.
+func typedCommand(data []Data, terminator Terminator) Command {
+	command := matchCommand(data, terminator)
+	if command == nil {
+		return NewCommandGeneric(data, terminator)
+	}
+	// The typed command must encode exactly like the parsed one.
+	expected, err := NewCommandGeneric(data, terminator).get()
+	if err != nil {
+		return NewCommandGeneric(data, terminator)
+	}
+	got, err := command.Encode()
+	if err != nil || string(got) != expected {
+		return NewCommandGeneric(data, terminator)
+	}
+	return command
+}
+
+// dataPieces indexes the variables of data by separator, false if a separator appears more than once.
+func dataPieces(data []Data) (map[SeparatorType]string, bool) {
+	pieces := map[SeparatorType]string{}
+	for _, d := range data {
+		if _, ok := pieces[d.separator]; ok {
+			return nil, false
+		}
+		pieces[d.separator] = d.variable
+	}
+	return pieces, true
+}
+
+// onlyPieces reports whether pieces contains only the given separators.
+func onlyPieces(pieces map[SeparatorType]string, separators ...SeparatorType) bool {
+	allowed := 0
+	for _, separator := range separators {
+		if _, ok := pieces[separator]; ok {
+			allowed++
+		}
+	}
+	return allowed == len(pieces)
+}
+
+// matchCommand builds the typed command for data and terminator, nil if no typed command matches.
+func matchCommand(data []Data, terminator Terminator) Command {
+	pieces, ok := dataPieces(data)
+	if !ok {
+		return nil
+	}
+	description, hasDescription := pieces[SeparatorTypeDescription]
+	terminatorType := terminator.terminatorType
+
+	switch terminatorType {
+	case TerminatorTypeSold:
+		if !onlyPieces(pieces, SeparatorTypeDescription, SeparatorTypeMultiply, SeparatorTypeValue) || terminator.variable == nil {
+			return nil
+		}
+		var product *string
+		var quantity *Quantity
+		if hasDescription {
+			product = &description
+		}
+		if value, ok := pieces[SeparatorTypeMultiply]; ok {
+			q, err := decodeQuantity(value)
+			if err != nil {
+				return nil
+			}
+			quantity = &q
+		}
+		var unitPrice Amount
+		if value, ok := pieces[SeparatorTypeValue]; ok {
+			p, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			unitPrice = Amount(p)
+		}
+		department, err := strconv.Atoi(*terminator.variable)
+		if err != nil {
+			return nil
+		}
+		return newCommandProduct(unitPrice, product, quantity, &department)
+	case TerminatorTypeCancellation:
+		if !onlyPieces(pieces, SeparatorTypeDescription, SeparatorTypeMultiply, SeparatorTypeValue) || terminator.variable != nil {
+			return nil
+		}
+		if len(pieces) == 0 {
+			return NewCommandVoidItem(nil)
+		}
+		var product *string
+		var quantity *Quantity
+		if hasDescription {
+			product = &description
+		}
+		if value, ok := pieces[SeparatorTypeMultiply]; ok {
+			q, err := decodeQuantity(value)
+			if err != nil {
+				return nil
+			}
+			quantity = &q
+		}
+		var unitPrice Amount
+		if value, ok := pieces[SeparatorTypeValue]; ok {
+			p, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			unitPrice = Amount(p)
+		}
+		return NewCommandVoidItem(newCommandProduct(unitPrice, product, quantity, nil))
+	case TerminatorTypeSoldPLU:
+		if !onlyPieces(pieces, SeparatorTypeMultiply, SeparatorTypeValue) || terminator.variable == nil {
+			return nil
+		}
+		var quantity *Quantity
+		if value, ok := pieces[SeparatorTypeMultiply]; ok {
+			q, err := decodeQuantity(value)
+			if err != nil {
+				return nil
+			}
+			quantity = &q
+		}
+		var unitPrice *Amount
+		if value, ok := pieces[SeparatorTypeValue]; ok {
+			p, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			price := Amount(p)
+			unitPrice = &price
+		}
+		plu, err := strconv.Atoi(*terminator.variable)
+		if err != nil {
+			return nil
+		}
+		command, err := newCommandProductPLU(plu, quantity, unitPrice)
+		if err != nil {
+			return nil
+		}
+		return command
+	case TerminatorTypeDiscountDepartment:
+		value, ok := pieces[SeparatorTypeValue]
+		if !ok || !onlyPieces(pieces, SeparatorTypeDescription, SeparatorTypeValue) || terminator.variable == nil {
+			return nil
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		var discountDescription *string
+		if hasDescription {
+			discountDescription = &description
+		}
+		department, err := strconv.Atoi(*terminator.variable)
+		if err != nil {
+			return nil
+		}
+		command, err := NewCommandDiscountDepartment(Amount(amount), discountDescription, department)
+		if err != nil {
+			return nil
+		}
+		return command
+	case TerminatorTypeDiscountValueTransaction, TerminatorTypeDiscountValueSubtotal,
+		TerminatorTypeIncreaseValueTransaction, TerminatorTypeIncreaseValueSubtotal:
+		value, ok := pieces[SeparatorTypeValue]
+		if !ok || len(pieces) != 1 || terminator.variable != nil {
+			return nil
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		var command Command
+		switch terminatorType {
+		case TerminatorTypeDiscountValueTransaction:
+			command, err = NewCommandDiscountAmount(Amount(amount))
+		case TerminatorTypeDiscountValueSubtotal:
+			command, err = NewCommandDiscountAmountSubtotal(Amount(amount))
+		case TerminatorTypeIncreaseValueTransaction:
+			command, err = NewCommandIncreaseAmount(Amount(amount))
+		default:
+			command, err = NewCommandIncreaseAmountSubtotal(Amount(amount))
+		}
+		if err != nil {
+			return nil
+		}
+		return command
+	case TerminatorTypeSetDateTime:
+		if len(pieces) != 0 || terminator.variable == nil {
+			return nil
+		}
+		dateTime, err := time.ParseInLocation(dateTimeLayout, *terminator.variable, time.Local)
+		if err != nil {
+			return nil
+		}
+		return NewCommandSetDateTime(dateTime)
+	case TerminatorTypeDiscountPercentTransaction, TerminatorTypeDiscountPercentSubtotal,
+		TerminatorTypeIncreasePercentTransaction, TerminatorTypeIncreasePercentSubtotal:
+		value, ok := pieces[SeparatorTypeDecimal]
+		if !ok || len(pieces) != 1 || terminator.variable != nil {
+			return nil
+		}
+		percentage, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil
+		}
+		var command Command
+		switch terminatorType {
+		case TerminatorTypeDiscountPercentTransaction:
+			command, err = NewCommandDiscountPercentage(percentage)
+		case TerminatorTypeDiscountPercentSubtotal:
+			command, err = NewCommandDiscountPercentageSubtotal(percentage)
+		case TerminatorTypeIncreasePercentTransaction:
+			command, err = NewCommandIncreasePercentage(percentage)
+		default:
+			command, err = NewCommandIncreasePercentageSubtotal(percentage)
+		}
+		if err != nil {
+			return nil
+		}
+		return command
+	}
+
+	if terminator.variable != nil {
+		return nil
+	}
+	if len(pieces) == 0 {
+		switch terminatorType {
+		case TerminatorTypeFinancialReportNoZeroing:
+			return NewCommandFinancialReport(false)
+		case TerminatorTypeFinancialReportAndFiscalClosureZeroing:
+			return NewCommandFinancialReport(true)
+		case TerminatorTypeOpenCashRegister:
+			return NewCommandOpenCashDrawer()
+		case TerminatorTypeCancelDocumentOrInvoice:
+			return NewCommandCancelDocument()
+		case terminatorTypeStatusRequest:
+			return NewCommandStatusRequest()
+		case TerminatorTypeSubtotal:
+			return NewCommandSubtotal()
+		}
+	}
+	if strings.HasSuffix(string(terminatorType), "T") {
+		if !onlyPieces(pieces, SeparatorTypeValue, SeparatorTypeDescription) {
+			return nil
+		}
+		var amount *Amount
+		if value, ok := pieces[SeparatorTypeValue]; ok {
+			a, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			paid := Amount(a)
+			amount = &paid
+		}
+		var paymentDescription *string
+		if hasDescription {
+			paymentDescription = &description
+		}
+		command, err := NewCommandPayment(terminatorType, amount, paymentDescription)
+		if err != nil {
+			return nil
+		}
+		return command
+	}
+
+	if !hasDescription || len(pieces) != 1 {
+		return nil
+	}
+	switch terminatorType {
+	case TerminatorTypePrintCourtesyMessage:
+		return NewCommandTrailer(description)
+	case TerminatorTypePrintCustomerIdentifier:
+		command, err := NewCommandCustomerIdentifier(description)
+		if err != nil {
+			return nil
+		}
+		return command
+	case TerminatorTypePrintBarcodeEAN13, TerminatorTypePrintBarcodeEAN8:
+		command, err := NewCommandBarcode(description)
+		if err != nil {
+			return nil
+		}
+		return command
+	case TerminatorTypeOpenReturnDocumentCommercial:
+		return NewCommandOpenDocumentCommercialReturn(DocumentId(description))
+	case TerminatorTypeOpenCancellationDocumentCommercial:
+		return NewCommandOpenDocumentCommercialCancellation(DocumentId(description))
+	case TerminatorTypeOpenReturnDocumentPOS, TerminatorTypeOpenCancellationDocumentPOS:
+		date, err := time.Parse("01-02-06", strings.TrimSuffix(description, "/POS"))
+		if err != nil {
+			return nil
+		}
+		if terminatorType == TerminatorTypeOpenReturnDocumentPOS {
+			return NewCommandOpenDocumentPOSReturn(date)
+		}
+		return NewCommandOpenDocumentPOSCancellation(date)
+	case TerminatorTypeDirectInvoice, TerminatorTypeInvoiceCommercialDocument:
+		number, err := strconv.Atoi(description)
+		if err != nil {
+			return nil
+		}
+		invoiceNumber := &number
+		if number == 0 {
+			invoiceNumber = nil
+		}
+		if terminatorType == TerminatorTypeDirectInvoice {
+			return NewCommandOpenInvoice(invoiceNumber)
+		}
+		return NewCommandOpenInvoiceCommercialDocument(invoiceNumber)
+	case TerminatorTypeInvoiceCustomerDetails:
+		return NewCommandInvoiceDetails(description)
+	case TerminatorTypeViewDescriptionOnDisplayFirstLine:
+		return NewCommandDisplayMessage(description, 1)
+	case TerminatorTypeViewDescriptionOnDisplaySecondLine:
+		return NewCommandDisplayMessage(description, 2)
+	}
+	return nil
+}
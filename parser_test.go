@@ -0,0 +1,152 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCommandRoundTrip(t *testing.T) {
+
+	product := "BREAD"
+	quantity := 2
+	department := 3
+	amount := Amount(100)
+	description := "test"
+	invoiceNumber := 123
+	payment, _ := NewCommandPayment(TerminatorTypePaymentCards, &amount, &description)
+	customerIdentifier, _ := NewCommandCustomerIdentifier("RSSMRA00A01F205F")
+	barcode, _ := NewCommandBarcode("1234567890123")
+	testDate := time.Date(2018, time.January, 2, 0, 0, 0, 0, time.UTC)
+	plu, _ := NewCommandProductPLU(12, &quantity, &amount)
+	pluProgrammed, _ := NewCommandProductPLU(12, nil, nil)
+	discountDepartment, _ := NewCommandDiscountDepartment(100, &description, 2)
+	weighed, _ := NewCommandProductQuantity(1890, &product, 750, &department)
+	pluWeighed, _ := NewCommandProductPLUQuantity(12, 1250, nil)
+
+	commands := []Command{
+		NewCommandProduct(750, &product, &quantity, &department),
+		NewCommandProduct(750, nil, nil, nil),
+		weighed,
+		NewCommandVoidItem(nil),
+		NewCommandVoidItem(NewCommandProduct(750, &product, &quantity, nil)),
+		NewCommandVoidItem(weighed),
+		plu,
+		pluWeighed,
+		pluProgrammed,
+		discountDepartment,
+		payment,
+		mustCommand(NewCommandDiscountAmount(1126)),
+		mustCommand(NewCommandDiscountPercentage(50.12)),
+		mustCommand(NewCommandIncreasePercentage(5)),
+		NewCommandSubtotal(),
+		mustCommand(NewCommandDiscountPercentageSubtotal(12.5)),
+		mustCommand(NewCommandDiscountAmountSubtotal(300)),
+		mustCommand(NewCommandIncreasePercentageSubtotal(2)),
+		mustCommand(NewCommandIncreaseAmountSubtotal(150)),
+		NewCommandTrailer("Hello World!"),
+		customerIdentifier,
+		barcode,
+		NewCommandOpenDocumentCommercialReturn(*NewDocumentId(12, 23, testDate, nil)),
+		NewCommandOpenDocumentCommercialCancellation(*NewDocumentId(12, 23, testDate, nil)),
+		NewCommandOpenDocumentPOSReturn(testDate),
+		NewCommandOpenDocumentPOSCancellation(testDate),
+		NewCommandOpenInvoice(&invoiceNumber),
+		NewCommandOpenInvoiceCommercialDocument(nil),
+		NewCommandInvoiceDetails("Mario Rossi"),
+		NewCommandDisplayMessage("Mario Rossi", 2),
+		NewCommandCancelDocument(),
+		NewCommandStatusRequest(),
+	}
+
+	var stream string
+	for _, command := range commands {
+		encoded, err := command.Encode()
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		expected := string(encoded)
+		stream += expected
+
+		parsed, err := ParseCommand(expected)
+		if err != nil {
+			t.Errorf("Expected error = nil parsing %s, got %s", expected, err)
+			continue
+		}
+		if reflect.TypeOf(parsed) != reflect.TypeOf(command) {
+			t.Errorf("Expected %T parsing %s, got %T", command, expected, parsed)
+		}
+		got, err := parsed.Encode()
+		if err != nil || string(got) != expected {
+			t.Errorf("Expected %s, got %s (%v)", expected, got, err)
+		}
+	}
+
+	parsed, err := ParseCommands(stream)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if len(parsed) != len(commands) {
+		t.Errorf("Expected %d commands, got %d", len(commands), len(parsed))
+	}
+
+	fmt.Println("Completed testParseCommandRoundTrip")
+}
+
+func TestParseCommandGeneric(t *testing.T) {
+
+	for _, raw := range []string{"j", "J", "k", "=", "8F", "1492E", "\"row\"@", "\"12345\"@37F", "0101181200D", "\"BREAD\"12P"} {
+		command, err := ParseCommand(raw)
+		if err != nil {
+			t.Errorf("Expected error = nil parsing %s, got %s", raw, err)
+			continue
+		}
+		got, err := command.Encode()
+		if err != nil || string(got) != raw {
+			t.Errorf("Expected %s, got %s (%v)", raw, got, err)
+		}
+	}
+
+	command, err := ParseCommand("\"BREAD\"12P")
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	generic, ok := command.(*CommandGeneric)
+	if !ok {
+		t.Fatalf("Expected *CommandGeneric, got %T", command)
+	}
+	terminator := generic.Terminator()
+	if terminator.Type() != TerminatorTypeSoldPLU || terminator.Variable() == nil || *terminator.Variable() != "12" {
+		t.Errorf("Expected PLU 12, got %+v", terminator)
+	}
+
+	fmt.Println("Completed testParseCommandGeneric")
+}
+
+func TestParseCommandErrors(t *testing.T) {
+
+	for _, raw := range []string{"\"BREAD", "750H", "10.00", "\"row\"@4"} {
+		_, err := ParseCommand(raw)
+		if !errors.Is(err, ErrIncompleteCommand) {
+			t.Errorf("Expected ErrIncompleteCommand parsing %s, got %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"12X", "!", "1R1T"} {
+		_, err := ParseCommand(raw)
+		if !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("Expected ErrInvalidCommand parsing %s, got %v", raw, err)
+		}
+	}
+
+	command, rest, err := ParseNext("1R1T")
+	if err != nil || rest != "1T" {
+		t.Errorf("Expected rest 1T, got %s (%v)", rest, err)
+	}
+	if _, ok := command.(*CommandProduct); !ok {
+		t.Errorf("Expected *CommandProduct, got %T", command)
+	}
+
+	fmt.Println("Completed testParseCommandErrors")
+}
@@ -0,0 +1,457 @@
+package gongoff
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// PoolPolicy chooses the printer of a PrinterPool receiving a document.
+type PoolPolicy int
+
+const (
+	// PoolRoundRobin uses the printers in turn.
+	PoolRoundRobin PoolPolicy = iota
+	// PoolLeastBusy uses the printer with the fewest calls in progress, the first one in case of tie.
+	PoolLeastBusy
+	// PoolStickyPerTill always uses the same printer for a till, see PrinterPool.Till.
+	// New tills are assigned in turn and moved to another printer only when theirs is unhealthy.
+	PoolStickyPerTill
+	// PoolFailover uses the first healthy printer, the others are backups.
+	PoolFailover
+)
+
+// PoolOptions configures a PrinterPool.
+type PoolOptions struct {
+	Policy PoolPolicy
+	// RetryUnhealthy is how long an unhealthy printer is skipped before being tried again, unless Check finds it healthy.
+	// Zero uses 30 seconds.
+	RetryUnhealthy time.Duration
+}
+
+const defaultRetryUnhealthy = 30 * time.Second
+
+// DeviceHealth is the health of a printer of a PrinterPool.
+type DeviceHealth struct {
+	Printer Printer
+	// Healthy is false after a connection error, or when the status reports the paper out or the cover open.
+	// A refused command does not make the printer unhealthy, the printer answered.
+	Healthy bool
+	// Busy is the number of calls in progress.
+	Busy int
+	// Status is the last status answered by the printer, nil if it was never checked or does not answer status requests.
+	Status    *PrinterStatus
+	LastError error
+	// LastCheck is the time of the last call or Check.
+	LastCheck time.Time
+}
+
+type poolMember struct {
+	printer Printer
+	// opening prevents concurrent calls from opening the printer twice.
+	opening sync.Mutex
+	// health is guarded by PrinterPool.mu.
+	health DeviceHealth
+}
+
+// open opens the printer if it is closed.
+func (m *poolMember) open(ctx context.Context) error {
+	m.opening.Lock()
+	defer m.opening.Unlock()
+	if m.printer.IsOpen() {
+		return nil
+	}
+	return OpenContext(ctx, m.printer)
+}
+
+// PrinterPool is a Printer routing every call to one of many printers, for stores with several fiscal devices.
+//
+// When the chosen printer fails in a way proving that nothing was printed, because it cannot be opened or reached
+// or it refuses the first command, the call is retried on the next printer. Any other error is
+// returned, ex. a connection lost after writing the first command, so a fiscal document is never printed on two devices.
+// PrinterPool is safe for concurrent use.
+type PrinterPool struct {
+	options PoolOptions
+
+	mu      sync.Mutex
+	members []*poolMember
+	next    int
+	tills   map[string]*poolMember
+}
+
+var ErrNoPrinterAvailable = errors.New("no printer available in the pool")
+
+// NewPrinterPool creates a pool of printers, they are opened by Open.
+func NewPrinterPool(printers []Printer, options PoolOptions) *PrinterPool {
+	if options.RetryUnhealthy <= 0 {
+		options.RetryUnhealthy = defaultRetryUnhealthy
+	}
+	pool := &PrinterPool{options: options, tills: map[string]*poolMember{}}
+	for _, printer := range printers {
+		pool.members = append(pool.members, &poolMember{
+			printer: printer,
+			health:  DeviceHealth{Printer: printer, Healthy: true},
+		})
+	}
+	return pool
+}
+
+func (p *PrinterPool) Open() error {
+	return p.OpenContext(context.Background())
+}
+
+// OpenContext opens every printer not already open, it fails only if none of them can be opened.
+// The printers failing to open are marked unhealthy and opened again when used.
+func (p *PrinterPool) OpenContext(ctx context.Context) error {
+	var firstErr error
+	opened := 0
+	for _, m := range p.members {
+		err := m.open(ctx)
+		p.record(m, err, nil)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		opened++
+	}
+	if opened == 0 {
+		if firstErr == nil {
+			return ErrNoPrinterAvailable
+		}
+		return firstErr
+	}
+	return nil
+}
+
+// IsOpen reports whether at least one printer is open.
+func (p *PrinterPool) IsOpen() bool {
+	for _, m := range p.members {
+		if m.printer.IsOpen() {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes every printer, returning the first error.
+func (p *PrinterPool) Close() error {
+	var firstErr error
+	for _, m := range p.members {
+		if !m.printer.IsOpen() {
+			continue
+		}
+		err := m.printer.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *PrinterPool) PrintDocument(doc Document) error {
+	return p.print(context.Background(), "", doc.Commands())
+}
+
+func (p *PrinterPool) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return p.print(ctx, "", doc.Commands())
+}
+
+func (p *PrinterPool) PrintCommands(commands []Command) error {
+	return p.print(context.Background(), "", commands)
+}
+
+func (p *PrinterPool) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	return p.print(ctx, "", commands)
+}
+
+func (p *PrinterPool) Status() (*PrinterStatus, error) {
+	return p.status(context.Background(), "")
+}
+
+// StatusContext returns the status of the printer chosen by the policy, like for a document.
+func (p *PrinterPool) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	return p.status(ctx, "")
+}
+
+// Session calls fn with exclusive access to one printer of the pool, there is no failover during a session.
+func (p *PrinterPool) Session(fn func(Session) error) error {
+	return p.session("", fn)
+}
+
+// Till returns a Printer routing the calls of a till through the pool.
+// With PoolStickyPerTill the documents of the till are always printed on the same device while it is healthy.
+// Open acts on the whole pool, Close does nothing: the pool is shared by the tills and closed by its owner.
+func (p *PrinterPool) Till(id string) Printer {
+	return &poolTill{pool: p, id: id}
+}
+
+// Health returns the health of every printer, in the order given to NewPrinterPool.
+// Printers without paper or with the cover open are unhealthy, with ErrPaperOut or ErrCoverOpen as LastError.
+func (p *PrinterPool) Health() []DeviceHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	health := make([]DeviceHealth, len(p.members))
+	for i, m := range p.members {
+		health[i] = m.health
+	}
+	return health
+}
+
+// Check queries the status of every printer, opening the closed ones, and updates their health.
+// Printers without replies or not supporting status requests are checked by opening them.
+func (p *PrinterPool) Check(ctx context.Context) []DeviceHealth {
+	for _, m := range p.members {
+		err := m.open(ctx)
+		var status *PrinterStatus
+		if err == nil {
+			status, err = StatusContext(ctx, m.printer)
+			var printerErr *PrinterError
+			if errors.As(err, &printerErr) || errors.Is(err, ErrRepliesDisabled) || errors.Is(err, ErrStatusUnsupported) {
+				// The printer is open, it cannot tell its status.
+				err = nil
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		p.record(m, err, status)
+	}
+	return p.Health()
+}
+
+// print sends the commands to a printer chosen for the till, failing over while nothing was printed, see unprinted.
+func (p *PrinterPool) print(ctx context.Context, till string, commands []Command) error {
+	tried := map[*poolMember]bool{}
+	var lastErr error
+	for {
+		m := p.acquire(till, tried)
+		if m == nil {
+			if lastErr == nil {
+				return ErrNoPrinterAvailable
+			}
+			return lastErr
+		}
+		tried[m] = true
+
+		accepted := 0
+		err := m.open(ctx)
+		opened := err == nil
+		if opened {
+			err = WithSession(m.printer, func(session Session) error {
+				for _, command := range commands {
+					err := session.PrintCommandsContext(ctx, []Command{command})
+					if err != nil {
+						return err
+					}
+					accepted++
+				}
+				return nil
+			})
+		}
+		p.release(m, err)
+		if err == nil || ctx.Err() != nil || !failover(err) {
+			return err
+		}
+		if opened && (accepted > 0 || !unprinted(err)) {
+			return err
+		}
+		lastErr = err
+	}
+}
+
+func (p *PrinterPool) status(ctx context.Context, till string) (*PrinterStatus, error) {
+	m := p.acquire(till, nil)
+	if m == nil {
+		return nil, ErrNoPrinterAvailable
+	}
+	err := m.open(ctx)
+	var status *PrinterStatus
+	if err == nil {
+		status, err = StatusContext(ctx, m.printer)
+	}
+	p.mu.Lock()
+	m.health.Busy--
+	p.mu.Unlock()
+	p.record(m, err, status)
+	return status, err
+}
+
+func (p *PrinterPool) session(till string, fn func(Session) error) error {
+	m := p.acquire(till, nil)
+	if m == nil {
+		return ErrNoPrinterAvailable
+	}
+	err := m.open(context.Background())
+	if err == nil {
+		err = WithSession(m.printer, fn)
+	}
+	p.release(m, err)
+	return err
+}
+
+// failover reports whether another printer should be tried after err, unless the context of the call ended.
+func failover(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// deviceFault reports whether err means that the printer cannot be used, ex. it is not reachable.
+// The cause of a refused command is unknown, ex. an invalid value or the paper end, and the printer answered:
+// refusals are not device faults, the status checked by Check reports the paper and the cover.
+// A status requested without replies is not a fault either.
+func deviceFault(err error) bool {
+	var printerErr *PrinterError
+	return err != nil && !errors.As(err, &printerErr) && !errors.Is(err, ErrRepliesDisabled) && !errors.Is(err, ErrStatusUnsupported) && failover(err)
+}
+
+// unprinted reports whether err proves that the command was not printed: refused by the printer,
+// or not written because the printer is closed or cannot be reached.
+// Errors while writing or waiting for the reply may come after the printer received the command.
+// ErrConnectionLost and ErrConnectionBroken are returned before writing, when the connection was lost and not replaced
+// or left unusable by an earlier call: a printer going down after Open fails over like a printer not opened.
+func unprinted(err error) bool {
+	var printerErr *PrinterError
+	var opErr *net.OpError
+	return errors.As(err, &printerErr) || errors.Is(err, ErrPrinterNotOpen) || errors.Is(err, ErrConnectionLost) ||
+		errors.Is(err, ErrConnectionBroken) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// acquire chooses a printer not in excluded according to the policy and marks it busy, nil if none is left.
+// Healthy printers are preferred, unhealthy ones are tried when no healthy printer is left.
+func (p *PrinterPool) acquire(till string, excluded map[*poolMember]bool) *poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy, unhealthy []*poolMember
+	for _, m := range p.members {
+		switch {
+		case excluded[m]:
+		case p.usable(m):
+			healthy = append(healthy, m)
+		default:
+			unhealthy = append(unhealthy, m)
+		}
+	}
+	candidates := healthy
+	if len(candidates) == 0 {
+		candidates = unhealthy
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var chosen *poolMember
+	switch p.options.Policy {
+	case PoolLeastBusy:
+		for _, m := range candidates {
+			if chosen == nil || m.health.Busy < chosen.health.Busy {
+				chosen = m
+			}
+		}
+	case PoolStickyPerTill:
+		if m, ok := p.tills[till]; ok && contains(candidates, m) {
+			chosen = m
+		} else {
+			chosen = p.roundRobin(candidates)
+			p.tills[till] = chosen
+		}
+	case PoolFailover:
+		chosen = candidates[0]
+	default:
+		chosen = p.roundRobin(candidates)
+	}
+	chosen.health.Busy++
+	return chosen
+}
+
+// usable reports whether m is healthy or was unhealthy long enough to be tried again.
+func (p *PrinterPool) usable(m *poolMember) bool {
+	return m.health.Healthy || time.Since(m.health.LastCheck) > p.options.RetryUnhealthy
+}
+
+// roundRobin returns the first candidate following the last printer used.
+func (p *PrinterPool) roundRobin(candidates []*poolMember) *poolMember {
+	for i := 0; i < len(p.members); i++ {
+		m := p.members[(p.next+i)%len(p.members)]
+		if contains(candidates, m) {
+			p.next = (p.next + i + 1) % len(p.members)
+			return m
+		}
+	}
+	return candidates[0]
+}
+
+func contains(members []*poolMember, m *poolMember) bool {
+	for _, member := range members {
+		if member == m {
+			return true
+		}
+	}
+	return false
+}
+
+// release marks the call on m as finished and records its outcome.
+func (p *PrinterPool) release(m *poolMember, err error) {
+	p.mu.Lock()
+	m.health.Busy--
+	p.mu.Unlock()
+	p.record(m, err, nil)
+}
+
+// record updates the health of m after a call, status is kept from the last check if nil.
+// Refused commands do not make the printer unhealthy, see deviceFault.
+func (p *PrinterPool) record(m *poolMember, err error, status *PrinterStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m.health.LastCheck = time.Now()
+	if status != nil {
+		m.health.Status = status
+		if err == nil && !status.CanPrint() {
+			err = status.ReadyForSale()
+		}
+	}
+	m.health.Healthy = !deviceFault(err)
+	m.health.LastError = err
+}
+
+// poolTill is the Printer returned by PrinterPool.Till.
+type poolTill struct {
+	pool *PrinterPool
+	id   string
+}
+
+func (t *poolTill) Open() error                           { return t.pool.Open() }
+func (t *poolTill) OpenContext(ctx context.Context) error { return t.pool.OpenContext(ctx) }
+func (t *poolTill) IsOpen() bool                          { return t.pool.IsOpen() }
+func (t *poolTill) Close() error                          { return nil }
+
+func (t *poolTill) PrintDocument(doc Document) error {
+	return t.pool.print(context.Background(), t.id, doc.Commands())
+}
+
+func (t *poolTill) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return t.pool.print(ctx, t.id, doc.Commands())
+}
+
+func (t *poolTill) PrintCommands(commands []Command) error {
+	return t.pool.print(context.Background(), t.id, commands)
+}
+
+func (t *poolTill) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	return t.pool.print(ctx, t.id, commands)
+}
+
+func (t *poolTill) Status() (*PrinterStatus, error) {
+	return t.pool.status(context.Background(), t.id)
+}
+
+func (t *poolTill) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	return t.pool.status(ctx, t.id)
+}
+
+func (t *poolTill) Session(fn func(Session) error) error {
+	return t.pool.session(t.id, fn)
+}
@@ -0,0 +1,311 @@
+package gongoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errFakeConnection = errors.New("connection refused")
+
+// fakePrinter records the commands it prints, fail can refuse them before they are recorded.
+// It answers status requests with status, refusing them if nil.
+type fakePrinter struct {
+	session sync.Mutex
+	mu      sync.Mutex
+	open    bool
+	openErr error
+	fail    func(command string) error
+	printed []string
+	status  *PrinterStatus
+}
+
+func (p *fakePrinter) Open() error { return p.OpenContext(context.Background()) }
+
+func (p *fakePrinter) OpenContext(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.openErr != nil {
+		return p.openErr
+	}
+	p.open = true
+	return nil
+}
+
+func (p *fakePrinter) IsOpen() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.open
+}
+
+func (p *fakePrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.open = false
+	return nil
+}
+
+func (p *fakePrinter) PrintDocument(doc Document) error {
+	return p.PrintCommands(doc.Commands())
+}
+
+func (p *fakePrinter) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return p.PrintCommands(doc.Commands())
+}
+
+func (p *fakePrinter) PrintCommands(commands []Command) error {
+	for _, command := range commands {
+		encoded, err := command.Encode()
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		fail := p.fail
+		p.mu.Unlock()
+		if fail != nil {
+			err = fail(string(encoded))
+			if err != nil {
+				return err
+			}
+		}
+		p.mu.Lock()
+		p.printed = append(p.printed, string(encoded))
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+func (p *fakePrinter) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	return p.PrintCommands(commands)
+}
+
+func (p *fakePrinter) Status() (*PrinterStatus, error) {
+	return p.StatusContext(context.Background())
+}
+
+func (p *fakePrinter) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status == nil {
+		return nil, newPrinterError(ErrorCode(1), "", NewCommandStatusRequest())
+	}
+	status := *p.status
+	return &status, nil
+}
+
+func (p *fakePrinter) Session(fn func(Session) error) error {
+	p.session.Lock()
+	defer p.session.Unlock()
+	return fn(p)
+}
+
+func (p *fakePrinter) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.printed)
+}
+
+func newFakePool(n int, options PoolOptions) (*PrinterPool, []*fakePrinter) {
+	fakes := make([]*fakePrinter, n)
+	printers := make([]Printer, n)
+	for i := range fakes {
+		fakes[i] = &fakePrinter{}
+		printers[i] = fakes[i]
+	}
+	return NewPrinterPool(printers, options), fakes
+}
+
+func TestPrinterPoolRoundRobin(t *testing.T) {
+
+	pool, fakes := newFakePool(3, PoolOptions{Policy: PoolRoundRobin})
+	err := pool.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	for i := 0; i < 6; i++ {
+		err = pool.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+		if err != nil {
+			t.Errorf("Expected error = nil, got %s", err)
+		}
+	}
+	for i, fake := range fakes {
+		if fake.count() != 2 {
+			t.Errorf("Expected 2 commands on printer %d, got %d", i, fake.count())
+		}
+	}
+
+	err = pool.Close()
+	if err != nil || pool.IsOpen() {
+		t.Errorf("Expected closed pool, got %v", err)
+	}
+
+	fmt.Println("Completed testPrinterPoolRoundRobin")
+}
+
+func TestPrinterPoolLeastBusy(t *testing.T) {
+
+	pool, fakes := newFakePool(2, PoolOptions{Policy: PoolLeastBusy})
+	_ = pool.Open()
+
+	// The first printer is busy until release is closed.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fakes[0].fail = func(command string) error {
+		close(started)
+		<-release
+		return nil
+	}
+	done := make(chan error)
+	go func() {
+		done <- pool.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	}()
+	<-started
+	err := pool.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if fakes[1].count() != 1 {
+		t.Errorf("Expected the second printer to be used while the first is busy")
+	}
+	if health := pool.Health(); health[0].Busy != 1 || health[1].Busy != 0 {
+		t.Errorf("Expected first printer busy, got %+v", health)
+	}
+	close(release)
+	<-done
+
+	fmt.Println("Completed testPrinterPoolLeastBusy")
+}
+
+func TestPrinterPoolStickyPerTill(t *testing.T) {
+
+	pool, fakes := newFakePool(2, PoolOptions{Policy: PoolStickyPerTill})
+	_ = pool.Open()
+	tillA := pool.Till("A")
+	tillB := pool.Till("B")
+
+	for i := 0; i < 3; i++ {
+		_ = tillA.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+		_ = tillB.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	}
+	if fakes[0].count() != 3 || fakes[1].count() != 3 {
+		t.Errorf("Expected 3 commands per printer, got %d and %d", fakes[0].count(), fakes[1].count())
+	}
+
+	// Till A moves to the other printer when its own is unreachable.
+	_ = fakes[0].Close()
+	fakes[0].openErr = errFakeConnection
+	err := tillA.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if err != nil {
+		t.Errorf("Expected error = nil after failover, got %s", err)
+	}
+	fakes[0].openErr = nil
+	_ = tillA.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if fakes[0].count() != 3 || fakes[1].count() != 5 {
+		t.Errorf("Expected till A moved to the second printer, got %d and %d", fakes[0].count(), fakes[1].count())
+	}
+
+	// Closing a till leaves the pool open for the others.
+	err = tillA.Close()
+	if err != nil || !fakes[1].IsOpen() || !pool.IsOpen() {
+		t.Errorf("Expected open pool after closing a till, got %v", err)
+	}
+
+	fmt.Println("Completed testPrinterPoolStickyPerTill")
+}
+
+func TestPrinterPoolFailover(t *testing.T) {
+
+	pool, fakes := newFakePool(2, PoolOptions{Policy: PoolFailover, RetryUnhealthy: time.Hour})
+	fakes[0].openErr = errFakeConnection
+	err := pool.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil with one printer open, got %s", err)
+	}
+	err = pool.PrintDocument(NewDocumentManagement([]string{"test"}))
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	health := pool.Health()
+	if health[0].Healthy || !errors.Is(health[0].LastError, errFakeConnection) || !health[1].Healthy {
+		t.Errorf("Expected first printer unhealthy, got %+v", health)
+	}
+
+	// Refused commands fail over while no command was accepted.
+	fakes[0].openErr = nil
+	pool.Check(context.Background())
+	fakes[0].fail = func(command string) error {
+		return newPrinterError(ErrorCode(8), "", nil)
+	}
+	err = pool.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if err != nil || fakes[1].count() != 4 {
+		t.Errorf("Expected failover to the second printer, got %v", err)
+	}
+
+	// A connection error on the first command is returned, the command may have been printed.
+	fakes[1].fail = func(command string) error { return errFakeConnection }
+	err = pool.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if !errors.Is(err, errFakeConnection) || fakes[0].count() != 0 {
+		t.Errorf("Expected connection error without failover, got %v", err)
+	}
+
+	// Once a command was accepted the error is returned.
+	fakes[1].fail = func(command string) error {
+		if command == "J" {
+			return errFakeConnection
+		}
+		return nil
+	}
+	err = pool.PrintDocument(NewDocumentManagement([]string{"test"}))
+	if !errors.Is(err, errFakeConnection) || fakes[0].count() != 0 {
+		t.Errorf("Expected connection error without failover, got %v", err)
+	}
+
+	// Refusals do not make the printers unhealthy, the error of the last printer is returned.
+	refuse := func(command string) error { return newPrinterError(ErrorCode(2), "", nil) }
+	fakes[0].fail = refuse
+	fakes[1].fail = refuse
+	err = pool.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if health := pool.Health(); !errors.Is(err, ErrorCode(2)) || !health[0].Healthy || !health[1].Healthy {
+		t.Errorf("Expected refusal with healthy printers, got %v %+v", err, health)
+	}
+
+	fmt.Println("Completed testPrinterPoolFailover")
+}
+
+// pipePrinter is a GenericPrinter already attached to a pipe.
+type pipePrinter struct {
+	*GenericPrinter
+}
+
+func (p pipePrinter) Open() error                           { return nil }
+func (p pipePrinter) OpenContext(ctx context.Context) error { return nil }
+func (p pipePrinter) Close() error                          { return nil }
+
+func TestPrinterPoolCheck(t *testing.T) {
+
+	printer, _, conn := newPipePrinter(func(command string) string { return "OK document=commercial" })
+	defer conn.Close()
+	down := &fakePrinter{openErr: errFakeConnection}
+	client, server := net.Pipe()
+	defer server.Close()
+	writeOnly := &GenericPrinter{timeouts: DefaultTimeouts}
+	writeOnly.attach(client)
+	pool := NewPrinterPool([]Printer{pipePrinter{printer}, down, pipePrinter{writeOnly}}, PoolOptions{})
+
+	health := pool.Check(context.Background())
+	if !health[0].Healthy || health[0].Status == nil || !health[0].Status.DocumentOpen() {
+		t.Errorf("Expected healthy printer with open document, got %+v", health[0])
+	}
+	if health[1].Healthy || health[1].Status != nil {
+		t.Errorf("Expected unhealthy printer without status, got %+v", health[1])
+	}
+	if !health[2].Healthy || health[2].Status != nil || health[2].LastError != nil {
+		t.Errorf("Expected healthy printer without replies, got %+v", health[2])
+	}
+
+	fmt.Println("Completed testPrinterPoolCheck")
+}
@@ -2,143 +2,1011 @@ package gongoff
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"go.bug.st/serial"
+	"io"
 	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// Printer is a fiscal printer, it can be implemented outside the package.
+// The printers of the package also implement ContextPrinter, StatusPrinter and SessionPrinter:
+// OpenContext, PrintCommandsContext, StatusContext and WithSession use them with any Printer.
 type Printer interface {
 	Open() error
 	IsOpen() bool
 	PrintDocument(Document) error
 	PrintCommands([]Command) error
 	Close() error
+}
+
+// ContextPrinter is a Printer whose calls can be given a deadline or cancelled with a context.
+type ContextPrinter interface {
+	Printer
+	OpenContext(context.Context) error
+	PrintDocumentContext(context.Context, Document) error
+	PrintCommandsContext(context.Context, []Command) error
+}
+
+// StatusPrinter is a Printer able to ask its status, see PrinterStatus.
+type StatusPrinter interface {
+	Printer
+	// Status asks the printer for its status, printers not supporting status requests refuse it with a *PrinterError
+	// and printers without replies return ErrRepliesDisabled.
+	Status() (*PrinterStatus, error)
+	StatusContext(context.Context) (*PrinterStatus, error)
+}
+
+// SessionPrinter is a Printer giving exclusive access to sequences of calls, see Session.
+type SessionPrinter interface {
+	Printer
+	// Session calls fn with exclusive access to the printer, other goroutines wait until fn returns.
+	Session(fn func(Session) error) error
+}
+
+// OpenContext opens printer with ContextPrinter.OpenContext if implemented, otherwise with Open if ctx is not done.
+func OpenContext(ctx context.Context, printer Printer) error {
+	if p, ok := printer.(ContextPrinter); ok {
+		return p.OpenContext(ctx)
+	}
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	return printer.Open()
+}
+
+// PrintCommandsContext prints the commands with ContextPrinter.PrintCommandsContext if implemented,
+// otherwise with PrintCommands if ctx is not done.
+func PrintCommandsContext(ctx context.Context, printer Printer, commands []Command) error {
+	if p, ok := printer.(ContextPrinter); ok {
+		return p.PrintCommandsContext(ctx, commands)
+	}
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	return printer.PrintCommands(commands)
+}
 
-	flush() error
+// StatusContext asks the status of printer, ErrStatusUnsupported if it does not implement StatusPrinter.
+func StatusContext(ctx context.Context, printer Printer) (*PrinterStatus, error) {
+	if p, ok := printer.(StatusPrinter); ok {
+		return p.StatusContext(ctx)
+	}
+	return nil, ErrStatusUnsupported
 }
 
+// Timeouts limits how long the printer operations can block.
+// A zero value disables the corresponding timeout.
+type Timeouts struct {
+	// Dial is the maximum time to establish the connection, only used by NetworkPrinter.
+	Dial time.Duration
+	// Read is the maximum time to wait for the printer response to a command, only used with replies enabled.
+	Read time.Duration
+	// Write is the maximum time to send a command. A serial port is closed when it expires, since a serial write cannot be interrupted.
+	Write time.Duration
+	// FlowControl is the maximum time to wait for XON after the printer sent XOFF.
+	FlowControl time.Duration
+}
+
+// DefaultTimeouts are the timeouts used by the printers unless changed with SetTimeouts.
+var DefaultTimeouts = Timeouts{
+	Dial:        5 * time.Second,
+	Read:        10 * time.Second,
+	Write:       10 * time.Second,
+	FlowControl: 30 * time.Second,
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// GenericPrinter implements the protocol on a connection, it is embedded by NetworkPrinter and SerialPrinter.
+// The printers are safe for concurrent use: each call holds the printer until it returns, so the commands
+// of a document are never interleaved with the ones sent by other goroutines.
+//
+// By default the commands are only written, paced by the XON/XOFF sent by the printer, see SetReplies
+// to wait for a reply after every command.
 type GenericPrinter struct {
-	dst *bufio.Writer
+	mu       sync.Mutex
+	conn     io.ReadWriter
+	dst      *bufio.Writer
+	replies  chan reply
+	done     chan struct{}
+	flow     *flowControl
+	lost     chan struct{}
+	timeouts Timeouts
+	// expectReplies makes every command wait for its reply, see SetReplies.
+	expectReplies bool
+	// stale is true when the wait for a reply was interrupted, the reply may still come.
+	stale bool
+	// broken is true when a command was interrupted halfway, the printer would take the next bytes as its rest.
+	broken bool
 }
 
+// IsOpen reports whether the printer is connected, it waits for the call in progress if any.
 func (p *GenericPrinter) IsOpen() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.isOpen()
+}
+
+func (p *GenericPrinter) isOpen() bool {
 	return p.dst != nil
 }
 
+// SetTimeouts changes the timeouts used by the printer, it must be called before Open to affect the dial timeout.
+func (p *GenericPrinter) SetTimeouts(timeouts Timeouts) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeouts = timeouts
+}
+
+// SetReplies makes the printer wait for a reply line after every command, it must be called before Open.
+// The replies are not part of the Epson Xon-Xoff protocol: they are the OK/ERR lines described in response.go,
+// answered by gongofftest.Emulator or by a gateway acknowledging the commands in front of the printer.
+// Leave them disabled, the default, when talking to the printer directly: the printer does not answer the commands,
+// so a refused command cannot be detected on a direct connection.
+// Only with replies a refused command returns a *PrinterError, and Status is supported.
+func (p *GenericPrinter) SetReplies(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expectReplies = enabled
+}
+
 func (p *GenericPrinter) flush() error {
 	return p.dst.Flush()
 }
 
+// attach starts using conn for the communication with the printer.
+func (p *GenericPrinter) attach(conn io.ReadWriter) {
+	p.conn = conn
+	p.dst = bufio.NewWriter(conn)
+	p.replies = nil
+	if p.expectReplies {
+		p.replies = make(chan reply, replyBufferSize)
+	}
+	p.done = make(chan struct{})
+	p.flow = newFlowControl()
+	p.lost = make(chan struct{})
+	p.stale = false
+	p.broken = false
+	go readReplies(bufio.NewReader(conn), p.replies, p.flow, p.lost, p.done)
+}
+
+// detach stops using the current connection, the connection itself must be closed by the caller.
+func (p *GenericPrinter) detach() {
+	if p.done != nil {
+		close(p.done)
+	}
+	p.conn = nil
+	p.dst = nil
+	p.replies = nil
+	p.done = nil
+	p.flow = nil
+	p.lost = nil
+}
+
+// connectionLost reports whether the connection failed since it was attached.
+func (p *GenericPrinter) connectionLost() bool {
+	select {
+	case <-p.lost:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *GenericPrinter) PrintDocument(doc Document) error {
-	return p.PrintCommands(doc.get())
+	return p.PrintDocumentContext(context.Background(), doc)
+}
+
+// PrintDocumentContext is like PrintDocument but stops waiting for the printer when ctx is done.
+func (p *GenericPrinter) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return p.PrintCommandsContext(ctx, doc.Commands())
 }
 
 // PrintCommands prints the given commands to the printer.
 // It allows for more flexibility than PrintDocument
+// Every command is written and flushed on its own. With replies enabled the reply is awaited before sending
+// the next one, and if the printer refuses a command a *PrinterError is returned and the remaining commands are not sent.
+// On a direct connection to the printer, without replies, refusals cannot be detected: nil means that the commands
+// were written, the printer may still have refused them, check its display or paper.
 func (p *GenericPrinter) PrintCommands(commands []Command) error {
+	return p.PrintCommandsContext(context.Background(), commands)
+}
+
+// PrintCommandsContext is like PrintCommands but stops waiting for the printer when ctx is done.
+// A command being written is completed first, so that the printer never receives half of it.
+// The commands already accepted by the printer are not rolled back.
+func (p *GenericPrinter) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.printCommands(ctx, commands)
+}
+
+// Session calls fn with exclusive access to the printer, see Session.
+func (p *GenericPrinter) Session(fn func(Session) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return runSession(p, fn)
+}
+
+// printCommands sends the commands, p.mu must be held.
+func (p *GenericPrinter) printCommands(ctx context.Context, commands []Command) error {
+	if !p.isOpen() {
+		return ErrPrinterNotOpen
+	}
+	if p.broken {
+		return ErrConnectionBroken
+	}
 	for _, command := range commands {
-		commandString, err := command.get()
+		_, err := p.sendCommand(ctx, command)
 		if err != nil {
 			return err
 		}
-		_, err = p.dst.Write([]byte(commandString))
+	}
+	return nil
+}
+
+// sendCommand sends a single command and, with replies enabled, waits for its response.
+// The response is returned only if the printer accepted the command, it is nil without replies.
+func (p *GenericPrinter) sendCommand(ctx context.Context, command Command) (*Response, error) {
+	encoded, err := command.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if p.stale {
+		_, err = p.discardReply(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	err := p.flush()
+
+	err = p.setWriteDeadline()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	started, err := p.write(ctx, encoded)
+	if err != nil {
+		p.broken = started
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if !p.expectReplies {
+		return nil, nil
+	}
+
+	response, err := p.waitResponse(ctx)
+	if err != nil {
+		if err == ErrResponseTimeout || err == ctx.Err() {
+			p.stale = true
+		}
+		return nil, err
+	}
+	err = response.err(command)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
+// discardReply waits at most Timeouts.Read for the reply of the command whose wait was interrupted and drops it,
+// reporting whether it came: the printer executes the commands in order, so it comes before the next reply.
+// A reply coming later is taken for the reply of the next command: NetworkPrinter replaces the connection instead
+// and SerialPrinter opens the port again.
+func (p *GenericPrinter) discardReply(ctx context.Context) (bool, error) {
+	var timeout <-chan time.Time
+	if p.timeouts.Read > 0 {
+		timer := time.NewTimer(p.timeouts.Read)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case _, ok := <-p.replies:
+		p.stale = false
+		return ok, nil
+	case <-timeout:
+		p.stale = false
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// write sends a command in chunks, pausing whenever the printer asks to with XOFF.
+// ctx is checked only before the first byte: once started the command is completed, waiting for XON at most
+// Timeouts.FlowControl and for each write at most Timeouts.Write, since the printer would take the bytes
+// of the next command as the rest of this one.
+// started reports whether some bytes were written, if so an error leaves the connection broken.
+func (p *GenericPrinter) write(ctx context.Context, data []byte) (started bool, err error) {
+	err = ctx.Err()
+	if err != nil {
+		return false, err
+	}
+	for len(data) > 0 {
+		waitCtx := ctx
+		if started {
+			waitCtx = context.Background()
+		}
+		err := p.flow.wait(waitCtx, p.timeouts.FlowControl)
+		if err != nil {
+			return started, err
+		}
+		chunk := data
+		if len(chunk) > flowChunkSize {
+			chunk = chunk[:flowChunkSize]
+		}
+		started = true
+		_, err = p.dst.Write(chunk)
+		if err != nil {
+			return started, err
+		}
+		err = p.flush()
+		if err != nil {
+			return started, err
+		}
+		data = data[len(chunk):]
+	}
+	return started, nil
+}
+
+// setWriteDeadline applies the write timeout to connections supporting deadlines, the context does not
+// interrupt a command being written, see write.
+func (p *GenericPrinter) setWriteDeadline() error {
+	deadliner, ok := p.conn.(writeDeadliner)
+	if !ok {
+		return nil
+	}
+	var deadline time.Time
+	if p.timeouts.Write > 0 {
+		deadline = time.Now().Add(p.timeouts.Write)
+	}
+	return deadliner.SetWriteDeadline(deadline)
+}
+
+// waitResponse waits for the next response line sent by the printer.
+func (p *GenericPrinter) waitResponse(ctx context.Context) (*Response, error) {
+	var timeout <-chan time.Time
+	if p.timeouts.Read > 0 {
+		timer := time.NewTimer(p.timeouts.Read)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case r, ok := <-p.replies:
+		if !ok {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		return decodeResponse(r.line)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timeout:
+		return nil, ErrResponseTimeout
+	}
+}
+
+// NetworkOptions configures the connection of NetworkPrinter.
+type NetworkOptions struct {
+	// KeepAlive is the period of the TCP keep-alive probes, zero uses the system default and a negative value disables them.
+	KeepAlive time.Duration
+	// ReconnectDelay is the delay between the first failed reconnection attempt and the next one,
+	// doubled after each failed attempt up to MaxReconnectDelay. Zero disables the automatic reconnection.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// MaxReconnectAttempts is the number of dials tried before giving up, 0 keeps trying until the context is done.
+	MaxReconnectAttempts int
+	// ProbeInterval sends a status request before a command when the connection was idle for longer,
+	// so that a connection silently dropped by the network is replaced before sending the command. Zero disables it.
+	// The probe needs replies, see SetReplies.
+	ProbeInterval time.Duration
+	// OnConnectionChange, if not nil, is called with true when the printer connects and with false when the
+	// connection is lost or closed. It is called by the goroutine using the printer and must not use it.
+	OnConnectionChange func(connected bool)
+}
+
+// DefaultNetworkOptions is the configuration used by NewNetworkPrinter: keep-alive every 30 seconds and
+// up to 5 reconnection attempts, waiting from half a second to 10 seconds between them.
+var DefaultNetworkOptions = NetworkOptions{
+	KeepAlive:            30 * time.Second,
+	ReconnectDelay:       500 * time.Millisecond,
+	MaxReconnectDelay:    10 * time.Second,
+	MaxReconnectAttempts: 5,
+}
+
+// NetworkPrinter is a printer connected through TCP.
+//
+// When the connection is lost the printer reconnects before sending the next command, see NetworkOptions.
+// A command is never sent twice: if the connection fails while waiting for its response the error is returned,
+// since the printer may have executed it, and only the following command reconnects.
+// The connection is also replaced after a reply did not come in time, so that a late reply is never taken
+// for the reply of the next command, and after a command was interrupted halfway.
+// A Session stays on one connection: once it used the connection, the following calls of the session return
+// ErrConnectionLost if it is lost, so that a document is never continued on a new connection.
+// IsOpen is false while the printer is disconnected, Ping checks that the printer actually answers.
 type NetworkPrinter struct {
 	GenericPrinter
-	socket *net.Conn
-	ip     string
-	port   int
+	socket  *net.Conn
+	ip      string
+	port    int
+	options NetworkOptions
+	// opened is true between Open and Close, even while disconnected.
+	opened       bool
+	connected    bool
+	lastActivity time.Time
+	// inSession is true during a Session, pinned once the session used the connection.
+	inSession bool
+	pinned    bool
 }
 
 func NewNetworkPrinter(ip string, port int) *NetworkPrinter {
-	return &NetworkPrinter{ip: ip, port: port}
+	return NewNetworkPrinterWithOptions(ip, port, DefaultNetworkOptions)
+}
+
+// NewNetworkPrinterWithOptions creates a NetworkPrinter with a custom connection configuration.
+func NewNetworkPrinterWithOptions(ip string, port int, options NetworkOptions) *NetworkPrinter {
+	printer := &NetworkPrinter{ip: ip, port: port, options: options}
+	printer.timeouts = DefaultTimeouts
+	return printer
 }
 
 func (p *NetworkPrinter) Open() error {
+	return p.OpenContext(context.Background())
+}
 
-	socket, err := net.Dial("tcp", fmt.Sprintf("%s:%d", p.ip, p.port))
+// OpenContext is like Open but gives up dialing when ctx is done.
+// Open dials only once, the automatic reconnection applies to the connections lost afterwards.
+func (p *NetworkPrinter) OpenContext(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	p.opened = true
+	return nil
+
+}
+
+func (p *NetworkPrinter) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeouts.Dial, KeepAlive: p.options.KeepAlive}
+	socket, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(p.ip, strconv.Itoa(p.port)))
 	if err != nil {
 		return err
 	}
 	p.socket = &socket
-	p.dst = bufio.NewWriter(socket)
+	p.attach(socket)
+	p.lastActivity = time.Now()
+	p.setConnected(true)
 	return nil
+}
+
+// disconnect drops a failed connection, the printer stays open and reconnects before the next command.
+func (p *NetworkPrinter) disconnect() {
+	if p.socket == nil {
+		return
+	}
+	socket := *p.socket
+	p.detach()
+	p.socket = nil
+	_ = socket.Close()
+	p.setConnected(false)
+}
+
+func (p *NetworkPrinter) setConnected(connected bool) {
+	if p.connected == connected {
+		return
+	}
+	p.connected = connected
+	if p.options.OnConnectionChange != nil {
+		p.options.OnConnectionChange(connected)
+	}
+}
+
+// connect makes sure the connection is alive before sending a command, reconnecting if it was lost.
+func (p *NetworkPrinter) connect(ctx context.Context) error {
+	if !p.opened {
+		return ErrPrinterNotOpen
+	}
+	if p.socket != nil && p.connectionLost() {
+		p.disconnect()
+	}
+	if p.socket != nil && p.expectReplies && p.options.ProbeInterval > 0 && time.Since(p.lastActivity) > p.options.ProbeInterval {
+		_, err := p.GenericPrinter.status(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var printerErr *PrinterError
+		if err != nil && !errors.As(err, &printerErr) {
+			p.disconnect()
+		} else {
+			p.lastActivity = time.Now()
+		}
+	}
+	if p.socket != nil {
+		return nil
+	}
+	if p.pinned || p.options.ReconnectDelay <= 0 {
+		return ErrConnectionLost
+	}
+	return p.reconnect(ctx)
+}
+
+// reconnect dials until it succeeds, waiting longer after each failed attempt.
+func (p *NetworkPrinter) reconnect(ctx context.Context) error {
+	delay := p.options.ReconnectDelay
+	for attempt := 1; ; attempt++ {
+		err := p.dial(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if p.options.MaxReconnectAttempts > 0 && attempt >= p.options.MaxReconnectAttempts {
+			return &reconnectError{err: err}
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		delay *= 2
+		if delay > p.options.MaxReconnectDelay {
+			delay = p.options.MaxReconnectDelay
+		}
+	}
+}
+
+// reconnectError is ErrConnectionLost after the reconnection failed, it unwraps to the error of the last dial
+// so that callers can tell that nothing was sent.
+type reconnectError struct {
+	err error
+}
+
+func (e *reconnectError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrConnectionLost, e.err)
+}
+
+func (e *reconnectError) Is(target error) bool {
+	return target == ErrConnectionLost
+}
+
+func (e *reconnectError) Unwrap() error {
+	return e.err
+}
+
+// done records the outcome of an exchange with the printer, dropping the connection if it failed
+// or if a reply is still expected.
+func (p *NetworkPrinter) done(ctx context.Context, err error) {
+	var printerErr *PrinterError
+	var opErr *net.OpError
+	switch {
+	case err == nil || errors.As(err, &printerErr):
+		p.lastActivity = time.Now()
+	case p.stale || p.broken:
+		p.disconnect()
+	case ctx.Err() != nil:
+	case p.connectionLost() || errors.As(err, &opErr):
+		p.disconnect()
+	}
+}
+
+func (p *NetworkPrinter) PrintDocument(doc Document) error {
+	return p.PrintCommandsContext(context.Background(), doc.Commands())
+}
+
+// PrintDocumentContext is like PrintDocument but stops waiting for the printer when ctx is done.
+func (p *NetworkPrinter) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return p.PrintCommandsContext(ctx, doc.Commands())
+}
+
+func (p *NetworkPrinter) PrintCommands(commands []Command) error {
+	return p.PrintCommandsContext(context.Background(), commands)
+}
 
+// PrintCommandsContext is like GenericPrinter.PrintCommandsContext but reconnects first if the connection was lost.
+func (p *NetworkPrinter) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.printCommands(ctx, commands)
+}
+
+// Session calls fn with exclusive access to the printer on a single connection, see Session.
+func (p *NetworkPrinter) Session(fn func(Session) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inSession = true
+	defer func() {
+		p.inSession = false
+		p.pinned = false
+	}()
+	return runSession(p, fn)
+}
+
+func (p *NetworkPrinter) printCommands(ctx context.Context, commands []Command) error {
+	err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	p.pinned = p.inSession
+	err = p.GenericPrinter.printCommands(ctx, commands)
+	p.done(ctx, err)
+	return err
+}
+
+func (p *NetworkPrinter) Status() (*PrinterStatus, error) {
+	return p.StatusContext(context.Background())
+}
+
+// StatusContext is like GenericPrinter.StatusContext but reconnects first if the connection was lost.
+func (p *NetworkPrinter) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status(ctx)
+}
+
+func (p *NetworkPrinter) status(ctx context.Context) (*PrinterStatus, error) {
+	err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.pinned = p.inSession
+	status, err := p.GenericPrinter.status(ctx)
+	p.done(ctx, err)
+	return status, err
+}
+
+// Ping checks that the printer answers, reconnecting first if the connection was lost.
+// Printers refusing the status request are considered alive. Without replies Ping only reconnects
+// if the connection is known to be lost, since the printer does not answer.
+func (p *NetworkPrinter) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.expectReplies {
+		return p.connect(ctx)
+	}
+	_, err := p.status(ctx)
+	var printerErr *PrinterError
+	if errors.As(err, &printerErr) {
+		return nil
+	}
+	return err
 }
 
 func (p *NetworkPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.opened = false
 	if p.socket != nil {
 		sP := *p.socket
-		err := sP.Close()
-		if err != nil {
-			return err
-		}
-		p.dst = nil
+		p.detach()
 		p.socket = nil
-		return nil
+		err := sP.Close()
+		p.setConnected(false)
+		return err
 	} else {
 		return nil
 	}
 }
 
+// Parity is the parity control of the serial port.
+type Parity int
+
+const (
+	ParityNone Parity = iota
+	ParityOdd
+	ParityEven
+	ParityMark
+	ParitySpace
+)
+
+// StopBits is the number of stop bits of the serial port.
+type StopBits int
+
+const (
+	StopBitsOne StopBits = iota
+	StopBitsOnePointFive
+	StopBitsTwo
+)
+
+// SerialOptions is the configuration of the serial port used by SerialPrinter.
+type SerialOptions struct {
+	BaudRate int
+	// DataBits must be 5, 6, 7 or 8.
+	DataBits int
+	Parity   Parity
+	StopBits StopBits
+	// RTS and DTR set the modem status bits after opening the port, nil leaves them untouched.
+	RTS *bool
+	DTR *bool
+	// ReadTimeout is the maximum time to wait for the printer response, zero keeps the printer Timeouts.Read.
+	ReadTimeout time.Duration
+}
+
+// DefaultSerialOptions is the configuration used by NewSerialPrinter: 9600 baud, 8 data bits, no parity, 1 stop bit.
+var DefaultSerialOptions = SerialOptions{
+	BaudRate: 9600,
+	DataBits: 8,
+	Parity:   ParityNone,
+	StopBits: StopBitsOne,
+}
+
+func (o SerialOptions) mode() (*serial.Mode, error) {
+	mode := &serial.Mode{BaudRate: o.BaudRate, DataBits: o.DataBits}
+	switch o.Parity {
+	case ParityNone:
+		mode.Parity = serial.NoParity
+	case ParityOdd:
+		mode.Parity = serial.OddParity
+	case ParityEven:
+		mode.Parity = serial.EvenParity
+	case ParityMark:
+		mode.Parity = serial.MarkParity
+	case ParitySpace:
+		mode.Parity = serial.SpaceParity
+	default:
+		return nil, fmt.Errorf("%w: parity %d", ErrInvalidSerialOptions, o.Parity)
+	}
+	switch o.StopBits {
+	case StopBitsOne:
+		mode.StopBits = serial.OneStopBit
+	case StopBitsOnePointFive:
+		mode.StopBits = serial.OnePointFiveStopBits
+	case StopBitsTwo:
+		mode.StopBits = serial.TwoStopBits
+	default:
+		return nil, fmt.Errorf("%w: stop bits %d", ErrInvalidSerialOptions, o.StopBits)
+	}
+	if o.BaudRate <= 0 {
+		return nil, fmt.Errorf("%w: baud rate %d", ErrInvalidSerialOptions, o.BaudRate)
+	}
+	if o.DataBits < 5 || o.DataBits > 8 {
+		return nil, fmt.Errorf("%w: data bits %d", ErrInvalidSerialOptions, o.DataBits)
+	}
+	return mode, nil
+}
+
+// SerialPrinter is a printer connected to a serial port.
+// After a reply did not come in time, the next call waits for it at most Timeouts.Read before sending, since the
+// printer answers in order. If it still does not come the port is closed and opened again with its input discarded:
+// a reply coming after that is taken for the reply of the next command, Timeouts.Read must exceed the slowest command.
+// After a command was interrupted halfway, ex. by Timeouts.FlowControl or Timeouts.Write, every call returns
+// ErrConnectionBroken until the printer is closed and opened again.
 type SerialPrinter struct {
 	GenericPrinter
-	serialPort *serial.Port
+	serialPort *serialConn
 	port       string
-	baudRate   int
+	options    SerialOptions
+	// opened is true between Open and Close, even if opening the port again failed.
+	opened bool
 }
 
 func NewSerialPrinter(port string) *SerialPrinter {
-	return &SerialPrinter{port: port, baudRate: 9600}
+	return NewSerialPrinterWithOptions(port, DefaultSerialOptions)
+}
+
+// NewSerialPrinterWithOptions creates a SerialPrinter with a custom serial port configuration.
+// port is either a name returned by the serial ports enumeration (ex. "COM3") or a device path (ex. "/dev/serial/by-id/...").
+func NewSerialPrinterWithOptions(port string, options SerialOptions) *SerialPrinter {
+	printer := &SerialPrinter{port: port, options: options}
+	printer.timeouts = DefaultTimeouts
+	if options.ReadTimeout > 0 {
+		printer.timeouts.Read = options.ReadTimeout
+	}
+	return printer
 }
 
 func (p *SerialPrinter) Open() error {
+	return p.OpenContext(context.Background())
+}
 
-	ports, err := serial.GetPortsList()
+// OpenContext is like Open but fails if ctx is already done.
+// The port is opened directly, symlinks and pseudo-terminals are supported.
+func (p *SerialPrinter) OpenContext(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := p.open()
 	if err != nil {
 		return err
 	}
-	if len(ports) == 0 {
-		return errors.New("no serial ports found")
+	p.opened = true
+	return nil
+}
+
+// open opens the port, discarding what the printer sent while it was closed.
+func (p *SerialPrinter) open() error {
+	mode, err := p.options.mode()
+	if err != nil {
+		return err
+	}
+
+	serialPort, err := serial.Open(p.port, mode)
+	if err != nil {
+		return p.openError(err)
+	}
+	if p.options.RTS != nil {
+		err = serialPort.SetRTS(*p.options.RTS)
+	}
+	if err == nil && p.options.DTR != nil {
+		err = serialPort.SetDTR(*p.options.DTR)
+	}
+	if err == nil {
+		err = serialPort.ResetInputBuffer()
 	}
+	if err != nil {
+		_ = serialPort.Close()
+		return err
+	}
+	p.serialPort = &serialConn{Port: serialPort}
+	p.attach(p.serialPort)
+	return nil
+}
 
+// openError explains why the port could not be opened, using the serial ports enumeration when possible.
+func (p *SerialPrinter) openError(err error) error {
+	ports, listErr := serial.GetPortsList()
+	if listErr != nil {
+		return err
+	}
+	if len(ports) == 0 {
+		return fmt.Errorf("%w: %v", ErrNoSerialPorts, err)
+	}
 	for _, port := range ports {
 		if port == p.port {
-			serialPort, err := serial.Open(port, &serial.Mode{BaudRate: p.baudRate})
-			if err != nil {
-				return err
-			}
-			p.serialPort = &serialPort
-			p.dst = bufio.NewWriter(serialPort)
-			return nil
+			return err
 		}
 	}
+	return fmt.Errorf("%w: %v", ErrSerialPortNotFound, err)
+}
 
-	return errors.New("chosen serial port not found")
+// closePort closes the port, keeping the printer open.
+func (p *SerialPrinter) closePort() error {
+	if p.serialPort == nil {
+		return nil
+	}
+	sP := p.serialPort
+	p.detach()
+	p.serialPort = nil
+	return sP.Close()
 }
 
-func (p *SerialPrinter) Close() error {
-	if p.serialPort != nil {
-		sP := *p.serialPort
-		err := sP.Close()
+// connect waits for the reply still expected before sending a command, opening the port again if it does not come.
+func (p *SerialPrinter) connect(ctx context.Context) error {
+	if !p.opened {
+		return ErrPrinterNotOpen
+	}
+	if p.serialPort != nil && p.stale {
+		came, err := p.discardReply(ctx)
 		if err != nil {
 			return err
 		}
-		p.dst = nil
-		p.serialPort = nil
-		return nil
-	} else {
+		if !came {
+			p.closePort()
+		}
+	}
+	if p.serialPort != nil {
 		return nil
 	}
+	return p.open()
+}
+
+func (p *SerialPrinter) PrintDocument(doc Document) error {
+	return p.PrintCommandsContext(context.Background(), doc.Commands())
+}
+
+// PrintDocumentContext is like PrintDocument but stops waiting for the printer when ctx is done.
+func (p *SerialPrinter) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return p.PrintCommandsContext(ctx, doc.Commands())
+}
+
+func (p *SerialPrinter) PrintCommands(commands []Command) error {
+	return p.PrintCommandsContext(context.Background(), commands)
+}
+
+// PrintCommandsContext is like GenericPrinter.PrintCommandsContext but waits first for the reply still expected, see SerialPrinter.
+func (p *SerialPrinter) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.printCommands(ctx, commands)
+}
+
+// Session calls fn with exclusive access to the printer, see Session.
+func (p *SerialPrinter) Session(fn func(Session) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return runSession(p, fn)
+}
+
+func (p *SerialPrinter) printCommands(ctx context.Context, commands []Command) error {
+	err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+	return p.GenericPrinter.printCommands(ctx, commands)
+}
+
+func (p *SerialPrinter) Status() (*PrinterStatus, error) {
+	return p.StatusContext(context.Background())
+}
+
+// StatusContext is like GenericPrinter.StatusContext but waits first for the reply still expected, see SerialPrinter.
+func (p *SerialPrinter) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status(ctx)
+}
+
+func (p *SerialPrinter) status(ctx context.Context) (*PrinterStatus, error) {
+	err := p.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.GenericPrinter.status(ctx)
+}
+
+func (p *SerialPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.opened = false
+	return p.closePort()
+}
+
+// serialConn adds the write deadline to a serial port. A write not completed in time closes the port,
+// the write itself ends when the driver gives up.
+type serialConn struct {
+	serial.Port
+	deadline time.Time
+}
+
+type writeResult struct {
+	n   int
+	err error
+}
+
+func (c *serialConn) SetWriteDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+func (c *serialConn) Write(data []byte) (int, error) {
+	if c.deadline.IsZero() {
+		return c.Port.Write(data)
+	}
+	timeout := time.Until(c.deadline)
+	if timeout <= 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+	result := make(chan writeResult, 1)
+	go func() {
+		n, err := c.Port.Write(data)
+		result <- writeResult{n: n, err: err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-result:
+		return r.n, r.err
+	case <-timer.C:
+		c.Port.Close()
+		return 0, os.ErrDeadlineExceeded
+	}
 }
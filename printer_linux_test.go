@@ -0,0 +1,204 @@
+package gongoff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPseudoTerminal returns the master side and the path of the slave side of a new pseudo-terminal.
+func openPseudoTerminal(t *testing.T) (*os.File, string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals not available: %s", err)
+	}
+	fd := int(master.Fd())
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	number, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return master, "/dev/pts/" + strconv.Itoa(number)
+}
+
+func TestSerialPrinterPseudoTerminal(t *testing.T) {
+
+	master, slave := openPseudoTerminal(t)
+	defer master.Close()
+
+	// Symlinked paths, like /dev/serial/by-id, must be opened directly.
+	link := filepath.Join(t.TempDir(), "printer")
+	err := os.Symlink(slave, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultSerialOptions
+	options.BaudRate = 19200
+	options.Parity = ParityEven
+	printer := NewSerialPrinterWithOptions(link, options)
+	err = printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		n, err := master.Read(buf)
+		if err != nil {
+			return
+		}
+		received <- string(buf[:n])
+		_, _ = master.Write([]byte("OK\r"))
+	}()
+
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("test", 1)})
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if command := <-received; command != "\"test\"1%" {
+		t.Errorf("Expected \"test\"1%%, got %s", command)
+	}
+
+	fmt.Println("Completed testSerialPrinterPseudoTerminal")
+}
+
+// readMaster reads from the master side of a pseudo-terminal, waiting while the slave side is closed.
+func readMaster(t *testing.T, master *os.File) string {
+	t.Helper()
+	buf := make([]byte, 64)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		n, err := master.Read(buf)
+		if err == nil {
+			return string(buf[:n])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Expected a command from the printer")
+	return ""
+}
+
+func TestSerialPrinterReplyTimeout(t *testing.T) {
+
+	master, slave := openPseudoTerminal(t)
+	defer master.Close()
+
+	printer := NewSerialPrinter(slave)
+	printer.SetReplies(true)
+	printer.SetTimeouts(Timeouts{Read: 50 * time.Millisecond})
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("late", 1)})
+	if !errors.Is(err, ErrResponseTimeout) {
+		t.Fatalf("Expected ErrResponseTimeout, got %v", err)
+	}
+	if command := readMaster(t, master); command != "\"late\"1%" {
+		t.Errorf("Expected \"late\"1%%, got %s", command)
+	}
+
+	// The reply comes after the timeout, while the next command waits for it.
+	result := make(chan error, 1)
+	go func() {
+		result <- printer.PrintCommands([]Command{NewCommandDisplayMessage("next", 1)})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	_, _ = master.Write([]byte("ERR 1 LATE\r"))
+	if command := readMaster(t, master); command != "\"next\"1%" {
+		t.Errorf("Expected \"next\"1%%, got %s", command)
+	}
+	_, _ = master.Write([]byte("OK\r"))
+	err = <-result
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	// The reply never comes, the port is opened again before the next command.
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("lost", 1)})
+	if !errors.Is(err, ErrResponseTimeout) {
+		t.Fatalf("Expected ErrResponseTimeout, got %v", err)
+	}
+	readMaster(t, master)
+	go func() {
+		result <- printer.PrintCommands([]Command{NewCommandDisplayMessage("next", 1)})
+	}()
+	if command := readMaster(t, master); command != "\"next\"1%" {
+		t.Errorf("Expected \"next\"1%%, got %s", command)
+	}
+	_, _ = master.Write([]byte("OK\r"))
+	err = <-result
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	fmt.Println("Completed testSerialPrinterReplyTimeout")
+}
+
+// rawCommand is a command sending arbitrary bytes.
+type rawCommand []byte
+
+func (c rawCommand) Encode() ([]byte, error) {
+	return c, nil
+}
+
+func TestSerialPrinterWriteTimeout(t *testing.T) {
+
+	master, slave := openPseudoTerminal(t)
+	defer master.Close()
+
+	printer := NewSerialPrinter(slave)
+	printer.SetTimeouts(Timeouts{Write: 50 * time.Millisecond})
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	// Nobody reads the port, the command fills its buffers.
+	err = printer.PrintCommands([]Command{rawCommand(bytes.Repeat([]byte("A"), 1<<20))})
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Expected os.ErrDeadlineExceeded, got %v", err)
+	}
+	err = printer.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if !errors.Is(err, ErrConnectionBroken) {
+		t.Errorf("Expected ErrConnectionBroken, got %v", err)
+	}
+	err = printer.Close()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	fmt.Println("Completed testSerialPrinterWriteTimeout")
+}
+
+func TestSerialPrinterOptions(t *testing.T) {
+
+	options := DefaultSerialOptions
+	options.DataBits = 9
+	err := NewSerialPrinterWithOptions("/dev/null", options).Open()
+	if !errors.Is(err, ErrInvalidSerialOptions) {
+		t.Errorf("Expected ErrInvalidSerialOptions, got %v", err)
+	}
+
+	err = NewSerialPrinter("/dev/gongoff-missing").Open()
+	if !errors.Is(err, ErrSerialPortNotFound) && !errors.Is(err, ErrNoSerialPorts) {
+		t.Errorf("Expected ErrSerialPortNotFound, got %v", err)
+	}
+
+	fmt.Println("Completed testSerialPrinterOptions")
+}
@@ -0,0 +1,251 @@
+package gongoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPipePrinter returns a GenericPrinter with replies enabled connected to a fake printer answering every command with reply.
+// An empty reply makes the fake printer ignore the command.
+func newPipePrinter(reply func(command string) string) (*GenericPrinter, *[]string, net.Conn) {
+	client, server := net.Pipe()
+	received := &[]string{}
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			command := string(buf[:n])
+			*received = append(*received, command)
+			answer := reply(command)
+			if answer == "" {
+				continue
+			}
+			_, err = server.Write([]byte(answer + "\r\n"))
+			if err != nil {
+				return
+			}
+		}
+	}()
+	printer := &GenericPrinter{timeouts: DefaultTimeouts, expectReplies: true}
+	printer.attach(client)
+	return printer, received, client
+}
+
+func TestGenericPrinterWriteOnly(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer client.Close()
+	received := make(chan string, 3)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			received <- string(buf[:n])
+		}
+	}()
+	printer := &GenericPrinter{timeouts: Timeouts{Read: 50 * time.Millisecond}}
+	printer.attach(client)
+
+	// The printer never answers, the commands are only written.
+	err := printer.PrintDocument(NewDocumentManagement([]string{"test"}))
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	for _, expected := range []string{"j", "\"test\"@", "J"} {
+		if command := <-received; command != expected {
+			t.Errorf("Expected %s, got %s", expected, command)
+		}
+	}
+	_, err = printer.Status()
+	if !errors.Is(err, ErrRepliesDisabled) {
+		t.Errorf("Expected ErrRepliesDisabled, got %v", err)
+	}
+
+	fmt.Println("Completed testGenericPrinterWriteOnly")
+}
+
+func TestGenericPrinterPrintCommands(t *testing.T) {
+
+	printer, received, conn := newPipePrinter(func(command string) string { return "OK" })
+	defer conn.Close()
+
+	err := printer.PrintDocument(NewDocumentManagement([]string{"test"}))
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if len(*received) != 3 || (*received)[1] != "\"test\"@" {
+		t.Errorf("Expected 3 commands, got %v", *received)
+	}
+
+	fmt.Println("Completed testGenericPrinterPrintCommands")
+}
+
+func TestGenericPrinterRefusedCommand(t *testing.T) {
+
+	printer, received, conn := newPipePrinter(func(command string) string {
+		if command == "J" {
+			return "ERR 5 DOCUMENT NOT OPEN"
+		}
+		return "OK"
+	})
+	defer conn.Close()
+
+	err := printer.PrintCommands([]Command{
+		NewCommandGeneric([]Data{}, Terminator{nil, TerminatorTypeCloseManagementDocument}),
+		NewCommandDisplayMessage("never sent", 1),
+	})
+	var printerError *PrinterError
+	if !errors.As(err, &printerError) {
+		t.Fatalf("Expected *PrinterError, got %v", err)
+	}
+	if printerError.Code != ErrorCode(5) || printerError.Message != "DOCUMENT NOT OPEN" {
+		t.Errorf("Expected code 5 DOCUMENT NOT OPEN, got %d %s", printerError.Code, printerError.Message)
+	}
+	if printerError.Command == nil {
+		t.Errorf("Expected refused command, got nil")
+	}
+	if !errors.Is(err, ErrorCode(5)) {
+		t.Errorf("Expected errors.Is(err, ErrorCode(5))")
+	}
+	if len(*received) != 1 {
+		t.Errorf("Expected 1 command sent, got %d", len(*received))
+	}
+
+	fmt.Println("Completed testGenericPrinterRefusedCommand")
+}
+
+func TestGenericPrinterContext(t *testing.T) {
+
+	printer, _, conn := newPipePrinter(func(command string) string { return "" })
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := printer.PrintCommandsContext(ctx, []Command{NewCommandDisplayMessage("test", 1)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	printer.SetTimeouts(Timeouts{Read: 50 * time.Millisecond})
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("test", 1)})
+	if !errors.Is(err, ErrResponseTimeout) {
+		t.Errorf("Expected ErrResponseTimeout, got %v", err)
+	}
+
+	closed := &GenericPrinter{}
+	err = closed.PrintCommands([]Command{NewCommandDisplayMessage("test", 1)})
+	if !errors.Is(err, ErrPrinterNotOpen) {
+		t.Errorf("Expected ErrPrinterNotOpen, got %v", err)
+	}
+
+	fmt.Println("Completed testGenericPrinterContext")
+}
+
+func TestGenericPrinterLostReply(t *testing.T) {
+
+	// The first command is never answered, the following ones are.
+	printer, _, conn := newPipePrinter(func(command string) string {
+		if strings.Contains(command, "lost") {
+			return ""
+		}
+		return "OK"
+	})
+	defer conn.Close()
+	printer.SetTimeouts(Timeouts{Read: 50 * time.Millisecond})
+
+	err := printer.PrintCommands([]Command{NewCommandDisplayMessage("lost", 1)})
+	if !errors.Is(err, ErrResponseTimeout) {
+		t.Fatalf("Expected ErrResponseTimeout, got %v", err)
+	}
+	// A lost reply does not block the next commands.
+	for i := 0; i < 3; i++ {
+		err = printer.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+		if err != nil {
+			t.Errorf("Expected error = nil, got %s", err)
+		}
+	}
+
+	fmt.Println("Completed testGenericPrinterLostReply")
+}
+
+func TestNetworkPrinterReplyTimeout(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// The first connection never answers, the following ones answer every command.
+	go func() {
+		for connections := 0; ; connections++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, answer bool) {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					_, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					if answer {
+						_, _ = conn.Write([]byte("OK\r\n"))
+					}
+				}
+			}(conn, connections > 0)
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	printer := NewNetworkPrinter(address.IP.String(), address.Port)
+	printer.SetReplies(true)
+	printer.SetTimeouts(Timeouts{Read: 50 * time.Millisecond})
+	err = printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	err = printer.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if !errors.Is(err, ErrResponseTimeout) {
+		t.Fatalf("Expected ErrResponseTimeout, got %v", err)
+	}
+	if printer.IsOpen() {
+		t.Errorf("Expected the connection to be dropped after the timeout")
+	}
+	err = printer.PrintCommands([]Command{NewCommandOpenCashDrawer()})
+	if err != nil {
+		t.Errorf("Expected error = nil on a new connection, got %s", err)
+	}
+
+	fmt.Println("Completed testNetworkPrinterReplyTimeout")
+}
+
+func TestNetworkPrinterOpenContext(t *testing.T) {
+
+	printer := NewNetworkPrinter("127.0.0.1", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := printer.OpenContext(ctx)
+	if err == nil {
+		t.Errorf("Expected error != nil, got nil")
+	}
+	if printer.IsOpen() {
+		t.Errorf("Expected printer to be closed")
+	}
+
+	fmt.Println("Completed testNetworkPrinterOpenContext")
+}
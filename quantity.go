@@ -0,0 +1,121 @@
+package gongoff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Quantity is a sold quantity in thousandths, the precision of the printer.
+// Ex. Quantity(2000) -> 2 pieces, Quantity(750) -> 0,750 kg
+type Quantity int64
+
+// quantityDecimals is the number of fraction digits accepted by the printer before the multiplier.
+const quantityDecimals = 3
+
+// quantityIntegers is the number of integer digits accepted by the printer before the multiplier,
+// quantityLength the longest quantity, ex. "9999.999".
+const (
+	quantityIntegers = 4
+	quantityLength   = quantityIntegers + 1 + quantityDecimals
+)
+
+// maxQuantity is the largest quantity accepted by the printer, 9999,999.
+const maxQuantity Quantity = 9999999
+
+// quantityUnit is the Quantity of one piece.
+const quantityUnit Quantity = 1000
+
+// NewQuantity returns the Quantity of the given number of pieces.
+func NewQuantity(pieces int) Quantity {
+	return Quantity(pieces) * quantityUnit
+}
+
+// ParseQuantity parses a quantity written with either ',' or '.' as decimal separator.
+// Ex. "2", "0,750", "1.5"
+// At most three decimal digits and four integer digits are accepted, the limits of the printer.
+func ParseQuantity(s string) (Quantity, error) {
+	return decodeQuantity(strings.Replace(strings.TrimSpace(s), ",", ".", 1))
+}
+
+// decodeQuantity parses a quantity as sent to the printer, ex. "2" or "0.750".
+func decodeQuantity(s string) (Quantity, error) {
+	integer, fraction, decimal := strings.Cut(s, ".")
+	if integer == "" || (decimal && fraction == "") || !allDigits(integer) || !allDigits(fraction) {
+		return 0, fmt.Errorf("%w: quantity %q", ErrInvalidData, s)
+	}
+	if len(fraction) > quantityDecimals {
+		return 0, fmt.Errorf("%w: %q", ErrQuantityPrecision, s)
+	}
+	if len(integer) > quantityIntegers || len(s) > quantityLength {
+		return 0, fmt.Errorf("%w: %q", ErrQuantityTooLarge, s)
+	}
+	fraction += strings.Repeat("0", quantityDecimals-len(fraction))
+	thousandths, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: quantity %q", ErrInvalidData, s)
+	}
+	return Quantity(thousandths), nil
+}
+
+// String formats the quantity in the italian locale, fractional quantities with three decimal digits.
+// Ex. Quantity(2000) -> "2", Quantity(750) -> "0,750"
+func (q Quantity) String() string {
+	return strings.Replace(q.encode(), ".", ",", 1)
+}
+
+// encode returns the quantity as sent to the printer before the multiplier, whole quantities without decimals.
+// Ex. Quantity(2000) -> "2", Quantity(750) -> "0.750"
+func (q Quantity) encode() string {
+	sign := ""
+	thousandths := int64(q)
+	if thousandths < 0 {
+		sign = "-"
+		thousandths = -thousandths
+	}
+	integer := strconv.FormatInt(thousandths/int64(quantityUnit), 10)
+	if q%quantityUnit == 0 {
+		return sign + integer
+	}
+	return fmt.Sprintf("%s%s.%03d", sign, integer, thousandths%int64(quantityUnit))
+}
+
+// MarshalJSON encodes the quantity as a JSON number of pieces, ex. 2 or 0.750.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.encode()), nil
+}
+
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	quantity, err := decodeQuantity(string(data))
+	if err != nil {
+		return err
+	}
+	*q = quantity
+	return nil
+}
+
+// MarshalYAML encodes the quantity as a YAML number of pieces, ex. 2 or 0.750.
+func (q Quantity) MarshalYAML() (interface{}, error) {
+	tag := "!!float"
+	if q%quantityUnit == 0 {
+		tag = "!!int"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: q.encode()}, nil
+}
+
+func (q *Quantity) UnmarshalYAML(value *yaml.Node) error {
+	quantity, err := decodeQuantity(value.Value)
+	if err != nil {
+		return err
+	}
+	*q = quantity
+	return nil
+}
+
+// MultiplyQuantity returns the amount of the given quantity, rounded to the cent like the printer does (half away from zero).
+// Ex. Amount(1890).MultiplyQuantity(Quantity(750)) -> Amount(1418)
+func (a Amount) MultiplyQuantity(quantity Quantity) Amount {
+	return Amount(divideRound(int64(a)*int64(quantity), int64(quantityUnit)))
+}
@@ -0,0 +1,128 @@
+package gongoff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseQuantity(t *testing.T) {
+
+	valid := map[string]Quantity{
+		"2":     2000,
+		"0,750": 750,
+		"0.750": 750,
+		"1.5":   1500,
+		" 3 ":   3000,
+		"0.001": 1,
+	}
+	for s, expected := range valid {
+		quantity, err := ParseQuantity(s)
+		if err != nil {
+			t.Errorf("Expected error = nil parsing %s, got %s", s, err)
+		}
+		if quantity != expected {
+			t.Errorf("Expected %d parsing %s, got %d", expected, s, quantity)
+		}
+	}
+
+	for _, s := range []string{"", "abc", ".5", "1.", "-1", "1.2.3", "1,2,3"} {
+		_, err := ParseQuantity(s)
+		if !errors.Is(err, ErrInvalidData) {
+			t.Errorf("Expected ErrInvalidData parsing %q, got %v", s, err)
+		}
+	}
+	_, err := ParseQuantity("0.7505")
+	if !errors.Is(err, ErrQuantityPrecision) {
+		t.Errorf("Expected ErrQuantityPrecision, got %v", err)
+	}
+	if quantity, err := ParseQuantity("9999,999"); err != nil || quantity != 9999999 {
+		t.Errorf("Expected 9999999, got %d, %v", quantity, err)
+	}
+	for _, s := range []string{"10000", "12345.5", "99999999999999999999"} {
+		_, err = ParseQuantity(s)
+		if !errors.Is(err, ErrQuantityTooLarge) {
+			t.Errorf("Expected ErrQuantityTooLarge parsing %q, got %v", s, err)
+		}
+	}
+
+	fmt.Println("Completed testParseQuantity")
+}
+
+func TestQuantityString(t *testing.T) {
+
+	formatted := map[Quantity]string{
+		2000:  "2",
+		750:   "0,750",
+		1500:  "1,500",
+		12005: "12,005",
+	}
+	for quantity, expected := range formatted {
+		if quantity.String() != expected {
+			t.Errorf("Expected %s, got %s", expected, quantity.String())
+		}
+	}
+	if NewQuantity(3) != 3000 {
+		t.Errorf("Expected 3000, got %d", NewQuantity(3))
+	}
+
+	fmt.Println("Completed testQuantityString")
+}
+
+func TestAmountMultiplyQuantity(t *testing.T) {
+
+	if got := Amount(1890).MultiplyQuantity(750); got != 1418 {
+		t.Errorf("Expected 1418, got %d", got)
+	}
+	if got := Amount(750).MultiplyQuantity(NewQuantity(3)); got != 2250 {
+		t.Errorf("Expected 2250, got %d", got)
+	}
+	if got := Amount(999).MultiplyQuantity(1); got != 1 {
+		t.Errorf("Expected 1, got %d", got)
+	}
+	if got := Amount(-1890).MultiplyQuantity(750); got != -1418 {
+		t.Errorf("Expected -1418, got %d", got)
+	}
+
+	fmt.Println("Completed testAmountMultiplyQuantity")
+}
+
+func TestQuantityEncoding(t *testing.T) {
+
+	for _, quantity := range []Quantity{750, 2000, 1500} {
+		encoded, err := json.Marshal(quantity)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		var decoded Quantity
+		err = json.Unmarshal(encoded, &decoded)
+		if err != nil || decoded != quantity {
+			t.Errorf("Expected %d from JSON %s, got %d, %v", quantity, encoded, decoded, err)
+		}
+
+		encoded, err = yaml.Marshal(quantity)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		decoded = 0
+		err = yaml.Unmarshal(encoded, &decoded)
+		if err != nil || decoded != quantity {
+			t.Errorf("Expected %d from YAML %s, got %d, %v", quantity, encoded, decoded, err)
+		}
+	}
+
+	encoded, _ := json.Marshal(Quantity(750))
+	if string(encoded) != "0.750" {
+		t.Errorf("Expected 0.750, got %s", encoded)
+	}
+	var decoded Quantity
+	err := json.Unmarshal([]byte("0.7505"), &decoded)
+	if !errors.Is(err, ErrQuantityPrecision) {
+		t.Errorf("Expected ErrQuantityPrecision, got %v", err)
+	}
+
+	fmt.Println("Completed testQuantityEncoding")
+}
@@ -0,0 +1,360 @@
+package gongoff
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ReceiptSession sends a commercial document to the printer one command at a time, ex. while the cashier scans the items,
+// so the printer and the customer display follow the sale instead of waiting for the payment.
+// Each command is checked against the commands already accepted before being sent, the running totals are recomputed
+// on the client side like Totals does. The document is closed by the payments covering the total, or cancelled by Abort.
+//
+// Calls are sent as separate commands, other documents sent to the same printer while the receipt is open are refused
+// by the printer: use a printer, or a PrinterPool till, dedicated to the session.
+//
+// A command failing after it may have reached the printer, ex. because the connection was lost while waiting for
+// the reply, leaves the session uncertain: the totals and the item indexes may no longer match the printer, so every
+// further command is refused with ErrReceiptUncertain. Check the printer, then call Abort to cancel the receipt.
+// ReceiptSession is safe for concurrent use.
+type ReceiptSession struct {
+	printer Printer
+
+	mu        sync.Mutex
+	commands  []Command
+	closed    bool
+	uncertain bool
+}
+
+// NewReceiptSession starts a receipt on printer, which must be open. Nothing is sent until the first item.
+func NewReceiptSession(printer Printer) *ReceiptSession {
+	return &ReceiptSession{printer: printer}
+}
+
+// AddItem sells a product once in the given department.
+func (s *ReceiptSession) AddItem(description string, unitPrice Amount, department int) error {
+	if department <= 0 {
+		return ErrInvalidDepartment
+	}
+	return s.Send(NewCommandProduct(unitPrice, &description, nil, &department))
+}
+
+// AddItemQty sells a product quantity times in the given department.
+func (s *ReceiptSession) AddItemQty(description string, unitPrice Amount, quantity int, department int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if department <= 0 {
+		return ErrInvalidDepartment
+	}
+	return s.Send(NewCommandProduct(unitPrice, &description, &quantity, &department))
+}
+
+// AddItemWeight sells a product by weight, or by any fractional quantity, at unitPrice per unit.
+func (s *ReceiptSession) AddItemWeight(description string, unitPrice Amount, weight Quantity, department int) error {
+	if department <= 0 {
+		return ErrInvalidDepartment
+	}
+	command, err := NewCommandProductQuantity(unitPrice, &description, weight, &department)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// AddPLU sells a PLU programmed in the printer quantity times at its programmed price.
+// The totals are unknown until the end of the receipt, see Totals.
+func (s *ReceiptSession) AddPLU(plu int, quantity int) error {
+	return s.addPLU(plu, quantity, nil)
+}
+
+// AddPLUPrice sells a PLU programmed in the printer quantity times at the given price.
+func (s *ReceiptSession) AddPLUPrice(plu int, quantity int, unitPrice Amount) error {
+	return s.addPLU(plu, quantity, &unitPrice)
+}
+
+func (s *ReceiptSession) addPLU(plu int, quantity int, unitPrice *Amount) error {
+	var pluQuantity *int
+	if quantity != 1 {
+		pluQuantity = &quantity
+	}
+	command, err := NewCommandProductPLU(plu, pluQuantity, unitPrice)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// AddPLUWeight sells a PLU programmed in the printer by weight at its programmed price per unit.
+func (s *ReceiptSession) AddPLUWeight(plu int, weight Quantity) error {
+	command, err := NewCommandProductPLUQuantity(plu, weight, nil)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// DiscountDepartment subtracts a fixed value from the sales of a department, ex. returned bottle deposits.
+func (s *ReceiptSession) DiscountDepartment(description string, discount Amount, department int) error {
+	command, err := NewCommandDiscountDepartment(discount, &description, department)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// DiscountPercent applies a percentage discount to the last item.
+func (s *ReceiptSession) DiscountPercent(percentage float64) error {
+	command, err := NewCommandDiscountPercentage(percentage)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// DiscountAmount applies a fixed value discount to the last item.
+func (s *ReceiptSession) DiscountAmount(discount Amount) error {
+	command, err := NewCommandDiscountAmount(discount)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// Surcharge applies a fixed value increase to the last item.
+func (s *ReceiptSession) Surcharge(increase Amount) error {
+	command, err := NewCommandIncreaseAmount(increase)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// SurchargePercent applies a percentage increase to the last item.
+func (s *ReceiptSession) SurchargePercent(percentage float64) error {
+	command, err := NewCommandIncreasePercentage(percentage)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// VoidLastItem voids the last item together with its discounts and increases.
+func (s *ReceiptSession) VoidLastItem() error {
+	return s.Send(NewCommandVoidItem(nil))
+}
+
+// VoidItem voids the item sold by the index-th call to AddItem, AddItemQty or AddItemWeight, counting from 0.
+func (s *ReceiptSession) VoidItem(index int) error {
+	s.mu.Lock()
+	product, ok := nthProduct(s.commands, index)
+	s.mu.Unlock()
+	if !ok {
+		return ErrVoidWithoutItem
+	}
+	return s.Send(NewCommandVoidItem(product))
+}
+
+// Subtotal prints the subtotal, also shown on the customer display, and returns the running totals.
+func (s *ReceiptSession) Subtotal() (*Totals, error) {
+	err := s.Send(NewCommandSubtotal())
+	if err != nil {
+		return nil, err
+	}
+	return s.Totals()
+}
+
+// SubtotalDiscountPercent applies a percentage discount to the subtotal, printing the subtotal first if needed.
+func (s *ReceiptSession) SubtotalDiscountPercent(percentage float64) error {
+	command, err := NewCommandDiscountPercentageSubtotal(percentage)
+	if err != nil {
+		return err
+	}
+	return s.adjustSubtotal(command)
+}
+
+// SubtotalDiscountAmount applies a fixed value discount to the subtotal, printing the subtotal first if needed.
+func (s *ReceiptSession) SubtotalDiscountAmount(discount Amount) error {
+	command, err := NewCommandDiscountAmountSubtotal(discount)
+	if err != nil {
+		return err
+	}
+	return s.adjustSubtotal(command)
+}
+
+// SubtotalSurchargePercent applies a percentage increase to the subtotal, printing the subtotal first if needed.
+func (s *ReceiptSession) SubtotalSurchargePercent(percentage float64) error {
+	command, err := NewCommandIncreasePercentageSubtotal(percentage)
+	if err != nil {
+		return err
+	}
+	return s.adjustSubtotal(command)
+}
+
+// SubtotalSurcharge applies a fixed value increase to the subtotal, printing the subtotal first if needed.
+func (s *ReceiptSession) SubtotalSurcharge(increase Amount) error {
+	command, err := NewCommandIncreaseAmountSubtotal(increase)
+	if err != nil {
+		return err
+	}
+	return s.adjustSubtotal(command)
+}
+
+func (s *ReceiptSession) adjustSubtotal(command Command) error {
+	s.mu.Lock()
+	printed := false
+	if len(s.commands) > 0 {
+		switch s.commands[len(s.commands)-1].(type) {
+		case *CommandSubtotal, *CommandDiscountPercentageSubtotal, *CommandDiscountAmountSubtotal,
+			*CommandIncreasePercentageSubtotal, *CommandIncreaseAmountSubtotal:
+			printed = true
+		}
+	}
+	s.mu.Unlock()
+	if !printed {
+		err := s.Send(NewCommandSubtotal())
+		if err != nil {
+			return err
+		}
+	}
+	return s.Send(command)
+}
+
+// Pay registers a payment of the given amount, the receipt is closed when the payments cover the total.
+func (s *ReceiptSession) Pay(method TerminatorType, amount Amount) error {
+	if err := checkAmount("payment", amount); err != nil {
+		return err
+	}
+	command, err := NewCommandPayment(method, &amount, nil)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// PayRest pays the amount still due with the given method, closing the receipt.
+func (s *ReceiptSession) PayRest(method TerminatorType) error {
+	command, err := NewCommandPayment(method, nil, nil)
+	if err != nil {
+		return err
+	}
+	return s.Send(command)
+}
+
+// Send sends any command of the receipt, ex. a customer identifier or a trailer before the payments.
+func (s *ReceiptSession) Send(command Command) error {
+	return s.SendContext(context.Background(), command)
+}
+
+// SendContext is Send with a context to set a deadline or cancel the command.
+// The command is not sent if the printer would refuse it in the current state of the receipt.
+func (s *ReceiptSession) SendContext(ctx context.Context, command Command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrReceiptClosed
+	}
+	if s.uncertain {
+		return ErrReceiptUncertain
+	}
+	commands := append(s.commands[:len(s.commands):len(s.commands)], command)
+	r, err := replayReceipt(commands)
+	if err != nil {
+		return err
+	}
+	if _, ok := command.(*CommandPayment); ok && len(r.lines) == 0 {
+		return ErrMissingProducts
+	}
+
+	err = PrintCommandsContext(ctx, s.printer, []Command{command})
+	if err != nil {
+		s.uncertain = !unprinted(err)
+		return err
+	}
+	s.commands = commands
+
+	if payment, ok := command.(*CommandPayment); ok {
+		s.closed = s.documentClosed(ctx, r, payment)
+	}
+	return nil
+}
+
+// documentClosed reports whether the payments closed the document, a payment without amount always does.
+// The printer status is asked when the total is unknown because of PLUs sold at their programmed price:
+// if the printer cannot tell, ex. without replies, the receipt stays open until PayRest or Abort.
+func (s *ReceiptSession) documentClosed(ctx context.Context, r *receipt, payment *CommandPayment) bool {
+	if payment.amount == nil {
+		return true
+	}
+	if !r.unknownPrice {
+		return r.paid >= r.total()
+	}
+	status, err := StatusContext(ctx, s.printer)
+	return err == nil && !status.DocumentOpen()
+}
+
+// Abort cancels the receipt, the registered sales are voided by the printer.
+// Nothing is sent if no command was sent yet, unless the session is uncertain.
+// The session is closed even if the printer refuses the cancellation, ex. because the receipt was never opened
+// by an uncertain command: the error is returned so that the printer can be checked.
+func (s *ReceiptSession) Abort() error {
+	return s.AbortContext(context.Background())
+}
+
+// AbortContext is Abort with a context to set a deadline or cancel the command.
+func (s *ReceiptSession) AbortContext(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrReceiptClosed
+	}
+	if len(s.commands) > 0 || s.uncertain {
+		err := PrintCommandsContext(ctx, s.printer, []Command{NewCommandCancelDocument()})
+		var printerErr *PrinterError
+		if err != nil && !errors.As(err, &printerErr) {
+			return err
+		}
+		s.close()
+		return err
+	}
+	s.close()
+	return nil
+}
+
+func (s *ReceiptSession) close() {
+	s.closed = true
+	s.uncertain = false
+	s.commands = nil
+}
+
+// Totals returns the running totals of the receipt, ErrProgrammedPLU if PLUs are sold at their programmed price.
+func (s *ReceiptSession) Totals() (*Totals, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: s.commands}}
+	return doc.Totals()
+}
+
+// Uncertain reports whether a command failed after it may have reached the printer, see ErrReceiptUncertain.
+func (s *ReceiptSession) Uncertain() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uncertain
+}
+
+// Closed reports whether the receipt was closed by the payments or aborted.
+func (s *ReceiptSession) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Commands returns the commands accepted by the printer, the document printed so far.
+func (s *ReceiptSession) Commands() []Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Command(nil), s.commands...)
+}
@@ -0,0 +1,259 @@
+package gongoff_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/paolo96/gongoff"
+	"github.com/paolo96/gongoff/gongofftest"
+)
+
+// lostReplyPrinter prints the commands, then fails with io.ErrUnexpectedEOF while lost is true,
+// like a connection lost while waiting for the reply. It only implements the Printer interface.
+type lostReplyPrinter struct {
+	gongoff.Printer
+	lost bool
+}
+
+func (p *lostReplyPrinter) PrintCommands(commands []gongoff.Command) error {
+	err := p.Printer.PrintCommands(commands)
+	if err == nil && p.lost {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func TestReceiptSession(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := emulator.Printer()
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	session := gongoff.NewReceiptSession(printer)
+	err = session.AddItem("BREAD", 750, 1)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.Document != gongofftest.DocumentCommercial || state.Total != 750 {
+		t.Errorf("Expected the item to be registered as soon as it is scanned, got %s", state)
+	}
+	err = session.AddItemQty("MILK", 120, 2, 2)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = session.AddItemWeight("CHEESE", 1890, 750, 0)
+	if !errors.Is(err, gongoff.ErrInvalidDepartment) {
+		t.Errorf("Expected ErrInvalidDepartment, got %v", err)
+	}
+	err = session.AddItem("WINE", 1200, 2)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = session.VoidItem(1)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = session.DiscountPercent(10)
+	if !errors.Is(err, gongoff.ErrInvalidDocumentOrder) {
+		t.Errorf("Expected ErrInvalidDocumentOrder after a void, got %v", err)
+	}
+	err = session.SubtotalDiscountAmount(150)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	totals, err := session.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if totals.Total != 1800 {
+		t.Errorf("Expected running total 18,00, got %s", totals.Total)
+	}
+	if state := emulator.State(); state.Total != totals.Total {
+		t.Errorf("Expected printer total %s, got %s", totals.Total, state.Total)
+	}
+
+	err = session.Pay(gongoff.TerminatorTypePaymentCash, 1000)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if session.Closed() {
+		t.Errorf("Expected the session to stay open until the total is paid")
+	}
+	err = session.PayRest(gongoff.TerminatorTypePaymentCards)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if !session.Closed() {
+		t.Errorf("Expected the session to be closed by the payments")
+	}
+	if state := emulator.State(); state.Document != gongofftest.DocumentNone || state.DailyTotal != 1800 {
+		t.Errorf("Expected closed receipt of 18,00, got %s", state)
+	}
+	err = session.AddItem("BREAD", 750, 1)
+	if !errors.Is(err, gongoff.ErrReceiptClosed) {
+		t.Errorf("Expected ErrReceiptClosed, got %v", err)
+	}
+
+	fmt.Println("Completed testReceiptSession")
+}
+
+func TestReceiptSessionAbort(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := emulator.Printer()
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	session := gongoff.NewReceiptSession(printer)
+	err = session.AddItem("BREAD", 750, 1)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = session.Abort()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.Document != gongofftest.DocumentNone || state.DailyTotal != 0 {
+		t.Errorf("Expected cancelled receipt, got %s", state)
+	}
+	received := emulator.Received()
+	if len(received) != 2 || received[1] != "k" {
+		t.Errorf("Expected the document to be cancelled, got %v", received)
+	}
+	err = session.Abort()
+	if !errors.Is(err, gongoff.ErrReceiptClosed) {
+		t.Errorf("Expected ErrReceiptClosed, got %v", err)
+	}
+
+	// Nothing was sent, there is nothing to cancel.
+	err = gongoff.NewReceiptSession(printer).Abort()
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if received := emulator.Received(); len(received) != 2 {
+		t.Errorf("Expected no command sent, got %v", received)
+	}
+
+	fmt.Println("Completed testReceiptSessionAbort")
+}
+
+func TestReceiptSessionUncertain(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	printer := &lostReplyPrinter{Printer: emulator.Printer()}
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	session := gongoff.NewReceiptSession(printer)
+	err = session.AddItem("BREAD", 750, 1)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	printer.lost = true
+	err = session.AddItem("MILK", 120, 1)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !session.Uncertain() {
+		t.Fatalf("Expected uncertain session after a lost reply, got %v", err)
+	}
+	printer.lost = false
+
+	// The printer registered the item the session does not know about, no further command is sent.
+	err = session.VoidLastItem()
+	if !errors.Is(err, gongoff.ErrReceiptUncertain) {
+		t.Errorf("Expected ErrReceiptUncertain, got %v", err)
+	}
+	if state := emulator.State(); state.Total != 870 || len(emulator.Received()) != 2 {
+		t.Errorf("Expected both items registered and nothing else sent, got %s", state)
+	}
+
+	err = session.Abort()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if state := emulator.State(); state.Document != gongofftest.DocumentNone || state.DailyTotal != 0 {
+		t.Errorf("Expected cancelled receipt, got %s", state)
+	}
+	if session.Uncertain() || !session.Closed() {
+		t.Errorf("Expected closed session after Abort")
+	}
+
+	fmt.Println("Completed testReceiptSessionUncertain")
+}
+
+func TestReceiptSessionConnectionLost(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	options := gongoff.NetworkOptions{ReconnectDelay: 5 * time.Millisecond, MaxReconnectAttempts: 1}
+	printer := gongoff.NewNetworkPrinterWithOptions(emulator.Host(), emulator.Port(), options)
+	printer.SetReplies(true)
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	session := gongoff.NewReceiptSession(printer)
+	err = session.AddItem("BREAD", 750, 1)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	// The printer is gone before the next command, which is not written.
+	emulator.Close()
+	time.Sleep(20 * time.Millisecond)
+	err = session.AddItem("MILK", 120, 1)
+	if !errors.Is(err, gongoff.ErrConnectionLost) || session.Uncertain() {
+		t.Errorf("Expected ErrConnectionLost without uncertainty, got %v", err)
+	}
+
+	fmt.Println("Completed testReceiptSessionConnectionLost")
+}
+
+func TestReceiptSessionWithoutReplies(t *testing.T) {
+
+	emulator := gongofftest.NewEmulator()
+	defer emulator.Close()
+	emulator.Update(func(state *gongofftest.State) {
+		state.PLUs = map[int]gongofftest.PLU{1: {Description: "WINE", UnitPrice: 1200, Department: 1}}
+	})
+	printer := emulator.Printer()
+	printer.SetReplies(false)
+	err := printer.Open()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	defer printer.Close()
+
+	// The total of a PLU at its programmed price is unknown and the printer cannot tell its status.
+	session := gongoff.NewReceiptSession(printer)
+	err = session.AddPLU(1, 1)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	err = session.Pay(gongoff.TerminatorTypePaymentCash, 500)
+	if err != nil || session.Closed() {
+		t.Fatalf("Expected open session after a partial payment, got %v", err)
+	}
+	err = session.PayRest(gongoff.TerminatorTypePaymentCards)
+	if err != nil || !session.Closed() {
+		t.Errorf("Expected the rest of the payment to close the session, got %v", err)
+	}
+
+	fmt.Println("Completed testReceiptSessionWithoutReplies")
+}
@@ -0,0 +1,63 @@
+package gongoff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Response anatomy:
+// With replies enabled (see GenericPrinter.SetReplies) every command is answered with a single line terminated
+// by CR and/or LF. The format is the one of gongofftest.Emulator and of gateways acknowledging the commands,
+// it is not defined by the Epson Xon-Xoff protocol.
+// OK[ message]          -> the command was accepted, message is optional.
+// ERR code[ message]    -> the command was refused with the given error code.
+
+const (
+	responseOK    = "OK"
+	responseError = "ERR"
+)
+
+// Response is the decoded reply sent by the printer after a command.
+type Response struct {
+	Success bool
+	Code    ErrorCode
+	Message string
+}
+
+// decodeResponse parses a single response line, without its line terminator.
+func decodeResponse(line string) (*Response, error) {
+	line = strings.TrimSpace(line)
+	fields := strings.SplitN(line, " ", 2)
+	message := ""
+	if len(fields) == 2 {
+		message = strings.TrimSpace(fields[1])
+	}
+
+	switch fields[0] {
+	case responseOK:
+		return &Response{Success: true, Message: message}, nil
+	case responseError:
+		codeFields := strings.SplitN(message, " ", 2)
+		code, err := strconv.Atoi(codeFields[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed printer response %q", line)
+		}
+		message = ""
+		if len(codeFields) == 2 {
+			message = strings.TrimSpace(codeFields[1])
+		}
+		return &Response{Success: false, Code: ErrorCode(code), Message: message}, nil
+	default:
+		return nil, fmt.Errorf("malformed printer response %q", line)
+	}
+}
+
+// err returns the error described by the response, nil if the command was accepted.
+// command is the command the response refers to.
+func (r *Response) err(command Command) error {
+	if r.Success {
+		return nil
+	}
+	return newPrinterError(r.Code, r.Message, command)
+}
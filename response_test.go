@@ -0,0 +1,41 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDecodeResponse(t *testing.T) {
+
+	response, err := decodeResponse("OK")
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if !response.Success || response.err(nil) != nil {
+		t.Errorf("Expected successful response, got %+v", response)
+	}
+
+	response, err = decodeResponse("ERR 8 PAPER END")
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if response.Success || response.Code != ErrorCode(8) || response.Message != "PAPER END" {
+		t.Errorf("Expected ERR 8 PAPER END, got %+v", response)
+	}
+	var printerError *PrinterError
+	if !errors.As(response.err(nil), &printerError) || printerError.Code != ErrorCode(8) {
+		t.Errorf("Expected *PrinterError with code 8, got %v", response.err(nil))
+	}
+
+	_, err = decodeResponse("ERR PAPER")
+	if err == nil {
+		t.Errorf("Expected error != nil, got nil")
+	}
+	_, err = decodeResponse("HELLO")
+	if err == nil {
+		t.Errorf("Expected error != nil, got nil")
+	}
+
+	fmt.Println("Completed testDecodeResponse")
+}
@@ -0,0 +1,888 @@
+package gongoff
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema anatomy:
+// Documents are serialized as a versioned object with a type and the list of its commands,
+// commands are serialized as an object with a type and the fields used by that type.
+// Amounts are integers in euro cents, dates are formatted as "2006-01-02" and date times as "2006-01-02T15:04" in local time.
+//
+// {
+//   "version": 1,
+//   "type": "commercial",
+//   "commands": [
+//     {"type": "product", "description": "BREAD", "quantity": 2, "unitPrice": 750, "department": 1},
+//     {"type": "discountAmount", "amount": 100},
+//     {"type": "payment", "method": "1T"}
+//   ]
+// }
+//
+// Management documents use "rows" instead of "commands".
+// Commercial documents with invoice use "commands" for the invoice commands and "commercial" for the commercial document.
+
+// SchemaVersion is the version of the document schema written by MarshalDocument and MarshalDocumentYAML.
+const SchemaVersion = 1
+
+const (
+	schemaDateLayout     = "2006-01-02"
+	schemaDateTimeLayout = "2006-01-02T15:04"
+)
+
+// DocumentType identifies the kind of document in the schema.
+type DocumentType string
+
+const (
+	DocumentTypeCommercial             DocumentType = "commercial"
+	DocumentTypeManagement             DocumentType = "management"
+	DocumentTypeCommercialReturn       DocumentType = "commercialReturn"
+	DocumentTypeCommercialCancellation DocumentType = "commercialCancellation"
+	DocumentTypePOSReturn              DocumentType = "posReturn"
+	DocumentTypePOSCancellation        DocumentType = "posCancellation"
+	DocumentTypeInvoice                DocumentType = "invoice"
+	DocumentTypeCommercialWithInvoice  DocumentType = "commercialWithInvoice"
+)
+
+// CommandType identifies the kind of command in the schema.
+type CommandType string
+
+const (
+	// CommandTypeProduct uses description, quantity (up to three decimals, ex. 0.750 kg), unitPrice and department.
+	CommandTypeProduct CommandType = "product"
+	// CommandTypeVoidItem uses description, quantity and unitPrice of the voided product, the last item is voided if none is given.
+	CommandTypeVoidItem CommandType = "voidItem"
+	// CommandTypeProductPLU uses plu, quantity and unitPrice, a zero unitPrice sells the PLU at its programmed price.
+	CommandTypeProductPLU CommandType = "productPLU"
+	// CommandTypeDiscountDepartment uses amount, description and department.
+	CommandTypeDiscountDepartment CommandType = "discountDepartment"
+	// CommandTypeDiscountPercentage uses percentage.
+	CommandTypeDiscountPercentage CommandType = "discountPercentage"
+	// CommandTypeDiscountAmount uses amount.
+	CommandTypeDiscountAmount CommandType = "discountAmount"
+	// CommandTypeIncreaseAmount uses amount.
+	CommandTypeIncreaseAmount CommandType = "increaseAmount"
+	// CommandTypeIncreasePercentage uses percentage.
+	CommandTypeIncreasePercentage CommandType = "increasePercentage"
+	// CommandTypeSubtotal has no fields.
+	CommandTypeSubtotal CommandType = "subtotal"
+	// CommandTypeDiscountPercentageSubtotal uses percentage.
+	CommandTypeDiscountPercentageSubtotal CommandType = "discountPercentageSubtotal"
+	// CommandTypeDiscountAmountSubtotal uses amount.
+	CommandTypeDiscountAmountSubtotal CommandType = "discountAmountSubtotal"
+	// CommandTypeIncreasePercentageSubtotal uses percentage.
+	CommandTypeIncreasePercentageSubtotal CommandType = "increasePercentageSubtotal"
+	// CommandTypeIncreaseAmountSubtotal uses amount.
+	CommandTypeIncreaseAmountSubtotal CommandType = "increaseAmountSubtotal"
+	// CommandTypePayment uses method, amount and description.
+	CommandTypePayment CommandType = "payment"
+	// CommandTypeCustomerIdentifier uses text.
+	CommandTypeCustomerIdentifier CommandType = "customerIdentifier"
+	// CommandTypeTrailer uses text.
+	CommandTypeTrailer CommandType = "trailer"
+	// CommandTypeBarcode uses text.
+	CommandTypeBarcode CommandType = "barcode"
+	// CommandTypeOpenCommercialReturn uses documentId.
+	CommandTypeOpenCommercialReturn CommandType = "openCommercialReturn"
+	// CommandTypeOpenCommercialCancellation uses documentId.
+	CommandTypeOpenCommercialCancellation CommandType = "openCommercialCancellation"
+	// CommandTypeOpenPOSReturn uses date.
+	CommandTypeOpenPOSReturn CommandType = "openPOSReturn"
+	// CommandTypeOpenPOSCancellation uses date.
+	CommandTypeOpenPOSCancellation CommandType = "openPOSCancellation"
+	// CommandTypeOpenInvoice uses invoiceNumber.
+	CommandTypeOpenInvoice CommandType = "openInvoice"
+	// CommandTypeOpenInvoiceCommercialDocument uses invoiceNumber.
+	CommandTypeOpenInvoiceCommercialDocument CommandType = "openInvoiceCommercialDocument"
+	// CommandTypeInvoiceDetails uses text.
+	CommandTypeInvoiceDetails CommandType = "invoiceDetails"
+	// CommandTypeDisplayMessage uses text and line.
+	CommandTypeDisplayMessage CommandType = "displayMessage"
+	// CommandTypeFinancialReport uses closure.
+	CommandTypeFinancialReport CommandType = "financialReport"
+	// CommandTypeOpenCashDrawer has no fields.
+	CommandTypeOpenCashDrawer CommandType = "openCashDrawer"
+	// CommandTypeSetDateTime uses dateTime.
+	CommandTypeSetDateTime CommandType = "setDateTime"
+	// CommandTypeCancelDocument has no fields.
+	CommandTypeCancelDocument CommandType = "cancelDocument"
+	// CommandTypeStatusRequest has no fields.
+	CommandTypeStatusRequest CommandType = "statusRequest"
+	// CommandTypeGeneric uses data and terminator.
+	CommandTypeGeneric CommandType = "generic"
+	// CommandTypeRaw uses raw, the Xon-Xoff string of the command. Commands defined outside this package are serialized as raw.
+	CommandTypeRaw CommandType = "raw"
+)
+
+// DocumentSchema is the serialized form of a document.
+type DocumentSchema struct {
+	Version    int             `json:"version" yaml:"version"`
+	Type       DocumentType    `json:"type" yaml:"type"`
+	Rows       []string        `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Commands   []CommandSchema `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Commercial *DocumentSchema `json:"commercial,omitempty" yaml:"commercial,omitempty"`
+}
+
+// CommandSchema is the serialized form of a command, Type selects the fields in use.
+type CommandSchema struct {
+	Type          CommandType       `json:"type" yaml:"type"`
+	Description   *string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Quantity      *Quantity         `json:"quantity,omitempty" yaml:"quantity,omitempty"`
+	UnitPrice     Amount            `json:"unitPrice,omitempty" yaml:"unitPrice,omitempty"`
+	Department    *int              `json:"department,omitempty" yaml:"department,omitempty"`
+	PLU           int               `json:"plu,omitempty" yaml:"plu,omitempty"`
+	Amount        *Amount           `json:"amount,omitempty" yaml:"amount,omitempty"`
+	Percentage    float64           `json:"percentage,omitempty" yaml:"percentage,omitempty"`
+	Method        TerminatorType    `json:"method,omitempty" yaml:"method,omitempty"`
+	Text          string            `json:"text,omitempty" yaml:"text,omitempty"`
+	Line          int               `json:"line,omitempty" yaml:"line,omitempty"`
+	DocumentId    DocumentId        `json:"documentId,omitempty" yaml:"documentId,omitempty"`
+	Date          string            `json:"date,omitempty" yaml:"date,omitempty"`
+	InvoiceNumber *int              `json:"invoiceNumber,omitempty" yaml:"invoiceNumber,omitempty"`
+	Closure       bool              `json:"closure,omitempty" yaml:"closure,omitempty"`
+	DateTime      string            `json:"dateTime,omitempty" yaml:"dateTime,omitempty"`
+	Data          []DataSchema      `json:"data,omitempty" yaml:"data,omitempty"`
+	Terminator    *TerminatorSchema `json:"terminator,omitempty" yaml:"terminator,omitempty"`
+	Raw           string            `json:"raw,omitempty" yaml:"raw,omitempty"`
+}
+
+// DataSchema is the serialized form of a Data of a generic command.
+type DataSchema struct {
+	Variable  string        `json:"variable" yaml:"variable"`
+	Separator SeparatorType `json:"separator" yaml:"separator"`
+}
+
+// TerminatorSchema is the serialized form of the Terminator of a generic command.
+type TerminatorSchema struct {
+	Variable *string        `json:"variable,omitempty" yaml:"variable,omitempty"`
+	Type     TerminatorType `json:"type" yaml:"type"`
+}
+
+// NewCommandSchema returns the serialized form of command.
+func NewCommandSchema(command Command) (*CommandSchema, error) {
+	switch c := command.(type) {
+	case *CommandProduct:
+		return &CommandSchema{Type: CommandTypeProduct, Description: c.product, Quantity: c.quantity, UnitPrice: c.unitPrice, Department: c.department}, nil
+	case *CommandVoidItem:
+		schema := &CommandSchema{Type: CommandTypeVoidItem}
+		if c.product != nil {
+			schema.Description = c.product.product
+			schema.Quantity = c.product.quantity
+			schema.UnitPrice = c.product.unitPrice
+		}
+		return schema, nil
+	case *CommandProductPLU:
+		schema := &CommandSchema{Type: CommandTypeProductPLU, PLU: c.plu, Quantity: c.quantity}
+		if c.unitPrice != nil {
+			schema.UnitPrice = *c.unitPrice
+		}
+		return schema, nil
+	case *CommandDiscountDepartment:
+		amount := c.discountAmount
+		department := c.department
+		return &CommandSchema{Type: CommandTypeDiscountDepartment, Amount: &amount, Description: c.description, Department: &department}, nil
+	case *CommandDiscountPercentage:
+		return &CommandSchema{Type: CommandTypeDiscountPercentage, Percentage: c.discountPercentage}, nil
+	case *CommandDiscountAmount:
+		amount := c.discountAmount
+		return &CommandSchema{Type: CommandTypeDiscountAmount, Amount: &amount}, nil
+	case *CommandIncreaseAmount:
+		amount := c.increaseAmount
+		return &CommandSchema{Type: CommandTypeIncreaseAmount, Amount: &amount}, nil
+	case *CommandIncreasePercentage:
+		return &CommandSchema{Type: CommandTypeIncreasePercentage, Percentage: c.increasePercentage}, nil
+	case *CommandSubtotal:
+		return &CommandSchema{Type: CommandTypeSubtotal}, nil
+	case *CommandDiscountPercentageSubtotal:
+		return &CommandSchema{Type: CommandTypeDiscountPercentageSubtotal, Percentage: c.discountPercentage}, nil
+	case *CommandDiscountAmountSubtotal:
+		amount := c.discountAmount
+		return &CommandSchema{Type: CommandTypeDiscountAmountSubtotal, Amount: &amount}, nil
+	case *CommandIncreasePercentageSubtotal:
+		return &CommandSchema{Type: CommandTypeIncreasePercentageSubtotal, Percentage: c.increasePercentage}, nil
+	case *CommandIncreaseAmountSubtotal:
+		amount := c.increaseAmount
+		return &CommandSchema{Type: CommandTypeIncreaseAmountSubtotal, Amount: &amount}, nil
+	case *CommandPayment:
+		schema := &CommandSchema{Type: CommandTypePayment, Method: c.paymentMethod, Amount: c.amount}
+		for _, d := range c.data {
+			if d.separator == SeparatorTypeDescription {
+				description := d.variable
+				schema.Description = &description
+			}
+		}
+		return schema, nil
+	case *CommandCustomerIdentifier:
+		return &CommandSchema{Type: CommandTypeCustomerIdentifier, Text: c.customerIdentifier}, nil
+	case *CommandTrailer:
+		return &CommandSchema{Type: CommandTypeTrailer, Text: c.trailer}, nil
+	case *CommandBarcode:
+		return &CommandSchema{Type: CommandTypeBarcode, Text: c.barcode}, nil
+	case *CommandOpenDocumentCommercialReturn:
+		return &CommandSchema{Type: CommandTypeOpenCommercialReturn, DocumentId: c.documentId}, nil
+	case *CommandOpenDocumentCommercialCancellation:
+		return &CommandSchema{Type: CommandTypeOpenCommercialCancellation, DocumentId: c.documentId}, nil
+	case *CommandOpenDocumentPOSReturn:
+		return &CommandSchema{Type: CommandTypeOpenPOSReturn, Date: c.date.Format(schemaDateLayout)}, nil
+	case *CommandOpenDocumentPOSCancellation:
+		return &CommandSchema{Type: CommandTypeOpenPOSCancellation, Date: c.date.Format(schemaDateLayout)}, nil
+	case *CommandOpenInvoice:
+		return &CommandSchema{Type: CommandTypeOpenInvoice, InvoiceNumber: c.invoiceNumber}, nil
+	case *CommandOpenInvoiceCommercialDocument:
+		return &CommandSchema{Type: CommandTypeOpenInvoiceCommercialDocument, InvoiceNumber: c.invoiceNumber}, nil
+	case *CommandInvoiceDetails:
+		return &CommandSchema{Type: CommandTypeInvoiceDetails, Text: c.details}, nil
+	case *CommandDisplayMessage:
+		return &CommandSchema{Type: CommandTypeDisplayMessage, Text: c.message, Line: c.line}, nil
+	case *CommandFinancialReport:
+		return &CommandSchema{Type: CommandTypeFinancialReport, Closure: c.closure}, nil
+	case *CommandOpenCashDrawer:
+		return &CommandSchema{Type: CommandTypeOpenCashDrawer}, nil
+	case *CommandSetDateTime:
+		return &CommandSchema{Type: CommandTypeSetDateTime, DateTime: c.dateTime.Format(schemaDateTimeLayout)}, nil
+	case *CommandCancelDocument:
+		return &CommandSchema{Type: CommandTypeCancelDocument}, nil
+	case *CommandStatusRequest:
+		return &CommandSchema{Type: CommandTypeStatusRequest}, nil
+	case *CommandGeneric:
+		schema := &CommandSchema{
+			Type:       CommandTypeGeneric,
+			Data:       []DataSchema{},
+			Terminator: &TerminatorSchema{Variable: c.terminator.variable, Type: c.terminator.terminatorType},
+		}
+		for _, d := range c.data {
+			schema.Data = append(schema.Data, DataSchema{Variable: d.variable, Separator: d.separator})
+		}
+		return schema, nil
+	default:
+		encoded, err := command.Encode()
+		if err != nil {
+			return nil, err
+		}
+		return &CommandSchema{Type: CommandTypeRaw, Raw: string(encoded)}, nil
+	}
+}
+
+// Command returns the command described by the schema.
+func (s *CommandSchema) Command() (Command, error) {
+	switch s.Type {
+	case CommandTypeProduct:
+		return newCommandProduct(s.UnitPrice, s.Description, s.Quantity, s.Department), nil
+	case CommandTypeVoidItem:
+		if s.Description == nil && s.Quantity == nil && s.UnitPrice == 0 {
+			return NewCommandVoidItem(nil), nil
+		}
+		return NewCommandVoidItem(newCommandProduct(s.UnitPrice, s.Description, s.Quantity, nil)), nil
+	case CommandTypeProductPLU:
+		var unitPrice *Amount
+		if s.UnitPrice != 0 {
+			unitPrice = &s.UnitPrice
+		}
+		return newCommandProductPLU(s.PLU, s.Quantity, unitPrice)
+	case CommandTypeDiscountDepartment:
+		amount, err := s.requiredAmount()
+		if err != nil {
+			return nil, err
+		}
+		if s.Department == nil {
+			return nil, fmt.Errorf("%w: %s command without department", ErrInvalidSchema, s.Type)
+		}
+		return NewCommandDiscountDepartment(amount, s.Description, *s.Department)
+	case CommandTypeDiscountPercentage:
+		return NewCommandDiscountPercentage(s.Percentage)
+	case CommandTypeDiscountAmount:
+		amount, err := s.requiredAmount()
+		if err != nil {
+			return nil, err
+		}
+		return NewCommandDiscountAmount(amount)
+	case CommandTypeIncreaseAmount:
+		amount, err := s.requiredAmount()
+		if err != nil {
+			return nil, err
+		}
+		return NewCommandIncreaseAmount(amount)
+	case CommandTypeIncreasePercentage:
+		return NewCommandIncreasePercentage(s.Percentage)
+	case CommandTypeSubtotal:
+		return NewCommandSubtotal(), nil
+	case CommandTypeDiscountPercentageSubtotal:
+		return NewCommandDiscountPercentageSubtotal(s.Percentage)
+	case CommandTypeDiscountAmountSubtotal:
+		amount, err := s.requiredAmount()
+		if err != nil {
+			return nil, err
+		}
+		return NewCommandDiscountAmountSubtotal(amount)
+	case CommandTypeIncreasePercentageSubtotal:
+		return NewCommandIncreasePercentageSubtotal(s.Percentage)
+	case CommandTypeIncreaseAmountSubtotal:
+		amount, err := s.requiredAmount()
+		if err != nil {
+			return nil, err
+		}
+		return NewCommandIncreaseAmountSubtotal(amount)
+	case CommandTypePayment:
+		return NewCommandPayment(s.Method, s.Amount, s.Description)
+	case CommandTypeCustomerIdentifier:
+		return NewCommandCustomerIdentifier(s.Text)
+	case CommandTypeTrailer:
+		return NewCommandTrailer(s.Text), nil
+	case CommandTypeBarcode:
+		return NewCommandBarcode(s.Text)
+	case CommandTypeOpenCommercialReturn:
+		return NewCommandOpenDocumentCommercialReturn(s.DocumentId), nil
+	case CommandTypeOpenCommercialCancellation:
+		return NewCommandOpenDocumentCommercialCancellation(s.DocumentId), nil
+	case CommandTypeOpenPOSReturn:
+		date, err := s.date()
+		if err != nil {
+			return nil, err
+		}
+		return NewCommandOpenDocumentPOSReturn(date), nil
+	case CommandTypeOpenPOSCancellation:
+		date, err := s.date()
+		if err != nil {
+			return nil, err
+		}
+		return NewCommandOpenDocumentPOSCancellation(date), nil
+	case CommandTypeOpenInvoice:
+		return NewCommandOpenInvoice(s.InvoiceNumber), nil
+	case CommandTypeOpenInvoiceCommercialDocument:
+		return NewCommandOpenInvoiceCommercialDocument(s.InvoiceNumber), nil
+	case CommandTypeInvoiceDetails:
+		return NewCommandInvoiceDetails(s.Text), nil
+	case CommandTypeDisplayMessage:
+		return NewCommandDisplayMessage(s.Text, s.Line), nil
+	case CommandTypeFinancialReport:
+		return NewCommandFinancialReport(s.Closure), nil
+	case CommandTypeOpenCashDrawer:
+		return NewCommandOpenCashDrawer(), nil
+	case CommandTypeSetDateTime:
+		dateTime, err := time.ParseInLocation(schemaDateTimeLayout, s.DateTime, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date time %q", ErrInvalidSchema, s.DateTime)
+		}
+		return NewCommandSetDateTime(dateTime), nil
+	case CommandTypeCancelDocument:
+		return NewCommandCancelDocument(), nil
+	case CommandTypeStatusRequest:
+		return NewCommandStatusRequest(), nil
+	case CommandTypeGeneric:
+		if s.Terminator == nil {
+			return nil, fmt.Errorf("%w: generic command without terminator", ErrInvalidSchema)
+		}
+		data := []Data{}
+		for _, d := range s.Data {
+			data = append(data, Data{variable: d.Variable, separator: d.Separator})
+		}
+		return NewCommandGeneric(data, Terminator{variable: s.Terminator.Variable, terminatorType: s.Terminator.Type}), nil
+	case CommandTypeRaw:
+		return ParseCommand(s.Raw)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownCommandType, s.Type)
+	}
+}
+
+func (s *CommandSchema) requiredAmount() (Amount, error) {
+	if s.Amount == nil {
+		return 0, fmt.Errorf("%w: %s command without amount", ErrInvalidSchema, s.Type)
+	}
+	return *s.Amount, nil
+}
+
+func (s *CommandSchema) date() (time.Time, error) {
+	date, err := time.Parse(schemaDateLayout, s.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidSchema, s.Date)
+	}
+	return date, nil
+}
+
+// NewDocumentSchema returns the serialized form of document.
+func NewDocumentSchema(document Document) (*DocumentSchema, error) {
+	schema := &DocumentSchema{Version: SchemaVersion}
+	switch d := document.(type) {
+	case *DocumentCommercial:
+		schema.Type = DocumentTypeCommercial
+	case *DocumentManagement:
+		schema.Type = DocumentTypeManagement
+		schema.Rows = append([]string{}, d.rows...)
+		return schema, nil
+	case *DocumentCommercialReturn:
+		schema.Type = DocumentTypeCommercialReturn
+	case *DocumentCommercialCancellation:
+		schema.Type = DocumentTypeCommercialCancellation
+	case *DocumentPOSReturn:
+		schema.Type = DocumentTypePOSReturn
+	case *DocumentPOSCancellation:
+		schema.Type = DocumentTypePOSCancellation
+	case *DocumentInvoice:
+		schema.Type = DocumentTypeInvoice
+	case *DocumentCommercialWithInvoice:
+		schema.Type = DocumentTypeCommercialWithInvoice
+		commercial, err := NewDocumentSchema(&d.commercialDocument)
+		if err != nil {
+			return nil, err
+		}
+		schema.Commercial = commercial
+	default:
+		return nil, fmt.Errorf("%w %T", ErrUnknownDocumentType, document)
+	}
+
+	for _, command := range document.Commands() {
+		commandSchema, err := NewCommandSchema(command)
+		if err != nil {
+			return nil, err
+		}
+		schema.Commands = append(schema.Commands, *commandSchema)
+	}
+	return schema, nil
+}
+
+// Document returns the document described by the schema, with its commands in the same order.
+func (s *DocumentSchema) Document() (Document, error) {
+	if s.Version != SchemaVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedSchemaVersion, s.Version)
+	}
+	if s.Type == DocumentTypeManagement {
+		return NewDocumentManagement(s.Rows), nil
+	}
+
+	var commands []Command
+	for i := range s.Commands {
+		command, err := s.Commands[i].Command()
+		if err != nil {
+			return nil, fmt.Errorf("command %d: %w", i, err)
+		}
+		commands = append(commands, command)
+	}
+	generic := DocumentGeneric{commands: commands}
+
+	switch s.Type {
+	case DocumentTypeCommercial:
+		return &DocumentCommercial{DocumentGeneric: generic}, nil
+	case DocumentTypeCommercialReturn:
+		return &DocumentCommercialReturn{DocumentGeneric: generic}, nil
+	case DocumentTypeCommercialCancellation:
+		return &DocumentCommercialCancellation{DocumentGeneric: generic}, nil
+	case DocumentTypePOSReturn:
+		return &DocumentPOSReturn{DocumentGeneric: generic}, nil
+	case DocumentTypePOSCancellation:
+		return &DocumentPOSCancellation{DocumentGeneric: generic}, nil
+	case DocumentTypeInvoice:
+		return &DocumentInvoice{DocumentGeneric: generic}, nil
+	case DocumentTypeCommercialWithInvoice:
+		if s.Commercial == nil {
+			return nil, fmt.Errorf("%w: missing commercial document", ErrInvalidSchema)
+		}
+		commercial, err := s.Commercial.Document()
+		if err != nil {
+			return nil, err
+		}
+		commercialDocument, ok := commercial.(*DocumentCommercial)
+		if !ok {
+			return nil, fmt.Errorf("%w: commercial document of type %q", ErrInvalidSchema, s.Commercial.Type)
+		}
+		return &DocumentCommercialWithInvoice{DocumentGeneric: generic, commercialDocument: *commercialDocument}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownDocumentType, s.Type)
+	}
+}
+
+// MarshalDocument returns the JSON encoding of document.
+func MarshalDocument(document Document) ([]byte, error) {
+	schema, err := NewDocumentSchema(document)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(schema)
+}
+
+// UnmarshalDocument decodes a document from its JSON encoding.
+func UnmarshalDocument(data []byte) (Document, error) {
+	var schema DocumentSchema
+	err := json.Unmarshal(data, &schema)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Document()
+}
+
+// MarshalDocumentYAML returns the YAML encoding of document.
+func MarshalDocumentYAML(document Document) ([]byte, error) {
+	schema, err := NewDocumentSchema(document)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(schema)
+}
+
+// UnmarshalDocumentYAML decodes a document from its YAML encoding.
+func UnmarshalDocumentYAML(data []byte) (Document, error) {
+	var schema DocumentSchema
+	err := yaml.Unmarshal(data, &schema)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Document()
+}
+
+func marshalCommand(command Command) ([]byte, error) {
+	schema, err := NewCommandSchema(command)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(schema)
+}
+
+// unmarshalCommand decodes data into the command pointed by target, which must have the type encoded in data.
+func unmarshalCommand[T any](data []byte, target *T) error {
+	var schema CommandSchema
+	err := json.Unmarshal(data, &schema)
+	if err != nil {
+		return err
+	}
+	command, err := schema.Command()
+	if err != nil {
+		return err
+	}
+	typed, ok := any(command).(*T)
+	if !ok {
+		return fmt.Errorf("%w: cannot decode %q command into %T", ErrInvalidSchema, schema.Type, target)
+	}
+	*target = *typed
+	return nil
+}
+
+// unmarshalDocument decodes data into the document pointed by target, which must have the type encoded in data.
+func unmarshalDocument[T any](data []byte, target *T) error {
+	document, err := UnmarshalDocument(data)
+	if err != nil {
+		return err
+	}
+	typed, ok := any(document).(*T)
+	if !ok {
+		return fmt.Errorf("%w: cannot decode document into %T", ErrInvalidSchema, target)
+	}
+	*target = *typed
+	return nil
+}
+
+// JSON encoding of commands and documents, using CommandSchema and DocumentSchema.
+
+func (c *CommandGeneric) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandGeneric) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandProduct) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandProduct) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandVoidItem) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandVoidItem) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandProductPLU) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandProductPLU) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandDiscountDepartment) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandDiscountDepartment) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandTrailer) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandTrailer) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandPayment) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandPayment) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandCustomerIdentifier) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandCustomerIdentifier) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandDiscountPercentage) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandDiscountPercentage) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandDiscountAmount) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandDiscountAmount) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandIncreaseAmount) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandIncreaseAmount) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandIncreasePercentage) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandIncreasePercentage) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandSubtotal) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandSubtotal) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandDiscountPercentageSubtotal) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandDiscountPercentageSubtotal) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandDiscountAmountSubtotal) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandDiscountAmountSubtotal) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandIncreasePercentageSubtotal) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandIncreasePercentageSubtotal) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandIncreaseAmountSubtotal) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandIncreaseAmountSubtotal) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandBarcode) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandBarcode) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenDocumentCommercialReturn) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenDocumentCommercialReturn) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenDocumentCommercialCancellation) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenDocumentCommercialCancellation) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenDocumentPOSReturn) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenDocumentPOSReturn) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenDocumentPOSCancellation) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenDocumentPOSCancellation) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenInvoice) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenInvoice) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenInvoiceCommercialDocument) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenInvoiceCommercialDocument) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandInvoiceDetails) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandInvoiceDetails) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandDisplayMessage) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandDisplayMessage) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandFinancialReport) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandFinancialReport) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandOpenCashDrawer) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandOpenCashDrawer) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandSetDateTime) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandSetDateTime) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandCancelDocument) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandCancelDocument) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (c *CommandStatusRequest) MarshalJSON() ([]byte, error) {
+	return marshalCommand(c)
+}
+
+func (c *CommandStatusRequest) UnmarshalJSON(data []byte) error {
+	return unmarshalCommand(data, c)
+}
+
+func (d *DocumentCommercial) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentCommercial) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentManagement) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentManagement) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentCommercialReturn) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentCommercialReturn) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentCommercialCancellation) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentCommercialCancellation) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentPOSReturn) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentPOSReturn) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentPOSCancellation) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentPOSCancellation) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentInvoice) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentInvoice) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
+
+func (d *DocumentCommercialWithInvoice) MarshalJSON() ([]byte, error) {
+	return MarshalDocument(d)
+}
+
+func (d *DocumentCommercialWithInvoice) UnmarshalJSON(data []byte) error {
+	return unmarshalDocument(data, d)
+}
@@ -0,0 +1,234 @@
+package gongoff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func schemaTestDocuments(t *testing.T) []Document {
+	product := "BREAD"
+	quantity := 2
+	department := 3
+	amount := Amount(500)
+	description := "SATISPAY"
+	date := time.Date(2023, 5, 17, 0, 0, 0, 0, time.UTC)
+	invoiceNumber := 12
+
+	payment, _ := NewCommandPayment(TerminatorTypePaymentCards, &amount, &description)
+	rest, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	customer, _ := NewCommandCustomerIdentifier("RSSMRA80A01H501U")
+	barcode, _ := NewCommandBarcode("12345678")
+	plu, _ := NewCommandProductPLU(12, &quantity, &amount)
+	pluProgrammed, _ := NewCommandProductPLU(7, nil, nil)
+	discountDepartment, _ := NewCommandDiscountDepartment(100, &description, 3)
+	weighed, _ := NewCommandProductQuantity(1890, &product, 750, nil)
+	pluWeighed, _ := NewCommandProductPLUQuantity(12, 1250, nil)
+
+	commercial := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: []Command{
+		NewCommandProduct(750, &product, &quantity, &department),
+		mustCommand(NewCommandDiscountPercentage(12.5)),
+		NewCommandProduct(300, nil, nil, nil),
+		mustCommand(NewCommandDiscountAmount(50)),
+		mustCommand(NewCommandIncreaseAmount(20)),
+		NewCommandVoidItem(NewCommandProduct(300, nil, nil, nil)),
+		NewCommandVoidItem(nil),
+		weighed,
+		NewCommandVoidItem(weighed),
+		plu,
+		pluProgrammed,
+		pluWeighed,
+		discountDepartment,
+		mustCommand(NewCommandIncreasePercentage(5)),
+		NewCommandSubtotal(),
+		mustCommand(NewCommandDiscountPercentageSubtotal(10)),
+		mustCommand(NewCommandDiscountAmountSubtotal(30)),
+		mustCommand(NewCommandIncreasePercentageSubtotal(2)),
+		mustCommand(NewCommandIncreaseAmountSubtotal(15)),
+		customer,
+		barcode,
+		NewCommandTrailer("Thank you"),
+		NewCommandGeneric([]Data{{variable: "LOTTERY", separator: SeparatorTypeDescription}}, Terminator{terminatorType: TerminatorTypeAdditionalDescription}),
+		payment,
+		rest,
+	}}}
+	id := NewDocumentId(1, 2, date, nil)
+	invoice, err := NewDocumentInvoice(
+		*NewCommandOpenInvoice(&invoiceNumber),
+		[]CommandInvoiceDetails{*NewCommandInvoiceDetails("Mario Rossi")},
+		[]CommandProduct{*NewCommandProduct(750, &product, nil, nil)},
+		[]CommandPayment{*rest},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []Document{
+		commercial,
+		NewDocumentManagement([]string{"first", "second"}),
+		NewDocumentCommercialReturn(*NewCommandOpenDocumentCommercialReturn(*id), NewCommandProduct(750, nil, nil, nil), rest),
+		NewDocumentCommercialCancellation(*NewCommandOpenDocumentCommercialCancellation(*id), nil, nil),
+		NewDocumentPOSReturn(*NewCommandOpenDocumentPOSReturn(date), nil, rest),
+		NewDocumentPOSCancellation(*NewCommandOpenDocumentPOSCancellation(date), nil, nil),
+		invoice,
+		NewDocumentCommercialWithInvoice(*NewCommandOpenInvoiceCommercialDocument(nil), []CommandInvoiceDetails{*NewCommandInvoiceDetails("Mario Rossi")}, *commercial),
+	}
+}
+
+// assertSameDocument checks that two documents have the same type and send the same commands.
+func assertSameDocument(t *testing.T, expected Document, got Document) {
+	t.Helper()
+	if reflect.TypeOf(expected) != reflect.TypeOf(got) {
+		t.Fatalf("Expected %T, got %T", expected, got)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Expected %T to round-trip exactly, got %+v", expected, got)
+	}
+	for i, command := range expected.Commands() {
+		e, _ := command.Encode()
+		g, _ := got.Commands()[i].Encode()
+		if string(e) != string(g) {
+			t.Errorf("Expected command %d = %s, got %s", i, e, g)
+		}
+	}
+}
+
+func TestDocumentSchemaJSON(t *testing.T) {
+
+	for _, doc := range schemaTestDocuments(t) {
+		data, err := MarshalDocument(doc)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		decoded, err := UnmarshalDocument(data)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s for %s", err, data)
+		}
+		assertSameDocument(t, doc, decoded)
+	}
+
+	fmt.Println("Completed testDocumentSchemaJSON")
+}
+
+func TestDocumentSchemaYAML(t *testing.T) {
+
+	for _, doc := range schemaTestDocuments(t) {
+		data, err := MarshalDocumentYAML(doc)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		decoded, err := UnmarshalDocumentYAML(data)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s for %s", err, data)
+		}
+		assertSameDocument(t, doc, decoded)
+	}
+
+	fmt.Println("Completed testDocumentSchemaYAML")
+}
+
+func TestDocumentSchemaEncodingJSON(t *testing.T) {
+
+	doc, err := NewReceiptBuilder().AddItem("BREAD", 750, 1).PayRest(TerminatorTypePaymentCash).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	expected := `{"version":1,"type":"commercial","commands":[{"type":"product","description":"BREAD","unitPrice":750,"department":1},{"type":"payment","method":"1T"}]}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	var decoded DocumentCommercial
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	assertSameDocument(t, doc, &decoded)
+
+	var management DocumentManagement
+	err = json.Unmarshal(data, &management)
+	if !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema, got %v", err)
+	}
+
+	var product CommandProduct
+	err = json.Unmarshal([]byte(`{"type":"product","description":"MILK","quantity":3,"unitPrice":120}`), &product)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	encoded, _ := product.Encode()
+	if string(encoded) != `"MILK"3*120H1R` {
+		t.Errorf("Expected \"MILK\"3*120H1R, got %s", encoded)
+	}
+
+	fmt.Println("Completed testDocumentSchemaEncodingJSON")
+}
+
+func TestCommandSchemaJSON(t *testing.T) {
+
+	tests := []struct {
+		command  Command
+		expected string
+	}{
+		{NewCommandCancelDocument(), `{"type":"cancelDocument"}`},
+		{NewCommandStatusRequest(), `{"type":"statusRequest"}`},
+		{NewCommandOpenCashDrawer(), `{"type":"openCashDrawer"}`},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(test.command)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, data)
+		}
+		schema := CommandSchema{}
+		err = json.Unmarshal(data, &schema)
+		if err != nil {
+			t.Fatalf("Expected error = nil, got %s", err)
+		}
+		decoded, err := schema.Command()
+		if err != nil || reflect.TypeOf(decoded) != reflect.TypeOf(test.command) {
+			t.Errorf("Expected %T from %s, got %T, %v", test.command, data, decoded, err)
+		}
+	}
+
+	var cancel CommandCancelDocument
+	err := json.Unmarshal([]byte(`{"type":"statusRequest"}`), &cancel)
+	if !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema, got %v", err)
+	}
+
+	fmt.Println("Completed testCommandSchemaJSON")
+}
+
+func TestDocumentSchemaErrors(t *testing.T) {
+
+	tests := []struct {
+		data     string
+		expected error
+	}{
+		{`{"type":"commercial","commands":[]}`, ErrUnsupportedSchemaVersion},
+		{`{"version":2,"type":"commercial","commands":[]}`, ErrUnsupportedSchemaVersion},
+		{`{"version":1,"type":"receipt"}`, ErrUnknownDocumentType},
+		{`{"version":1,"type":"commercial","commands":[{"type":"coupon"}]}`, ErrUnknownCommandType},
+		{`{"version":1,"type":"commercial","commands":[{"type":"discountAmount"}]}`, ErrInvalidSchema},
+		{`{"version":1,"type":"commercial","commands":[{"type":"payment","method":"1M"}]}`, ErrInvalidPaymentMethod},
+		{`{"version":1,"type":"posReturn","commands":[{"type":"openPOSReturn","date":"17/05/2023"}]}`, ErrInvalidSchema},
+	}
+	for _, test := range tests {
+		_, err := UnmarshalDocument([]byte(test.data))
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v for %s, got %v", test.expected, test.data, err)
+		}
+	}
+
+	fmt.Println("Completed testDocumentSchemaErrors")
+}
@@ -0,0 +1,87 @@
+package gongoff
+
+import "context"
+
+// Session is an exclusive access to a printer, obtained with SessionPrinter.Session or WithSession.
+// It is used for sequences of calls that must not be interrupted by other goroutines,
+// ex. a return document immediately followed by the new receipt.
+// The session must not be used after the function it was passed to returns.
+type Session interface {
+	PrintDocument(Document) error
+	PrintDocumentContext(context.Context, Document) error
+	PrintCommands([]Command) error
+	PrintCommandsContext(context.Context, []Command) error
+	Status() (*PrinterStatus, error)
+	StatusContext(context.Context) (*PrinterStatus, error)
+}
+
+// lockedPrinter is implemented by the printers of the package, its methods expect the printer to be locked.
+type lockedPrinter interface {
+	printCommands(ctx context.Context, commands []Command) error
+	status(ctx context.Context) (*PrinterStatus, error)
+}
+
+// session is the Session of the printers of the package.
+type session struct {
+	printer lockedPrinter
+	ended   bool
+}
+
+// WithSession calls fn with a session on printer, see SessionPrinter.
+// Printers not implementing SessionPrinter are called directly by the session, without exclusive access.
+func WithSession(printer Printer, fn func(Session) error) error {
+	if p, ok := printer.(SessionPrinter); ok {
+		return p.Session(fn)
+	}
+	return runSession(unlockedPrinter{printer}, fn)
+}
+
+// unlockedPrinter is the lockedPrinter of the printers not implementing SessionPrinter.
+type unlockedPrinter struct {
+	printer Printer
+}
+
+func (p unlockedPrinter) printCommands(ctx context.Context, commands []Command) error {
+	return PrintCommandsContext(ctx, p.printer, commands)
+}
+
+func (p unlockedPrinter) status(ctx context.Context) (*PrinterStatus, error) {
+	return StatusContext(ctx, p.printer)
+}
+
+// runSession calls fn with a session on printer, the printer must be locked.
+func runSession(printer lockedPrinter, fn func(Session) error) error {
+	s := &session{printer: printer}
+	defer func() { s.ended = true }()
+	return fn(s)
+}
+
+func (s *session) PrintDocument(doc Document) error {
+	return s.PrintCommandsContext(context.Background(), doc.Commands())
+}
+
+func (s *session) PrintDocumentContext(ctx context.Context, doc Document) error {
+	return s.PrintCommandsContext(ctx, doc.Commands())
+}
+
+func (s *session) PrintCommands(commands []Command) error {
+	return s.PrintCommandsContext(context.Background(), commands)
+}
+
+func (s *session) PrintCommandsContext(ctx context.Context, commands []Command) error {
+	if s.ended {
+		return ErrSessionEnded
+	}
+	return s.printer.printCommands(ctx, commands)
+}
+
+func (s *session) Status() (*PrinterStatus, error) {
+	return s.StatusContext(context.Background())
+}
+
+func (s *session) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	if s.ended {
+		return nil, ErrSessionEnded
+	}
+	return s.printer.status(ctx)
+}
@@ -0,0 +1,126 @@
+package gongoff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Status anatomy:
+// The status request and its reply are not part of the Epson Xon-Xoff protocol, they are answered with replies
+// enabled (see GenericPrinter.SetReplies) by gongofftest.Emulator and by gateways implementing the same format.
+// The device answers the status request (NewCommandStatusRequest, "?") with OK followed by space separated key=value fields,
+// only the fields describing an abnormal condition or an open document are present.
+// OK                                -> ready, no document open.
+// OK paper=low document=commercial  -> paper is running out, a commercial document is open.
+// Keys: paper=low|out, cover=open, fiscalmemory=nearlyfull, document=KIND, closure=overdue.
+// Unknown keys are ignored, so newer firmwares can report more fields.
+
+const (
+	statusKeyPaper        = "paper"
+	statusKeyCover        = "cover"
+	statusKeyFiscalMemory = "fiscalmemory"
+	statusKeyDocument     = "document"
+	statusKeyClosure      = "closure"
+)
+
+// PrinterStatus is the state reported by the printer, see Printer.Status.
+type PrinterStatus struct {
+	PaperLow  bool
+	PaperOut  bool
+	CoverOpen bool
+	// FiscalMemoryNearlyFull means the printer must be replaced or serviced soon, it stops working when full.
+	FiscalMemoryNearlyFull bool
+	// Document is the kind of the open document (ex. "commercial", "management"), empty if none is open.
+	Document string
+	// ClosureOverdue means the daily closure was not printed in time, no sale is accepted until it is.
+	ClosureOverdue bool
+}
+
+// DocumentOpen reports whether a document is open, the printer refuses to open a new one until it is
+// completed or cancelled with NewCommandCancelDocument.
+func (s *PrinterStatus) DocumentOpen() bool {
+	return s.Document != ""
+}
+
+// CanPrint reports whether the printer has paper and its cover is closed.
+func (s *PrinterStatus) CanPrint() bool {
+	return !s.PaperOut && !s.CoverOpen
+}
+
+// NeedsAttention reports whether the operator has to act: paper low or out, cover open,
+// fiscal memory nearly full or daily closure overdue.
+func (s *PrinterStatus) NeedsAttention() bool {
+	return s.PaperLow || s.FiscalMemoryNearlyFull || !s.CanPrint() || s.ClosureOverdue
+}
+
+// ReadyForSale returns nil if a new sale can start, otherwise the condition preventing it:
+// ErrPaperOut, ErrCoverOpen, ErrClosureOverdue or ErrDocumentOpen.
+func (s *PrinterStatus) ReadyForSale() error {
+	switch {
+	case s.PaperOut:
+		return ErrPaperOut
+	case s.CoverOpen:
+		return ErrCoverOpen
+	case s.ClosureOverdue:
+		return ErrClosureOverdue
+	case s.DocumentOpen():
+		return ErrDocumentOpen
+	}
+	return nil
+}
+
+// decodeStatus parses the message of the response to a status request.
+func decodeStatus(message string) (*PrinterStatus, error) {
+	status := &PrinterStatus{}
+	for _, field := range strings.Fields(message) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed printer status %q", message)
+		}
+		switch key {
+		case statusKeyPaper:
+			status.PaperLow = value == "low"
+			status.PaperOut = value == "out"
+		case statusKeyCover:
+			status.CoverOpen = value == "open"
+		case statusKeyFiscalMemory:
+			status.FiscalMemoryNearlyFull = value == "nearlyfull"
+		case statusKeyDocument:
+			status.Document = value
+		case statusKeyClosure:
+			status.ClosureOverdue = value == "overdue"
+		}
+	}
+	return status, nil
+}
+
+// Status asks the printer for its status, it needs replies and returns ErrRepliesDisabled without them.
+func (p *GenericPrinter) Status() (*PrinterStatus, error) {
+	return p.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but stops waiting for the printer when ctx is done.
+func (p *GenericPrinter) StatusContext(ctx context.Context) (*PrinterStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status(ctx)
+}
+
+// status asks the printer for its status, p.mu must be held.
+func (p *GenericPrinter) status(ctx context.Context) (*PrinterStatus, error) {
+	if !p.isOpen() {
+		return nil, ErrPrinterNotOpen
+	}
+	if !p.expectReplies {
+		return nil, ErrRepliesDisabled
+	}
+	if p.broken {
+		return nil, ErrConnectionBroken
+	}
+	response, err := p.sendCommand(ctx, NewCommandStatusRequest())
+	if err != nil {
+		return nil, err
+	}
+	return decodeStatus(response.Message)
+}
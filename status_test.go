@@ -0,0 +1,109 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDecodeStatus(t *testing.T) {
+
+	status, err := decodeStatus("")
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if status.DocumentOpen() {
+		t.Errorf("Expected no document open, got %q", status.Document)
+	}
+
+	status, err = decodeStatus("document=commercial future=1")
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if !status.DocumentOpen() || status.Document != "commercial" {
+		t.Errorf("Expected commercial document open, got %q", status.Document)
+	}
+
+	status, err = decodeStatus("paper=low cover=open fiscalmemory=nearlyfull closure=overdue")
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if !status.PaperLow || status.PaperOut || !status.CoverOpen || !status.FiscalMemoryNearlyFull || !status.ClosureOverdue {
+		t.Errorf("Expected paper low, cover open, fiscal memory nearly full and closure overdue, got %+v", status)
+	}
+
+	status, err = decodeStatus("paper=out")
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if !status.PaperOut || status.PaperLow {
+		t.Errorf("Expected paper out, got %+v", status)
+	}
+
+	_, err = decodeStatus("document")
+	if err == nil {
+		t.Errorf("Expected error for malformed status, got nil")
+	}
+
+	fmt.Println("Completed testDecodeStatus")
+}
+
+func TestPrinterStatusChecks(t *testing.T) {
+
+	tests := []struct {
+		status         PrinterStatus
+		canPrint       bool
+		needsAttention bool
+		readyForSale   error
+	}{
+		{PrinterStatus{}, true, false, nil},
+		{PrinterStatus{PaperLow: true}, true, true, nil},
+		{PrinterStatus{FiscalMemoryNearlyFull: true}, true, true, nil},
+		{PrinterStatus{PaperOut: true}, false, true, ErrPaperOut},
+		{PrinterStatus{CoverOpen: true}, false, true, ErrCoverOpen},
+		{PrinterStatus{ClosureOverdue: true}, true, true, ErrClosureOverdue},
+		{PrinterStatus{Document: "commercial"}, true, false, ErrDocumentOpen},
+	}
+	for _, test := range tests {
+		if test.status.CanPrint() != test.canPrint {
+			t.Errorf("Expected CanPrint %t for %+v, got %t", test.canPrint, test.status, !test.canPrint)
+		}
+		if test.status.NeedsAttention() != test.needsAttention {
+			t.Errorf("Expected NeedsAttention %t for %+v, got %t", test.needsAttention, test.status, !test.needsAttention)
+		}
+		if err := test.status.ReadyForSale(); !errors.Is(err, test.readyForSale) || (err == nil) != (test.readyForSale == nil) {
+			t.Errorf("Expected ReadyForSale %v for %+v, got %v", test.readyForSale, test.status, err)
+		}
+	}
+
+	fmt.Println("Completed testPrinterStatusChecks")
+}
+
+func TestGenericPrinterStatus(t *testing.T) {
+
+	printer, received, conn := newPipePrinter(func(command string) string {
+		if command == "?" {
+			return "OK document=management"
+		}
+		return "ERR 1 UNKNOWN COMMAND"
+	})
+	defer conn.Close()
+
+	status, err := printer.Status()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if status.Document != "management" {
+		t.Errorf("Expected management document open, got %q", status.Document)
+	}
+	if len(*received) != 1 || (*received)[0] != "?" {
+		t.Errorf("Expected status request, got %v", *received)
+	}
+
+	_, err = (&GenericPrinter{}).Status()
+	if !errors.Is(err, ErrPrinterNotOpen) {
+		t.Errorf("Expected ErrPrinterNotOpen, got %v", err)
+	}
+
+	fmt.Println("Completed testGenericPrinterStatus")
+}
@@ -0,0 +1,411 @@
+package gongoff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// receiptLine is a sale of a commercial document with the adjustments applied to it.
+type receiptLine struct {
+	// department is 0 for PLUs, their department is programmed in the printer.
+	department int
+	amount     Amount
+	// sale, discount and increase are the parts of amount removed from the totals when the line is voided.
+	sale     Amount
+	discount Amount
+	increase Amount
+	// product is the sale of the line, nil for PLUs and department discounts.
+	product *CommandProduct
+	// unknown is set for PLUs sold at the price programmed in the printer.
+	unknown bool
+}
+
+// receipt is the state of a commercial document replayed like the printer does.
+type receipt struct {
+	lines    []receiptLine
+	subtotal Amount
+	discount Amount
+	increase Amount
+	paid     Amount
+	// cashPaid is the part of paid given with payment methods allowing change.
+	cashPaid  Amount
+	zeroPrice bool
+	// unknownPrice is set when a PLU is sold at the price programmed in the printer, the amounts cannot be computed.
+	unknownPrice bool
+	// lineClosed is set by subtotal adjustments and voids, line adjustments are refused until the next sale.
+	lineClosed bool
+	// subtotalSpread is set once a subtotal adjustment is spread on the lines, voids are refused afterwards.
+	subtotalSpread bool
+	// nonCashOverpayment is set when a payment method not allowing change exceeded the amount due.
+	nonCashOverpayment bool
+}
+
+// cashPaymentMethods are the payment methods for which the printer gives change.
+var cashPaymentMethods = map[TerminatorType]bool{
+	TerminatorTypePaymentCash:  true,
+	TerminatorTypePaymentCash2: true,
+}
+
+func (r *receipt) total() Amount {
+	return r.subtotal - r.discount + r.increase
+}
+
+// replayReceipt replays the sales, adjustments and payments of commands like the printer does.
+// Transaction discounts and increases apply to the sale they follow, department discounts to the sales of their department,
+// subtotal discounts and increases are spread on the sales proportionally to their amount, payments without amount pay the amount still due.
+func replayReceipt(commands []Command) (*receipt, error) {
+	r := &receipt{}
+	for _, command := range commands {
+		switch c := command.(type) {
+		case *CommandProduct:
+			if r.paid > 0 {
+				return nil, fmt.Errorf("%w: product after payment", ErrInvalidDocumentOrder)
+			}
+			if c.unitPrice <= 0 {
+				r.zeroPrice = true
+			}
+			r.lines = append(r.lines, receiptLine{department: c.departmentNumber(), amount: c.amount(), sale: c.amount(), product: c})
+			r.subtotal += c.amount()
+			r.lineClosed = false
+		case *CommandProductPLU:
+			if r.paid > 0 {
+				return nil, fmt.Errorf("%w: product after payment", ErrInvalidDocumentOrder)
+			}
+			amount, known := c.amount()
+			if !known {
+				r.unknownPrice = true
+			}
+			r.lines = append(r.lines, receiptLine{amount: amount, sale: amount, unknown: !known})
+			r.subtotal += amount
+			r.lineClosed = false
+		case *CommandVoidItem:
+			err := r.void(c.product)
+			if err != nil {
+				return nil, err
+			}
+		case *CommandDiscountDepartment:
+			err := r.discountDepartment(c.department, c.discountAmount)
+			if err != nil {
+				return nil, err
+			}
+		case *CommandDiscountAmount:
+			err := r.discountLast(c.discountAmount)
+			if err != nil {
+				return nil, err
+			}
+		case *CommandDiscountPercentage:
+			if len(r.lines) == 0 {
+				return nil, ErrDiscountWithoutProduct
+			}
+			err := r.discountLast(r.lines[len(r.lines)-1].amount.Percentage(c.discountPercentage))
+			if err != nil {
+				return nil, err
+			}
+		case *CommandIncreaseAmount:
+			err := r.increaseLast(c.increaseAmount)
+			if err != nil {
+				return nil, err
+			}
+		case *CommandIncreasePercentage:
+			if len(r.lines) == 0 {
+				return nil, ErrDiscountWithoutProduct
+			}
+			err := r.increaseLast(r.lines[len(r.lines)-1].amount.Percentage(c.increasePercentage))
+			if err != nil {
+				return nil, err
+			}
+		case *CommandDiscountPercentageSubtotal:
+			err := r.adjustSubtotal(-r.total().Percentage(c.discountPercentage))
+			if err != nil {
+				return nil, err
+			}
+		case *CommandDiscountAmountSubtotal:
+			err := r.adjustSubtotal(-c.discountAmount)
+			if err != nil {
+				return nil, err
+			}
+		case *CommandIncreasePercentageSubtotal:
+			err := r.adjustSubtotal(r.total().Percentage(c.increasePercentage))
+			if err != nil {
+				return nil, err
+			}
+		case *CommandIncreaseAmountSubtotal:
+			err := r.adjustSubtotal(c.increaseAmount)
+			if err != nil {
+				return nil, err
+			}
+		case *CommandPayment:
+			err := r.pay(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// discountLast applies a discount to the last sale.
+func (r *receipt) discountLast(discount Amount) error {
+	if len(r.lines) == 0 {
+		return ErrDiscountWithoutProduct
+	}
+	if r.paid > 0 {
+		return fmt.Errorf("%w: discount after payment", ErrInvalidDocumentOrder)
+	}
+	if r.lineClosed {
+		return fmt.Errorf("%w: line discount not following a sale", ErrInvalidDocumentOrder)
+	}
+	last := &r.lines[len(r.lines)-1]
+	last.amount -= discount
+	if last.amount < 0 && !last.unknown {
+		return ErrNegativeAmount
+	}
+	last.discount += discount
+	r.discount += discount
+	return nil
+}
+
+// increaseLast applies an increase to the last sale.
+func (r *receipt) increaseLast(increase Amount) error {
+	if len(r.lines) == 0 {
+		return ErrDiscountWithoutProduct
+	}
+	if r.paid > 0 {
+		return fmt.Errorf("%w: increase after payment", ErrInvalidDocumentOrder)
+	}
+	if r.lineClosed {
+		return fmt.Errorf("%w: line increase not following a sale", ErrInvalidDocumentOrder)
+	}
+	last := &r.lines[len(r.lines)-1]
+	last.amount += increase
+	last.increase += increase
+	r.increase += increase
+	return nil
+}
+
+// adjustSubtotal applies a discount (negative adjustment) or an increase to the subtotal,
+// spreading it on the sales proportionally to their amount, the last sale gets the rounding difference.
+func (r *receipt) adjustSubtotal(adjustment Amount) error {
+	if len(r.lines) == 0 {
+		return ErrDiscountWithoutProduct
+	}
+	if r.paid > 0 {
+		return fmt.Errorf("%w: subtotal adjustment after payment", ErrInvalidDocumentOrder)
+	}
+	subtotal := r.total()
+	if subtotal+adjustment < 0 {
+		return ErrNegativeTotal
+	}
+	remaining := adjustment
+	if subtotal > 0 {
+		for i := range r.lines[:len(r.lines)-1] {
+			share := Amount(divideRound(int64(adjustment)*int64(r.lines[i].amount), int64(subtotal)))
+			r.lines[i].amount += share
+			remaining -= share
+		}
+	}
+	r.lines[len(r.lines)-1].amount += remaining
+	if adjustment < 0 {
+		r.discount -= adjustment
+	} else {
+		r.increase += adjustment
+	}
+	r.lineClosed = true
+	r.subtotalSpread = true
+	return nil
+}
+
+// void removes a line and its adjustments from the totals: the last line if product is nil,
+// otherwise the last line selling the same quantity of the same product at the same price.
+func (r *receipt) void(product *CommandProduct) error {
+	if r.paid > 0 {
+		return fmt.Errorf("%w: void after payment", ErrInvalidDocumentOrder)
+	}
+	if r.subtotalSpread {
+		return fmt.Errorf("%w: void after a subtotal adjustment", ErrInvalidDocumentOrder)
+	}
+	index := len(r.lines) - 1
+	if product != nil && len(product.data) > 0 {
+		for index >= 0 && (r.lines[index].product == nil || !r.lines[index].product.sameSale(product)) {
+			index--
+		}
+	}
+	if index < 0 {
+		return ErrVoidWithoutItem
+	}
+
+	line := r.lines[index]
+	r.lines = append(r.lines[:index], r.lines[index+1:]...)
+	r.subtotal -= line.sale
+	r.discount -= line.discount
+	r.increase -= line.increase
+	r.lineClosed = true
+	r.unknownPrice = false
+	for _, remaining := range r.lines {
+		if remaining.unknown {
+			r.unknownPrice = true
+		}
+	}
+	return nil
+}
+
+// discountDepartment registers a discount on the sales of a department as a negative sale.
+// The sales of the department must cover the discount, PLUs may belong to any department.
+func (r *receipt) discountDepartment(department int, discount Amount) error {
+	if len(r.lines) == 0 {
+		return ErrDiscountWithoutProduct
+	}
+	if r.paid > 0 {
+		return fmt.Errorf("%w: discount after payment", ErrInvalidDocumentOrder)
+	}
+	var sales Amount
+	plu := false
+	for _, line := range r.lines {
+		switch line.department {
+		case department:
+			sales += line.amount
+		case 0:
+			plu = true
+		}
+	}
+	if sales < discount && !plu {
+		return ErrNegativeAmount
+	}
+	r.lines = append(r.lines, receiptLine{department: department, amount: -discount, discount: discount})
+	r.discount += discount
+	return nil
+}
+
+// pay registers a payment, the printer closes the document as soon as the total is covered.
+func (r *receipt) pay(c *CommandPayment) error {
+	due := r.total() - r.paid
+	if r.paid > 0 && due <= 0 && !r.unknownPrice {
+		return fmt.Errorf("%w: payment after the total was covered", ErrInvalidDocumentOrder)
+	}
+	amount := due
+	if c.amount != nil {
+		amount = *c.amount
+	}
+	if amount > due && !cashPaymentMethods[c.paymentMethod] {
+		r.nonCashOverpayment = true
+	}
+	if cashPaymentMethods[c.paymentMethod] {
+		r.cashPaid += amount
+	}
+	r.paid += amount
+	return nil
+}
+
+// Totals are the amounts of a commercial document as computed by the printer.
+type Totals struct {
+	// Subtotal is the sum of the sales before discounts.
+	Subtotal Amount
+	Discount Amount
+	Increase Amount
+	Total    Amount
+	Paid     Amount
+	// Change is the amount given back, only for payment methods allowing change.
+	Change Amount
+}
+
+// Totals computes the amounts of the document exactly as the printer would.
+// An error is returned if the commands are in an order the printer refuses,
+// ErrProgrammedPLU if a PLU is sold without overriding its price.
+func (d *DocumentCommercial) Totals() (*Totals, error) {
+	r, err := replayReceipt(d.commands)
+	if err != nil {
+		return nil, err
+	}
+	if r.unknownPrice {
+		return nil, ErrProgrammedPLU
+	}
+	totals := &Totals{
+		Subtotal: r.subtotal,
+		Discount: r.discount,
+		Increase: r.increase,
+		Total:    r.total(),
+		Paid:     r.paid,
+	}
+	if r.paid > totals.Total {
+		totals.Change = r.paid - totals.Total
+		if totals.Change > r.cashPaid {
+			totals.Change = r.cashPaid
+		}
+	}
+	return totals, nil
+}
+
+// Validate checks the document before it is sent to the printer.
+// It rejects documents without products, products without price, negative totals,
+// payments not covering the total and overpayments with methods not allowing change.
+// The amounts are not checked if a PLU is sold at the price programmed in the printer.
+func (d *DocumentCommercial) Validate() error {
+	r, err := replayReceipt(d.commands)
+	if err != nil {
+		return err
+	}
+	if len(r.lines) == 0 {
+		return ErrMissingProducts
+	}
+	if r.zeroPrice {
+		return ErrZeroPrice
+	}
+	if r.unknownPrice {
+		return nil
+	}
+	if r.total() < 0 {
+		return ErrNegativeTotal
+	}
+	if r.nonCashOverpayment {
+		return ErrNonCashOverpayment
+	}
+	if r.paid < r.total() {
+		return ErrInsufficientPayment
+	}
+	return nil
+}
+
+// VATTotal is the total of a commercial document for a VAT rate.
+type VATTotal struct {
+	// Rate is the VAT percentage, ex. 22.
+	Rate float64
+	// Gross is the amount including VAT.
+	Gross   Amount
+	Taxable Amount
+	Tax     Amount
+}
+
+// VATBreakdown computes the totals per VAT rate of the document, sorted by rate.
+// departmentRates maps every department used by the document to its VAT percentage, as programmed in the printer.
+// Prices include VAT: Tax = Gross * Rate / (100 + Rate) rounded to the cent, Taxable = Gross - Tax.
+// ErrProgrammedPLU is returned for documents selling PLUs, their department is only known by the printer.
+func (d *DocumentCommercial) VATBreakdown(departmentRates map[int]float64) ([]VATTotal, error) {
+	r, err := replayReceipt(d.commands)
+	if err != nil {
+		return nil, err
+	}
+
+	grossByRate := map[float64]Amount{}
+	for _, line := range r.lines {
+		if line.department == 0 {
+			return nil, ErrProgrammedPLU
+		}
+		rate, ok := departmentRates[line.department]
+		if !ok {
+			return nil, fmt.Errorf("%w: department %d", ErrMissingVATRate, line.department)
+		}
+		grossByRate[rate] += line.amount
+	}
+
+	var totals []VATTotal
+	for rate, gross := range grossByRate {
+		basisPoints := int64(math.Round(rate * 100))
+		tax := Amount(divideRound(int64(gross)*basisPoints, 10000+basisPoints))
+		totals = append(totals, VATTotal{Rate: rate, Gross: gross, Taxable: gross - tax, Tax: tax})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Rate < totals[j].Rate
+	})
+	return totals, nil
+}
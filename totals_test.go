@@ -0,0 +1,287 @@
+package gongoff
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestVATBreakdown(t *testing.T) {
+
+	food := 1
+	drinks := 2
+	quantity := 2
+	commandPayment, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	commandDiscount, _ := NewCommandDiscountAmount(100)
+	doc := NewDocumentCommercial(
+		[]CommandProduct{
+			*NewCommandProduct(1000, nil, nil, &drinks),
+			*NewCommandProduct(550, nil, &quantity, &food),
+		},
+		[]CommandPayment{*commandPayment},
+		commandDiscount,
+		nil,
+		nil,
+		nil,
+	)
+
+	totals, err := doc.VATBreakdown(map[int]float64{food: 10, drinks: 22})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if len(totals) != 2 {
+		t.Fatalf("Expected 2 VAT rates, got %d", len(totals))
+	}
+	// The discount applies to the subtotal: 0,48 of 1,00 to 10,00 at 22%, the rest to 2 * 5,50 at 10%.
+	if totals[0] != (VATTotal{Rate: 10, Gross: 1048, Taxable: 953, Tax: 95}) {
+		t.Errorf("Expected 10%% total 10,48 = 9,53 + 0,95, got %+v", totals[0])
+	}
+	if totals[1] != (VATTotal{Rate: 22, Gross: 952, Taxable: 780, Tax: 172}) {
+		t.Errorf("Expected 22%% total 9,52 = 7,80 + 1,72, got %+v", totals[1])
+	}
+
+	_, err = doc.VATBreakdown(map[int]float64{food: 10})
+	if !errors.Is(err, ErrMissingVATRate) {
+		t.Errorf("Expected ErrMissingVATRate, got %v", err)
+	}
+
+	fmt.Println("Completed testVATBreakdown")
+}
+
+func TestTotals(t *testing.T) {
+
+	quantity := 3
+	cash := Amount(2000)
+	commandCash, _ := NewCommandPayment(TerminatorTypePaymentCash, &cash, nil)
+	commandDiscount, _ := NewCommandDiscountPercentage(10)
+	doc := NewDocumentCommercial(
+		[]CommandProduct{
+			*NewCommandProduct(1000, nil, nil, nil),
+			*NewCommandProduct(250, nil, &quantity, nil),
+		},
+		[]CommandPayment{*commandCash},
+		nil,
+		commandDiscount,
+		nil,
+		nil,
+	)
+
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 10,00 + 3 * 2,50 = 17,50, 10% of the subtotal is 1,75.
+	expected := Totals{Subtotal: 1750, Discount: 175, Total: 1575, Paid: 2000, Change: 425}
+	if *totals != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *totals)
+	}
+	if err := doc.Validate(); err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	fmt.Println("Completed testTotals")
+}
+
+func TestTotalsPLU(t *testing.T) {
+
+	quantity := 2
+	price := Amount(650)
+	plu, _ := NewCommandProductPLU(12, &quantity, &price)
+	programmed, _ := NewCommandProductPLU(7, nil, nil)
+	bottles, _ := NewCommandDiscountDepartment(200, nil, 1)
+	commandCash, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+
+	doc := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: []Command{
+		NewCommandProduct(1000, nil, nil, nil), plu, bottles, commandCash,
+	}}}
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 10,00 + 2 * 6,50 - 2,00 of department 1 = 21,00
+	expected := Totals{Subtotal: 2300, Discount: 200, Total: 2100, Paid: 2100}
+	if *totals != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *totals)
+	}
+	_, err = doc.VATBreakdown(map[int]float64{1: 22})
+	if !errors.Is(err, ErrProgrammedPLU) {
+		t.Errorf("Expected ErrProgrammedPLU, got %v", err)
+	}
+
+	doc = &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: []Command{programmed, commandCash}}}
+	_, err = doc.Totals()
+	if !errors.Is(err, ErrProgrammedPLU) {
+		t.Errorf("Expected ErrProgrammedPLU, got %v", err)
+	}
+	if err := doc.Validate(); err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+
+	fmt.Println("Completed testTotalsPLU")
+}
+
+func TestTotalsSubtotal(t *testing.T) {
+
+	department := 2
+	commandCash, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	doc, err := NewDocumentCommercialWithItems(
+		[]DocumentItem{
+			{Sale: NewCommandProduct(1000, nil, nil, nil)},
+			{Sale: NewCommandProduct(500, nil, nil, &department), Adjustments: []Command{mustCommand(NewCommandIncreasePercentage(10))}},
+		},
+		[]Command{mustCommand(NewCommandDiscountPercentageSubtotal(10)), mustCommand(NewCommandIncreaseAmountSubtotal(105))},
+		[]CommandPayment{*commandCash},
+		nil,
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 10,00 + 5,00 + 0,50 = 15,50, 10% subtotal discount is 1,55, then 1,05 increase.
+	expected := Totals{Subtotal: 1500, Discount: 155, Increase: 155, Total: 1500, Paid: 1500}
+	if *totals != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *totals)
+	}
+
+	// The discount is spread 1,00 and 0,55, the increase 0,68 and 0,37.
+	breakdown, err := doc.VATBreakdown(map[int]float64{1: 22, 2: 10})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if len(breakdown) != 2 || breakdown[0].Gross != 532 || breakdown[1].Gross != 968 {
+		t.Errorf("Expected gross 5,32 at 10%% and 9,68 at 22%%, got %+v", breakdown)
+	}
+
+	fmt.Println("Completed testTotalsSubtotal")
+}
+
+func TestTotalsVoidItem(t *testing.T) {
+
+	bread := "BREAD"
+	milk := "MILK"
+	commandCash, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	doc := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: []Command{
+		NewCommandProduct(750, &bread, nil, nil),
+		mustCommand(NewCommandDiscountAmount(50)),
+		NewCommandProduct(120, &milk, nil, nil),
+		mustCommand(NewCommandIncreaseAmount(10)),
+		NewCommandProduct(300, nil, nil, nil),
+		NewCommandVoidItem(nil),
+		NewCommandVoidItem(NewCommandProduct(750, &bread, nil, nil)),
+		commandCash,
+	}}}
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// Only the milk with its increase is left.
+	expected := Totals{Subtotal: 120, Increase: 10, Total: 130, Paid: 130}
+	if *totals != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *totals)
+	}
+
+	tests := []struct {
+		name     string
+		commands []Command
+	}{
+		{"void without items", []Command{NewCommandVoidItem(nil)}},
+		{"void of unknown item", []Command{NewCommandProduct(120, &milk, nil, nil), NewCommandVoidItem(NewCommandProduct(750, &bread, nil, nil))}},
+		{"discount after void", []Command{NewCommandProduct(120, &milk, nil, nil), NewCommandProduct(750, &bread, nil, nil), NewCommandVoidItem(nil), mustCommand(NewCommandDiscountAmount(10))}},
+		{"void after subtotal discount", []Command{NewCommandProduct(120, &milk, nil, nil), mustCommand(NewCommandDiscountAmountSubtotal(10)), NewCommandVoidItem(nil)}},
+	}
+	for _, test := range tests {
+		doc := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: test.commands}}
+		_, err := doc.Totals()
+		if !errors.Is(err, ErrVoidWithoutItem) && !errors.Is(err, ErrInvalidDocumentOrder) {
+			t.Errorf("%s: expected ErrVoidWithoutItem or ErrInvalidDocumentOrder, got %v", test.name, err)
+		}
+	}
+
+	fmt.Println("Completed testTotalsVoidItem")
+}
+
+func TestTotalsQuantity(t *testing.T) {
+
+	cheese := "CHEESE"
+	ham := "HAM"
+	department := 2
+	commandCheese, _ := NewCommandProductQuantity(1890, &cheese, 750, &department)
+	commandHam, _ := NewCommandProductQuantity(2400, &ham, 125, &department)
+	commandCash, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	doc := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: []Command{
+		commandCheese,
+		mustCommand(NewCommandDiscountPercentage(10)),
+		commandHam,
+		NewCommandVoidItem(commandHam),
+		NewCommandProduct(750, nil, nil, nil),
+		commandCash,
+	}}}
+	totals, err := doc.Totals()
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	// 0,750 kg * 18,90 = 14,175 rounded to 14,18, minus 10% = 12,76, plus 7,50.
+	expected := Totals{Subtotal: 2168, Discount: 142, Total: 2026, Paid: 2026}
+	if *totals != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *totals)
+	}
+
+	breakdown, err := doc.VATBreakdown(map[int]float64{1: 10, 2: 4})
+	if err != nil {
+		t.Fatalf("Expected error = nil, got %s", err)
+	}
+	if len(breakdown) != 2 || breakdown[0].Gross != 1276 || breakdown[1].Gross != 750 {
+		t.Errorf("Expected 12,76 at 4%% and 7,50 at 10%%, got %+v", breakdown)
+	}
+
+	fmt.Println("Completed testTotalsQuantity")
+}
+
+func TestValidate(t *testing.T) {
+
+	amount := Amount(2000)
+	commandCash, _ := NewCommandPayment(TerminatorTypePaymentCash, nil, nil)
+	commandCashAmount, _ := NewCommandPayment(TerminatorTypePaymentCash, &amount, nil)
+	commandCard, _ := NewCommandPayment(TerminatorTypePaymentCards, &amount, nil)
+	product := NewCommandProduct(1000, nil, nil, nil)
+	discountDepartment, _ := NewCommandDiscountDepartment(500, nil, 2)
+
+	tests := []struct {
+		name     string
+		commands []Command
+		expected error
+	}{
+		{"valid", []Command{product, commandCash}, nil},
+		{"no products", []Command{commandCash}, ErrMissingProducts},
+		{"zero price", []Command{NewCommandProduct(0, nil, nil, nil), commandCash}, ErrZeroPrice},
+		{"negative line", []Command{product, mustCommand(NewCommandDiscountAmount(1500)), commandCash}, ErrNegativeAmount},
+		{"non cash overpayment", []Command{product, commandCard}, ErrNonCashOverpayment},
+		{"cash overpayment", []Command{product, commandCashAmount}, nil},
+		{"insufficient payment", []Command{product, NewCommandProduct(1500, nil, nil, nil), commandCashAmount}, ErrInsufficientPayment},
+		{"no payments", []Command{product}, ErrInsufficientPayment},
+		{"department discount exceeding sales", []Command{product, discountDepartment, commandCash}, ErrNegativeAmount},
+		{"line discount after subtotal discount", []Command{product, mustCommand(NewCommandDiscountAmountSubtotal(100)), mustCommand(NewCommandDiscountAmount(100)), commandCash}, ErrInvalidDocumentOrder},
+		{"subtotal discount exceeding total", []Command{product, mustCommand(NewCommandDiscountAmountSubtotal(1500)), commandCash}, ErrNegativeTotal},
+		{"discount without product", []Command{mustCommand(NewCommandDiscountAmount(100)), product, commandCash}, ErrDiscountWithoutProduct},
+		{"product after payment", []Command{product, commandCash, product}, ErrInvalidDocumentOrder},
+		{"payment after closing", []Command{product, commandCash, commandCash}, ErrInvalidDocumentOrder},
+	}
+
+	for _, test := range tests {
+		doc := &DocumentCommercial{DocumentGeneric: DocumentGeneric{commands: test.commands}}
+		err := doc.Validate()
+		if test.expected == nil && err != nil {
+			t.Errorf("%s: expected error = nil, got %s", test.name, err)
+		}
+		if test.expected != nil && !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+
+	fmt.Println("Completed testValidate")
+}
@@ -0,0 +1,139 @@
+package gongoff
+
+import (
+	"bufio"
+	"context"
+	"sync"
+	"time"
+)
+
+// Xon-Xoff software flow control:
+// The printer sends XOFF when its input buffer is almost full and XON when it can receive data again.
+// XON and XOFF can be interleaved with the response lines at any point.
+const (
+	xon  byte = 0x11
+	xoff byte = 0x13
+)
+
+// flowChunkSize is the maximum number of bytes written without checking for XOFF.
+const flowChunkSize = 32
+
+// replyBufferSize is the number of response lines buffered before the reader stops reading.
+const replyBufferSize = 16
+
+type reply struct {
+	line string
+	err  error
+}
+
+// flowControl tracks the XON/XOFF state announced by the printer.
+type flowControl struct {
+	mu sync.Mutex
+	// resumed is closed while the printer accepts data.
+	resumed chan struct{}
+}
+
+func newFlowControl() *flowControl {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &flowControl{resumed: resumed}
+}
+
+func (f *flowControl) pause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.resumed:
+		f.resumed = make(chan struct{})
+	default:
+	}
+}
+
+func (f *flowControl) resume() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.resumed:
+	default:
+		close(f.resumed)
+	}
+}
+
+func (f *flowControl) paused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+// wait blocks until the printer accepts data, ctx is done or timeout (if positive) expires.
+func (f *flowControl) wait(ctx context.Context, timeout time.Duration) error {
+	f.mu.Lock()
+	resumed := f.resumed
+	f.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-expired:
+		return ErrFlowControlTimeout
+	}
+}
+
+// readReplies reads the stream sent by the printer until the connection fails or done is closed.
+// XON and XOFF update flow, every other byte is part of a response line. The lines are discarded if replies is nil.
+// lost is closed as soon as the connection fails, even if nobody is waiting for a reply.
+func readReplies(src *bufio.Reader, replies chan<- reply, flow *flowControl, lost chan<- struct{}, done <-chan struct{}) {
+	if replies != nil {
+		defer close(replies)
+	}
+	var line []byte
+	for {
+		b, err := src.ReadByte()
+		if err != nil {
+			close(lost)
+			if replies == nil {
+				return
+			}
+			select {
+			case replies <- reply{err: err}:
+			case <-done:
+			}
+			return
+		}
+		switch b {
+		case xoff:
+			flow.pause()
+			continue
+		case xon:
+			flow.resume()
+			continue
+		case '\r', '\n':
+		default:
+			line = append(line, b)
+			continue
+		}
+		if len(line) == 0 || replies == nil {
+			line = nil
+			continue
+		}
+		select {
+		case replies <- reply{line: string(line)}:
+		case <-done:
+			return
+		}
+		line = nil
+	}
+}
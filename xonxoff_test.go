@@ -0,0 +1,284 @@
+package gongoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timedConn records when the first write happened.
+type timedConn struct {
+	net.Conn
+	firstWrite chan time.Time
+}
+
+func (c *timedConn) Write(b []byte) (int, error) {
+	select {
+	case c.firstWrite <- time.Now():
+	default:
+	}
+	return c.Conn.Write(b)
+}
+
+// waitPaused waits until the printer has processed an XOFF.
+func waitPaused(t *testing.T, printer *GenericPrinter) {
+	for i := 0; i < 100; i++ {
+		if printer.flow.paused() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected printer to be paused by XOFF")
+}
+
+func TestFlowControl(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &timedConn{Conn: client, firstWrite: make(chan time.Time, 1)}
+	printer := &GenericPrinter{timeouts: DefaultTimeouts}
+	printer.attach(conn)
+
+	_, err := server.Write([]byte{xoff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPaused(t, printer)
+
+	resumedAt := make(chan time.Time, 1)
+	received := make(chan string, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		resumedAt <- time.Now()
+		_, _ = server.Write([]byte{xon})
+		var command []byte
+		buf := make([]byte, 64)
+		for !strings.HasSuffix(string(command), "J") {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			command = append(command, buf[:n]...)
+		}
+		received <- string(command)
+		_, _ = server.Write([]byte("OK\r"))
+	}()
+
+	longRow := strings.Repeat("X", 46)
+	err = printer.PrintCommands([]Command{
+		NewCommandGeneric([]Data{{variable: longRow, separator: SeparatorTypeDescription}}, Terminator{nil, TerminatorTypeCloseManagementDocument}),
+	})
+	if err != nil {
+		t.Errorf("Expected error = nil, got %s", err)
+	}
+	if (<-conn.firstWrite).Before(<-resumedAt) {
+		t.Errorf("Expected command to be written after XON")
+	}
+	if command := <-received; command != "\""+longRow+"\"J" {
+		t.Errorf("Expected \"%s\"J, got %s", longRow, command)
+	}
+
+	fmt.Println("Completed testFlowControl")
+}
+
+func TestFlowControlTimeout(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer client.Close()
+	printer := &GenericPrinter{timeouts: Timeouts{FlowControl: 50 * time.Millisecond}}
+	printer.attach(client)
+
+	_, err := server.Write([]byte{xoff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPaused(t, printer)
+
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("test", 1)})
+	if !errors.Is(err, ErrFlowControlTimeout) {
+		t.Errorf("Expected ErrFlowControlTimeout, got %v", err)
+	}
+
+	fmt.Println("Completed testFlowControlTimeout")
+}
+
+// pauseAfterFirstChunk reads the first chunk written to conn, then pauses the printer with XOFF and
+// discards everything written until conn is closed.
+func pauseAfterFirstChunk(conn net.Conn, flow *flowControl) string {
+	buf := make([]byte, flowChunkSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	_, _ = conn.Write([]byte{xoff})
+	// The next chunk may already be on the way, the following ones wait for XON.
+	for !flow.paused() {
+		time.Sleep(time.Millisecond)
+	}
+	rest, _ := readCommand(conn, "\x00")
+	return string(buf[:n]) + rest
+}
+
+// readCommand reads from conn until the end of a command terminated by terminator.
+func readCommand(conn net.Conn, terminator string) (string, error) {
+	var command []byte
+	buf := make([]byte, 64)
+	for !strings.HasSuffix(string(command), terminator) {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return string(command), err
+		}
+		command = append(command, buf[:n]...)
+	}
+	return string(command), nil
+}
+
+func TestFlowControlCancelHalfway(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer client.Close()
+	printer := &GenericPrinter{timeouts: DefaultTimeouts}
+	printer.attach(client)
+
+	longRow := strings.Repeat("X", 100)
+	command := NewCommandGeneric([]Data{{variable: longRow, separator: SeparatorTypeDescription}}, Terminator{nil, TerminatorTypeCloseManagementDocument})
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- printer.PrintCommandsContext(ctx, []Command{command})
+	}()
+
+	// XOFF after the first chunk, the context is cancelled while the printer waits for XON.
+	buf := make([]byte, flowChunkSize)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Write([]byte{xoff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPaused(t, printer)
+	received := make(chan string, 1)
+	go func() {
+		rest, _ := readCommand(server, "J")
+		received <- string(buf[:n]) + rest
+	}()
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	_, err = server.Write([]byte{xon})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-received; got != "\""+longRow+"\"J" {
+		t.Errorf("Expected the whole command, got %s", got)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Expected error = nil for the completed command, got %s", err)
+	}
+
+	// Cancelled before the first byte, nothing is written.
+	err = printer.PrintCommandsContext(ctx, []Command{command})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if printer.broken {
+		t.Errorf("Expected the connection not to be broken")
+	}
+
+	fmt.Println("Completed testFlowControlCancelHalfway")
+}
+
+func TestFlowControlTimeoutHalfway(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer client.Close()
+	printer := &GenericPrinter{timeouts: Timeouts{FlowControl: 50 * time.Millisecond}}
+	printer.attach(client)
+
+	received := make(chan string, 1)
+	flow := printer.flow
+	go func() {
+		received <- pauseAfterFirstChunk(server, flow)
+	}()
+
+	longRow := strings.Repeat("X", 100)
+	err := printer.PrintCommands([]Command{
+		NewCommandGeneric([]Data{{variable: longRow, separator: SeparatorTypeDescription}}, Terminator{nil, TerminatorTypeCloseManagementDocument}),
+	})
+	if !errors.Is(err, ErrFlowControlTimeout) {
+		t.Errorf("Expected ErrFlowControlTimeout, got %v", err)
+	}
+
+	// The printer holds half a command, nothing else is written until the connection is replaced.
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("test", 1)})
+	if !errors.Is(err, ErrConnectionBroken) {
+		t.Errorf("Expected ErrConnectionBroken, got %v", err)
+	}
+	client.Close()
+	if got := <-received; strings.Contains(got, "test") || strings.HasSuffix(got, "J") {
+		t.Errorf("Expected only part of the first command, got %s", got)
+	}
+
+	fmt.Println("Completed testFlowControlTimeoutHalfway")
+}
+
+func TestNetworkPrinterFlowControlTimeoutHalfway(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	commands := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		command, err := readCommand(conn, "%")
+		if err == nil {
+			commands <- command
+		}
+	}()
+
+	// The first connection is a pipe pausing the printer after the first chunk and never resuming it,
+	// the printer reconnects to the listener.
+	address := listener.Addr().(*net.TCPAddr)
+	printer := NewNetworkPrinter(address.IP.String(), address.Port)
+	printer.SetTimeouts(Timeouts{FlowControl: 50 * time.Millisecond})
+	client, server := net.Pipe()
+	defer server.Close()
+	printer.socket = &client
+	printer.attach(client)
+	printer.opened = true
+	defer printer.Close()
+	flow := printer.flow
+	go pauseAfterFirstChunk(server, flow)
+
+	longRow := strings.Repeat("X", 100)
+	err = printer.PrintCommands([]Command{
+		NewCommandGeneric([]Data{{variable: longRow, separator: SeparatorTypeDescription}}, Terminator{nil, TerminatorTypeCloseManagementDocument}),
+	})
+	if !errors.Is(err, ErrFlowControlTimeout) {
+		t.Fatalf("Expected ErrFlowControlTimeout, got %v", err)
+	}
+	if printer.IsOpen() {
+		t.Errorf("Expected the connection to be dropped after the interrupted command")
+	}
+	err = printer.PrintCommands([]Command{NewCommandDisplayMessage("test", 1)})
+	if err != nil {
+		t.Errorf("Expected error = nil on a new connection, got %s", err)
+	}
+	if command := <-commands; command != "\"test\"1%" {
+		t.Errorf("Expected \"test\"1%% alone on the new connection, got %s", command)
+	}
+
+	fmt.Println("Completed testNetworkPrinterFlowControlTimeoutHalfway")
+}